@@ -0,0 +1,67 @@
+// Package apikey manages the personal API keys scripts use instead of a password.
+package apikey
+
+import (
+	"time"
+
+	"mkmgo-todo/todo/auth"
+)
+
+type APIKey struct {
+	ID     uint64 `gorm:"primaryKey"`
+	UserID uint64 `gorm:"not null;index"`
+	Label  string `gorm:"not null"`
+	// Prefix is the start of the key, shown so that keys can be told apart, and how a key is found.
+	Prefix     string     `gorm:"not null;uniqueIndex"`
+	Hash       string     `gorm:"not null"` // hex SHA-256 of the whole key
+	Scope      auth.Scope `gorm:"not null"`
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+}
+
+func (APIKey) TableName() string {
+	return "api_key"
+}
+
+func (k APIKey) IsRevoked() bool {
+	return k.RevokedAt != nil
+}
+
+type CreateAPIKeyRequest struct {
+	Label string     `json:"label" validate:"required,trimmed,max=100,printable"`
+	Scope auth.Scope `json:"scope" validate:"required,oneof=read-only read-write admin"`
+}
+
+type UpdateAPIKeyRequest struct {
+	ID    uint64 `json:"-"`
+	Label string `json:"label" validate:"required,trimmed,max=100,printable"`
+}
+
+type APIKeyResponse struct {
+	ID         uint64     `json:"id"`
+	Label      string     `json:"label"`
+	Prefix     string     `json:"prefix"`
+	Scope      auth.Scope `json:"scope"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+}
+
+// CreateAPIKeyResponse is the only response that carries the key itself; it is not stored.
+type CreateAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
+
+func (k APIKey) ToResponse() APIKeyResponse {
+	return APIKeyResponse{
+		ID:         k.ID,
+		Label:      k.Label,
+		Prefix:     k.Prefix,
+		Scope:      k.Scope,
+		CreatedAt:  k.CreatedAt,
+		LastUsedAt: k.LastUsedAt,
+		RevokedAt:  k.RevokedAt,
+	}
+}
@@ -0,0 +1,100 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+)
+
+type APIKeyRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+func NewAPIKeyRepositoryImpl(db *gorm.DB) *APIKeyRepositoryImpl {
+	return &APIKeyRepositoryImpl{DB: db}
+}
+
+func (r *APIKeyRepositoryImpl) SaveAPIKey(ctx context.Context, key *APIKey) error {
+	log := zerolog.Ctx(ctx).With().Str("method", "apiKeyRepository.SaveAPIKey").Logger()
+	if err := r.DB.WithContext(ctx).Create(key).Error; err != nil {
+		log.Error().Err(err).Msg("failed to save API key")
+		return fmt.Errorf("failed to save API key: %w", err)
+	}
+	log.Info().Uint64("id", key.ID).Msg("success to save API key")
+	return nil
+}
+
+// GetAPIKeys returns every key of the user, revoked ones included, oldest first.
+func (r *APIKeyRepositoryImpl) GetAPIKeys(ctx context.Context, userID uint64) ([]APIKey, error) {
+	log := zerolog.Ctx(ctx).With().Str("method", "apiKeyRepository.GetAPIKeys").Logger()
+	var keys []APIKey
+	if err := r.DB.WithContext(ctx).Where("user_id = ?", userID).Order("id").Find(&keys).Error; err != nil {
+		log.Error().Err(err).Msg("failed to retrieve API keys")
+		return nil, fmt.Errorf("failed to retrieve API keys: %w", err)
+	}
+	log.Info().Int("count", len(keys)).Msg("success to retrieve API keys")
+	return keys, nil
+}
+
+func (r *APIKeyRepositoryImpl) GetAPIKey(ctx context.Context, userID, id uint64) (*APIKey, error) {
+	log := zerolog.Ctx(ctx).With().Str("method", "apiKeyRepository.GetAPIKey").Logger()
+	var key APIKey
+	if err := r.DB.WithContext(ctx).Where("user_id = ?", userID).First(&key, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Info().Uint64("id", id).Msg("API key not found")
+			return nil, fmt.Errorf("%w: id %d", ErrAPIKeyNotFound, id)
+		}
+		log.Error().Err(err).Msg("failed to retrieve API key")
+		return nil, fmt.Errorf("failed to retrieve API key: %w", err)
+	}
+	log.Info().Msg("success to retrieve API key")
+	return &key, nil
+}
+
+// GetAPIKeyByPrefix finds the key a request presents, whoever it belongs to.
+func (r *APIKeyRepositoryImpl) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*APIKey, error) {
+	log := zerolog.Ctx(ctx).With().Str("method", "apiKeyRepository.GetAPIKeyByPrefix").Logger()
+	var key APIKey
+	if err := r.DB.WithContext(ctx).Where("prefix = ?", prefix).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Info().Str("prefix", prefix).Msg("API key not found")
+			return nil, ErrAPIKeyNotFound
+		}
+		log.Error().Err(err).Msg("failed to retrieve API key")
+		return nil, fmt.Errorf("failed to retrieve API key: %w", err)
+	}
+	log.Debug().Msg("success to retrieve API key")
+	return &key, nil
+}
+
+func (r *APIKeyRepositoryImpl) UpdateAPIKey(ctx context.Context, key *APIKey) error {
+	log := zerolog.Ctx(ctx).With().Str("method", "apiKeyRepository.UpdateAPIKey").Logger()
+	result := r.DB.WithContext(ctx).Model(&APIKey{}).
+		Where("id = ? AND user_id = ?", key.ID, key.UserID).
+		Updates(map[string]any{"label": key.Label, "revoked_at": key.RevokedAt})
+	if result.Error != nil {
+		log.Error().Err(result.Error).Msg("failed to update API key")
+		return fmt.Errorf("failed to update API key: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		log.Info().Uint64("id", key.ID).Msg("API key not found")
+		return fmt.Errorf("%w: id %d", ErrAPIKeyNotFound, key.ID)
+	}
+	log.Info().Uint64("id", key.ID).Msg("success to update API key")
+	return nil
+}
+
+// TouchAPIKey records that the key was used at the given time.
+func (r *APIKeyRepositoryImpl) TouchAPIKey(ctx context.Context, id uint64, usedAt time.Time) error {
+	log := zerolog.Ctx(ctx).With().Str("method", "apiKeyRepository.TouchAPIKey").Logger()
+	if err := r.DB.WithContext(ctx).Model(&APIKey{}).Where("id = ?", id).UpdateColumn("last_used_at", usedAt).Error; err != nil {
+		log.Error().Err(err).Msg("failed to record API key use")
+		return fmt.Errorf("failed to record API key use: %w", err)
+	}
+	log.Debug().Uint64("id", id).Msg("success to record API key use")
+	return nil
+}
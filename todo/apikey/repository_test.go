@@ -0,0 +1,81 @@
+package apikey
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func newMockRepository(t *testing.T) (*APIKeyRepositoryImpl, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+	assert.NoError(t, err)
+	return NewAPIKeyRepositoryImpl(gormDB), mock
+}
+
+func TestGetAPIKeyMockScopedToUser(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "api_key" WHERE user_id = $1 AND "api_key"."id" = $2 ORDER BY "api_key"."id" LIMIT $3`)).
+		WithArgs(7, 1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, err := repo.GetAPIKey(context.Background(), 7, 1)
+
+	assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetAPIKeyByPrefixMock(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "api_key" WHERE prefix = $1 ORDER BY "api_key"."id" LIMIT $2`)).
+		WithArgs("todo_000000000001", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "prefix", "scope"}).AddRow(1, 7, "todo_000000000001", "read-only"))
+
+	key, err := repo.GetAPIKeyByPrefix(context.Background(), "todo_000000000001")
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(7), key.UserID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateAPIKeyMockWhenNotFound(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "api_key" SET "label"=$1,"revoked_at"=$2 WHERE id = $3 AND user_id = $4`)).
+		WithArgs("ci", nil, 1, 7).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	err := repo.UpdateAPIKey(context.Background(), &APIKey{ID: 1, UserID: 7, Label: "ci"})
+
+	assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTouchAPIKeyMock(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	usedAt := time.Date(2024, time.May, 1, 9, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "api_key" SET "last_used_at"=$1 WHERE id = $2`)).
+		WithArgs(usedAt, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repo.TouchAPIKey(context.Background(), 1, usedAt))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
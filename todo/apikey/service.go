@@ -0,0 +1,190 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"mkmgo-todo/todo/auth"
+	"mkmgo-todo/todo/task"
+
+	"github.com/rs/zerolog"
+)
+
+var ErrAPIKeyNotFound = task.NewError(task.ErrNotFound, "API key not found")
+
+var errNoUser = errors.New("no authenticated user to manage API keys for")
+
+// Keys look like todo_0123456789ab_<43 characters>. The todo_ and hex ID make up the prefix
+// stored in the clear; the rest is 256 random bits.
+const (
+	keyPrefix   = "todo_"
+	idBytes     = 6
+	secretBytes = 32
+	prefixLen   = len(keyPrefix) + 2*idBytes
+)
+
+// lastUsedResolution is how far the recorded last-used time may lag behind, so that a busy key
+// does not cost a database write on every request.
+const lastUsedResolution = time.Minute
+
+type APIKeyRepository interface {
+	SaveAPIKey(ctx context.Context, key *APIKey) error
+	GetAPIKeys(ctx context.Context, userID uint64) ([]APIKey, error)
+	GetAPIKey(ctx context.Context, userID, id uint64) (*APIKey, error)
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (*APIKey, error)
+	UpdateAPIKey(ctx context.Context, key *APIKey) error
+	TouchAPIKey(ctx context.Context, id uint64, usedAt time.Time) error
+}
+
+type APIKeyServiceImpl struct {
+	repo APIKeyRepository
+	now  func() time.Time
+}
+
+func NewAPIKeyServiceImpl(repo APIKeyRepository) *APIKeyServiceImpl {
+	return &APIKeyServiceImpl{repo: repo, now: time.Now}
+}
+
+// CreateAPIKey creates a key for the authenticated user. The response is the only place the key
+// appears; only its hash is kept.
+func (svc *APIKeyServiceImpl) CreateAPIKey(ctx context.Context, request *CreateAPIKeyRequest) (*CreateAPIKeyResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := task.Validate(request); err != nil {
+		return nil, err
+	}
+	secret, err := generateKey()
+	if err != nil {
+		return nil, err
+	}
+	key := APIKey{
+		UserID: userID,
+		Label:  request.Label,
+		Prefix: secret[:prefixLen],
+		Hash:   hashKey(secret),
+		Scope:  request.Scope,
+	}
+	if err := svc.repo.SaveAPIKey(ctx, &key); err != nil {
+		return nil, err
+	}
+	return &CreateAPIKeyResponse{APIKeyResponse: key.ToResponse(), Key: secret}, nil
+}
+
+func (svc *APIKeyServiceImpl) GetAPIKeys(ctx context.Context) ([]APIKeyResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := svc.repo.GetAPIKeys(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]APIKeyResponse, len(keys))
+	for i, key := range keys {
+		responses[i] = key.ToResponse()
+	}
+	return responses, nil
+}
+
+// UpdateAPIKey changes the label of a key.
+func (svc *APIKeyServiceImpl) UpdateAPIKey(ctx context.Context, request *UpdateAPIKeyRequest) (*APIKeyResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := task.Validate(request); err != nil {
+		return nil, err
+	}
+	key, err := svc.repo.GetAPIKey(ctx, userID, request.ID)
+	if err != nil {
+		return nil, err
+	}
+	key.Label = request.Label
+	if err := svc.repo.UpdateAPIKey(ctx, key); err != nil {
+		return nil, err
+	}
+	response := key.ToResponse()
+	return &response, nil
+}
+
+// RevokeAPIKey stops a key from working for good. Revoking a revoked key does nothing.
+func (svc *APIKeyServiceImpl) RevokeAPIKey(ctx context.Context, id uint64) error {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return err
+	}
+	key, err := svc.repo.GetAPIKey(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if key.IsRevoked() {
+		return nil
+	}
+	now := svc.now()
+	key.RevokedAt = &now
+	return svc.repo.UpdateAPIKey(ctx, key)
+}
+
+func (svc *APIKeyServiceImpl) Scheme() string {
+	return "Bearer"
+}
+
+// Authenticate accepts an API key sent as a Bearer credential and returns its user with the
+// key's scope. Other Bearer credentials, such as access tokens, are rejected without a lookup.
+func (svc *APIKeyServiceImpl) Authenticate(ctx context.Context, credentials string) (auth.Principal, error) {
+	if !strings.HasPrefix(credentials, keyPrefix) || len(credentials) <= prefixLen {
+		return auth.Principal{}, auth.ErrInvalidCredentials
+	}
+	key, err := svc.repo.GetAPIKeyByPrefix(ctx, credentials[:prefixLen])
+	if errors.Is(err, ErrAPIKeyNotFound) {
+		return auth.Principal{}, auth.ErrInvalidCredentials
+	}
+	if err != nil {
+		return auth.Principal{}, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashKey(credentials)), []byte(key.Hash)) != 1 || key.IsRevoked() {
+		return auth.Principal{}, auth.ErrInvalidCredentials
+	}
+
+	now := svc.now()
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedResolution {
+		// failing to record the use is no reason to turn the request away
+		if err := svc.repo.TouchAPIKey(ctx, key.ID, now); err != nil {
+			zerolog.Ctx(ctx).Warn().Err(err).Uint64("apiKeyId", key.ID).Msg("failed to record API key use")
+		}
+	}
+	return auth.Principal{UserID: key.UserID, Scope: key.Scope}, nil
+}
+
+func currentUser(ctx context.Context) (uint64, error) {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return 0, errNoUser
+	}
+	return principal.UserID, nil
+}
+
+func generateKey() (string, error) {
+	random := make([]byte, idBytes+secretBytes)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	return keyPrefix + hex.EncodeToString(random[:idBytes]) + "_" + base64.RawURLEncoding.EncodeToString(random[idBytes:]), nil
+}
+
+// hashKey returns the hex SHA-256 of a key. Keys are long and random, so unlike passwords they
+// need no slow hash to resist guessing, and checking one stays cheap.
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,239 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"mkmgo-todo/todo/auth"
+	"mkmgo-todo/todo/task"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+/*
+	Mock apikey/repository.go
+*/
+
+type MockAPIKeyRepository struct {
+	SaveAPIKeyFunc        func(ctx context.Context, key *APIKey) error
+	GetAPIKeysFunc        func(ctx context.Context, userID uint64) ([]APIKey, error)
+	GetAPIKeyFunc         func(ctx context.Context, userID, id uint64) (*APIKey, error)
+	GetAPIKeyByPrefixFunc func(ctx context.Context, prefix string) (*APIKey, error)
+	UpdateAPIKeyFunc      func(ctx context.Context, key *APIKey) error
+	TouchAPIKeyFunc       func(ctx context.Context, id uint64, usedAt time.Time) error
+}
+
+func (m *MockAPIKeyRepository) SaveAPIKey(ctx context.Context, key *APIKey) error {
+	if m.SaveAPIKeyFunc != nil {
+		return m.SaveAPIKeyFunc(ctx, key)
+	}
+	return nil
+}
+
+func (m *MockAPIKeyRepository) GetAPIKeys(ctx context.Context, userID uint64) ([]APIKey, error) {
+	if m.GetAPIKeysFunc != nil {
+		return m.GetAPIKeysFunc(ctx, userID)
+	}
+	return nil, nil
+}
+
+func (m *MockAPIKeyRepository) GetAPIKey(ctx context.Context, userID, id uint64) (*APIKey, error) {
+	if m.GetAPIKeyFunc != nil {
+		return m.GetAPIKeyFunc(ctx, userID, id)
+	}
+	return nil, nil
+}
+
+func (m *MockAPIKeyRepository) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*APIKey, error) {
+	if m.GetAPIKeyByPrefixFunc != nil {
+		return m.GetAPIKeyByPrefixFunc(ctx, prefix)
+	}
+	return nil, ErrAPIKeyNotFound
+}
+
+func (m *MockAPIKeyRepository) UpdateAPIKey(ctx context.Context, key *APIKey) error {
+	if m.UpdateAPIKeyFunc != nil {
+		return m.UpdateAPIKeyFunc(ctx, key)
+	}
+	return nil
+}
+
+func (m *MockAPIKeyRepository) TouchAPIKey(ctx context.Context, id uint64, usedAt time.Time) error {
+	if m.TouchAPIKeyFunc != nil {
+		return m.TouchAPIKeyFunc(ctx, id, usedAt)
+	}
+	return nil
+}
+
+// userCtx is the context of a request authenticated as user 7.
+var userCtx = auth.WithPrincipal(context.Background(), auth.Principal{UserID: 7, Scope: auth.ScopeAdmin})
+
+/*
+	Unit test for apikey/service.go
+*/
+
+func TestCreateAPIKey(t *testing.T) {
+	var saved APIKey
+	svc := NewAPIKeyServiceImpl(&MockAPIKeyRepository{
+		SaveAPIKeyFunc: func(ctx context.Context, key *APIKey) error {
+			key.ID = 1
+			saved = *key
+			return nil
+		},
+	})
+
+	resp, err := svc.CreateAPIKey(userCtx, &CreateAPIKeyRequest{Label: "ci", Scope: auth.ScopeReadWrite})
+
+	require.NoError(t, err)
+	assert.Regexp(t, `^todo_[0-9a-f]{12}_[A-Za-z0-9_-]{43}$`, resp.Key)
+	assert.Equal(t, resp.Key[:prefixLen], resp.Prefix)
+	assert.Equal(t, APIKey{ID: 1, UserID: 7, Label: "ci", Prefix: resp.Prefix, Hash: hashKey(resp.Key), Scope: auth.ScopeReadWrite}, saved)
+	assert.NotContains(t, saved.Hash, resp.Key[prefixLen:])
+}
+
+func TestCreateAPIKeyWhenInvalid(t *testing.T) {
+	svc := NewAPIKeyServiceImpl(&MockAPIKeyRepository{})
+
+	_, err := svc.CreateAPIKey(userCtx, &CreateAPIKeyRequest{Label: "ci", Scope: "root"})
+	assert.ErrorIs(t, err, task.ErrValidation)
+
+	_, err = svc.CreateAPIKey(context.Background(), &CreateAPIKeyRequest{Label: "ci", Scope: auth.ScopeAdmin})
+	assert.ErrorIs(t, err, errNoUser)
+}
+
+func TestGetAPIKeys(t *testing.T) {
+	svc := NewAPIKeyServiceImpl(&MockAPIKeyRepository{
+		GetAPIKeysFunc: func(ctx context.Context, userID uint64) ([]APIKey, error) {
+			assert.Equal(t, uint64(7), userID)
+			return []APIKey{{ID: 1, Label: "ci", Prefix: "todo_000000000001", Hash: "secret"}}, nil
+		},
+	})
+
+	resp, err := svc.GetAPIKeys(userCtx)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []APIKeyResponse{{ID: 1, Label: "ci", Prefix: "todo_000000000001"}}, resp)
+}
+
+func TestUpdateAPIKey(t *testing.T) {
+	var updated APIKey
+	svc := NewAPIKeyServiceImpl(&MockAPIKeyRepository{
+		GetAPIKeyFunc: func(ctx context.Context, userID, id uint64) (*APIKey, error) {
+			return &APIKey{ID: id, UserID: userID, Label: "old"}, nil
+		},
+		UpdateAPIKeyFunc: func(ctx context.Context, key *APIKey) error {
+			updated = *key
+			return nil
+		},
+	})
+
+	resp, err := svc.UpdateAPIKey(userCtx, &UpdateAPIKeyRequest{ID: 1, Label: "new"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "new", resp.Label)
+	assert.Equal(t, APIKey{ID: 1, UserID: 7, Label: "new"}, updated)
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	now := time.Date(2024, time.May, 1, 9, 0, 0, 0, time.UTC)
+	var revoked *APIKey
+	svc := NewAPIKeyServiceImpl(&MockAPIKeyRepository{
+		GetAPIKeyFunc: func(ctx context.Context, userID, id uint64) (*APIKey, error) {
+			if id == 2 {
+				return &APIKey{ID: id, UserID: userID, RevokedAt: &now}, nil
+			}
+			if id != 1 {
+				return nil, ErrAPIKeyNotFound
+			}
+			return &APIKey{ID: id, UserID: userID}, nil
+		},
+		UpdateAPIKeyFunc: func(ctx context.Context, key *APIKey) error {
+			revoked = key
+			return nil
+		},
+	})
+	svc.now = func() time.Time { return now }
+
+	assert.NoError(t, svc.RevokeAPIKey(userCtx, 1))
+	assert.Equal(t, &now, revoked.RevokedAt)
+
+	revoked = nil
+	assert.NoError(t, svc.RevokeAPIKey(userCtx, 2))
+	assert.Nil(t, revoked, "a revoked key is left alone")
+
+	assert.ErrorIs(t, svc.RevokeAPIKey(userCtx, 3), ErrAPIKeyNotFound)
+}
+
+func TestAuthenticate(t *testing.T) {
+	now := time.Date(2024, time.May, 1, 9, 0, 0, 0, time.UTC)
+	secret, err := generateKey()
+	require.NoError(t, err)
+	stored := APIKey{ID: 1, UserID: 7, Prefix: secret[:prefixLen], Hash: hashKey(secret), Scope: auth.ScopeReadOnly}
+	wrong := []byte(secret)
+	wrong[len(wrong)-1] ^= 1
+	var touched []time.Time
+	svc := NewAPIKeyServiceImpl(&MockAPIKeyRepository{
+		GetAPIKeyByPrefixFunc: func(ctx context.Context, prefix string) (*APIKey, error) {
+			if prefix != stored.Prefix {
+				return nil, ErrAPIKeyNotFound
+			}
+			key := stored
+			return &key, nil
+		},
+		TouchAPIKeyFunc: func(ctx context.Context, id uint64, usedAt time.Time) error {
+			touched = append(touched, usedAt)
+			stored.LastUsedAt = &usedAt
+			return nil
+		},
+	})
+	svc.now = func() time.Time { return now }
+
+	principal, err := svc.Authenticate(context.Background(), secret)
+	assert.NoError(t, err)
+	assert.Equal(t, auth.Principal{UserID: 7, Scope: auth.ScopeReadOnly}, principal)
+
+	// use within the resolution is not recorded again
+	now = now.Add(lastUsedResolution / 2)
+	_, err = svc.Authenticate(context.Background(), secret)
+	assert.NoError(t, err)
+	now = now.Add(lastUsedResolution)
+	_, err = svc.Authenticate(context.Background(), secret)
+	assert.NoError(t, err)
+	assert.Len(t, touched, 2)
+
+	for name, credentials := range map[string]string{
+		"wrong secret": string(wrong),
+		"unknown":      "todo_000000000000_" + strings.Repeat("A", 43),
+		"access token": "eyJhbGciOiJIUzI1NiJ9.e30.sig",
+		"prefix only":  secret[:prefixLen],
+	} {
+		_, err := svc.Authenticate(context.Background(), credentials)
+		assert.ErrorIs(t, err, auth.ErrInvalidCredentials, name)
+	}
+
+	stored.RevokedAt = &now
+	_, err = svc.Authenticate(context.Background(), secret)
+	assert.ErrorIs(t, err, auth.ErrInvalidCredentials)
+}
+
+func TestAuthenticateWhenTouchFails(t *testing.T) {
+	secret, err := generateKey()
+	require.NoError(t, err)
+	svc := NewAPIKeyServiceImpl(&MockAPIKeyRepository{
+		GetAPIKeyByPrefixFunc: func(ctx context.Context, prefix string) (*APIKey, error) {
+			return &APIKey{ID: 1, UserID: 7, Prefix: prefix, Hash: hashKey(secret), Scope: auth.ScopeAdmin}, nil
+		},
+		TouchAPIKeyFunc: func(ctx context.Context, id uint64, usedAt time.Time) error {
+			return errors.New("database is locked")
+		},
+	})
+
+	principal, err := svc.Authenticate(context.Background(), secret)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(7), principal.UserID)
+}
@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// ErrInvalidCredentials reports credentials that were understood but not accepted.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// Authenticator verifies the credentials of one Authorization header scheme.
+type Authenticator interface {
+	// Scheme is the scheme name, such as Basic, compared case-insensitively.
+	Scheme() string
+	// Authenticate checks the credentials following the scheme name. It returns an error wrapping
+	// ErrInvalidCredentials when they are malformed or wrong.
+	Authenticate(ctx context.Context, credentials string) (Principal, error)
+}
+
+type PasswordVerifier interface {
+	VerifyPassword(ctx context.Context, email, password string) (uint64, error)
+}
+
+// BasicAuthenticator accepts an email and password sent with HTTP Basic authentication.
+type BasicAuthenticator struct {
+	verifier PasswordVerifier
+}
+
+func NewBasicAuthenticator(verifier PasswordVerifier) *BasicAuthenticator {
+	return &BasicAuthenticator{verifier: verifier}
+}
+
+func (a *BasicAuthenticator) Scheme() string {
+	return "Basic"
+}
+
+func (a *BasicAuthenticator) Authenticate(ctx context.Context, credentials string) (Principal, error) {
+	decoded, err := base64.StdEncoding.DecodeString(credentials)
+	if err != nil {
+		return Principal{}, ErrInvalidCredentials
+	}
+	email, password, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return Principal{}, ErrInvalidCredentials
+	}
+	userID, err := a.verifier.VerifyPassword(ctx, email, password)
+	if err != nil {
+		return Principal{}, err
+	}
+	return Principal{UserID: userID, Scope: ScopeAdmin}, nil
+}
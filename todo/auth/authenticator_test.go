@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type verifierFunc func(ctx context.Context, email, password string) (uint64, error)
+
+func (f verifierFunc) VerifyPassword(ctx context.Context, email, password string) (uint64, error) {
+	return f(ctx, email, password)
+}
+
+func TestBasicAuthenticator(t *testing.T) {
+	authenticator := NewBasicAuthenticator(verifierFunc(func(ctx context.Context, email, password string) (uint64, error) {
+		if email == "ana@example.com" && password == "pass:word" {
+			return 1, nil
+		}
+		return 0, ErrInvalidCredentials
+	}))
+
+	principal, err := authenticator.Authenticate(context.Background(), base64.StdEncoding.EncodeToString([]byte("ana@example.com:pass:word")))
+	assert.NoError(t, err)
+	assert.Equal(t, Principal{UserID: 1, Scope: ScopeAdmin}, principal)
+
+	for _, credentials := range []string{
+		base64.StdEncoding.EncodeToString([]byte("ana@example.com:wrong")),
+		base64.StdEncoding.EncodeToString([]byte("ana@example.com")),
+		"not base64!",
+	} {
+		_, err := authenticator.Authenticate(context.Background(), credentials)
+		assert.ErrorIs(t, err, ErrInvalidCredentials, credentials)
+	}
+}
+
+func TestPrincipalFromContext(t *testing.T) {
+	_, ok := PrincipalFromContext(context.Background())
+	assert.False(t, ok)
+
+	principal, ok := PrincipalFromContext(WithPrincipal(context.Background(), Principal{UserID: 7}))
+	assert.True(t, ok)
+	assert.Equal(t, Principal{UserID: 7}, principal)
+}
+
+func TestScopeAllows(t *testing.T) {
+	assert.True(t, ScopeAdmin.Allows(ScopeReadWrite))
+	assert.True(t, ScopeReadWrite.Allows(ScopeReadWrite))
+	assert.True(t, ScopeReadWrite.Allows(ScopeReadOnly))
+	assert.False(t, ScopeReadOnly.Allows(ScopeReadWrite))
+	assert.False(t, ScopeReadWrite.Allows(ScopeAdmin))
+	assert.False(t, Scope("").Allows(ScopeReadOnly))
+}
+
+func TestRoleAllows(t *testing.T) {
+	assert.True(t, RoleOwner.Allows(RoleEditor))
+	assert.True(t, RoleEditor.Allows(RoleEditor))
+	assert.True(t, RoleEditor.Allows(RoleViewer))
+	assert.False(t, RoleViewer.Allows(RoleEditor))
+	assert.False(t, RoleEditor.Allows(RoleOwner))
+	assert.False(t, Role("").Allows(RoleViewer))
+}
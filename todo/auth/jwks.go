@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// jwksCheckInterval is how often a JWKS file is checked for changes, so that a burst of tokens
+// costs one stat rather than one each.
+const jwksCheckInterval = 5 * time.Second
+
+// minRSABits is the smallest RSA modulus accepted for signatures.
+const minRSABits = 2048
+
+// jwksFile is a JSON Web Key Set on disk whose RS256 keys verify tokens. Rotating keys is a
+// matter of rewriting the file; it is read again when its modification time changes.
+type jwksFile struct {
+	path string
+
+	mu      sync.Mutex
+	checked time.Time
+	modTime time.Time
+	keys    map[string]*rsa.PublicKey // by kid
+}
+
+func openJWKSFile(path string) (*jwksFile, error) {
+	f := &jwksFile{path: path}
+	if err := f.reload(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// key returns the key with the given kid. A file that has become unreadable or invalid is
+// reported and the keys read before are kept, so a half-written file cannot lock everyone out.
+func (f *jwksFile) key(ctx context.Context, kid string, now time.Time) (*rsa.PublicKey, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if now.Sub(f.checked) >= jwksCheckInterval {
+		f.checked = now
+		if err := f.reload(); err != nil {
+			zerolog.Ctx(ctx).Warn().Err(err).Msg("failed to reload JWKS file, keeping the previous keys")
+		}
+	}
+	key, ok := f.keys[kid]
+	return key, ok
+}
+
+func (f *jwksFile) reload() error {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return fmt.Errorf("failed to read JWKS file: %w", err)
+	}
+	if f.keys != nil && info.ModTime().Equal(f.modTime) {
+		return nil
+	}
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return fmt.Errorf("failed to read JWKS file: %w", err)
+	}
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse JWKS file %s: %w", f.path, err)
+	}
+	f.keys, f.modTime = keys, info.ModTime()
+	return nil
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// parseJWKS returns the RSA signature keys of a key set by kid, skipping keys meant for anything
+// else. Keys without a kid are known by their thumbprint.
+func parseJWKS(data []byte) (map[string]*rsa.PublicKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]*rsa.PublicKey)
+	for i, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") || (k.Alg != "" && k.Alg != "RS256") {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("key %d has a malformed modulus or exponent", i)
+		}
+		key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		if key.N.BitLen() < minRSABits {
+			return nil, fmt.Errorf("key %d is shorter than %d bits", i, minRSABits)
+		}
+		kid := k.Kid
+		if kid == "" {
+			kid = thumbprint(key)
+		}
+		keys[kid] = key
+	}
+	return keys, nil
+}
+
+// thumbprint returns the RFC 7638 JWK thumbprint of an RSA key, a stable key ID.
+func thumbprint(key *rsa.PublicKey) string {
+	// the members in lexical order and without whitespace, as RFC 7638 requires
+	canonical := fmt.Sprintf(`{"e":%q,"kty":"RSA","n":%q}`, encodeExponent(key), encodeModulus(key))
+	sum := sha256.Sum256([]byte(canonical))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func encodeModulus(key *rsa.PublicKey) string {
+	return base64.RawURLEncoding.EncodeToString(key.N.Bytes())
+}
+
+func encodeExponent(key *rsa.PublicKey) string {
+	return base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+}
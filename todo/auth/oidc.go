@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"mkmgo-todo/todo/config"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// ErrOIDCRejected reports a sign-in the provider or the ID token it returned did not allow.
+var ErrOIDCRejected = errors.New("single sign-on failed")
+
+// Identity is a user as an OpenID Connect provider knows them. Issuer and Subject together name
+// the user for good; the email may change.
+type Identity struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// OIDCLogin is a sign-in in progress: what the browser must be sent to and what must be kept to
+// finish it when the provider sends the browser back.
+type OIDCLogin struct {
+	URL      string
+	State    string // sent back by the provider, ties the callback to this sign-in
+	Nonce    string // repeated in the ID token, ties the token to this sign-in
+	Verifier string // PKCE code verifier
+}
+
+// OIDCProvider signs users in with the authorization code flow and PKCE against a provider
+// found through its discovery document.
+type OIDCProvider struct {
+	oauth    oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+// NewOIDCProvider fetches the discovery document of cfg.Issuer.
+func NewOIDCProvider(ctx context.Context, cfg config.OIDCConfig) (*OIDCProvider, error) {
+	provider, err := oidc.NewProvider(ctx, cfg.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover OpenID Connect provider %s: %w", cfg.Issuer, err)
+	}
+	return &OIDCProvider{
+		oauth: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       []string{oidc.ScopeOpenID, "email", "profile"},
+		},
+		verifier: provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+	}, nil
+}
+
+// StartLogin begins a sign-in with fresh state, nonce and code verifier.
+func (p *OIDCProvider) StartLogin() (OIDCLogin, error) {
+	state, err := randomString()
+	if err != nil {
+		return OIDCLogin{}, err
+	}
+	nonce, err := randomString()
+	if err != nil {
+		return OIDCLogin{}, err
+	}
+	verifier := oauth2.GenerateVerifier()
+	return OIDCLogin{
+		URL:      p.oauth.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)),
+		State:    state,
+		Nonce:    nonce,
+		Verifier: verifier,
+	}, nil
+}
+
+// FinishLogin redeems the authorization code the provider sent back and returns the identity in
+// the verified ID token. Errors the provider or the token are to blame for wrap ErrOIDCRejected.
+func (p *OIDCProvider) FinishLogin(ctx context.Context, login OIDCLogin, code string) (Identity, error) {
+	token, err := p.oauth.Exchange(ctx, code, oauth2.VerifierOption(login.Verifier))
+	if err != nil {
+		var retrieveErr *oauth2.RetrieveError
+		if errors.As(err, &retrieveErr) {
+			return Identity{}, fmt.Errorf("%w: code exchange refused: %v", ErrOIDCRejected, err)
+		}
+		return Identity{}, fmt.Errorf("failed to exchange authorization code: %w", err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return Identity{}, fmt.Errorf("%w: token response has no ID token", ErrOIDCRejected)
+	}
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return Identity{}, fmt.Errorf("%w: %v", ErrOIDCRejected, err)
+	}
+	if idToken.Nonce != login.Nonce {
+		return Identity{}, fmt.Errorf("%w: ID token nonce does not match", ErrOIDCRejected)
+	}
+
+	var claims struct {
+		Email         string `json:"email"`
+		EmailVerified any    `json:"email_verified"` // some providers send "true"
+		Name          string `json:"name"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return Identity{}, fmt.Errorf("%w: %v", ErrOIDCRejected, err)
+	}
+	return Identity{
+		Issuer:        idToken.Issuer,
+		Subject:       idToken.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified == true || claims.EmailVerified == "true",
+		Name:          claims.Name,
+	}, nil
+}
+
+func randomString() (string, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("failed to generate random value: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(random), nil
+}
@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"mkmgo-todo/todo/auth/oidctest"
+	"mkmgo-todo/todo/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newOIDCProvider(t *testing.T) (*oidctest.Provider, *OIDCProvider) {
+	idp, err := oidctest.NewProvider("todo")
+	require.NoError(t, err)
+	t.Cleanup(idp.Close)
+	idp.User = oidctest.User{Subject: "u-1", Email: "ana@example.com", EmailVerified: true, Name: "Ana"}
+
+	provider, err := NewOIDCProvider(context.Background(), config.OIDCConfig{
+		Issuer:      idp.Issuer(),
+		ClientID:    "todo",
+		RedirectURL: "http://localhost:8080/todo/oidc/callback",
+	})
+	require.NoError(t, err)
+	return idp, provider
+}
+
+// signIn starts a login and lets the user through at the provider, returning the code it sends
+// back.
+func signIn(t *testing.T, idp *oidctest.Provider, provider *OIDCProvider) (OIDCLogin, string) {
+	login, err := provider.StartLogin()
+	require.NoError(t, err)
+	callback, err := idp.Authorize(login.URL)
+	require.NoError(t, err)
+	assert.Equal(t, login.State, callback.Query().Get("state"))
+	return login, callback.Query().Get("code")
+}
+
+/* Unit test for OIDCProvider */
+
+func TestOIDCLogin(t *testing.T) {
+	idp, provider := newOIDCProvider(t)
+
+	login, code := signIn(t, idp, provider)
+	identity, err := provider.FinishLogin(context.Background(), login, code)
+
+	require.NoError(t, err)
+	assert.Equal(t, Identity{Issuer: idp.Issuer(), Subject: "u-1", Email: "ana@example.com", EmailVerified: true, Name: "Ana"}, identity)
+
+	// codes are single use
+	_, err = provider.FinishLogin(context.Background(), login, code)
+	assert.ErrorIs(t, err, ErrOIDCRejected)
+}
+
+func TestOIDCLoginWhenVerifierWrong(t *testing.T) {
+	idp, provider := newOIDCProvider(t)
+
+	login, code := signIn(t, idp, provider)
+	other, err := provider.StartLogin()
+	require.NoError(t, err)
+	login.Verifier = other.Verifier
+	_, err = provider.FinishLogin(context.Background(), login, code)
+
+	assert.ErrorIs(t, err, ErrOIDCRejected)
+}
+
+func TestOIDCLoginWhenNonceDiffers(t *testing.T) {
+	idp, provider := newOIDCProvider(t)
+
+	login, code := signIn(t, idp, provider)
+	login.Nonce = "replayed"
+	_, err := provider.FinishLogin(context.Background(), login, code)
+
+	assert.ErrorIs(t, err, ErrOIDCRejected)
+}
+
+func TestOIDCLoginWhenTokenForAnotherClient(t *testing.T) {
+	idp, provider := newOIDCProvider(t)
+	idp.Audience = "someone-else"
+
+	login, code := signIn(t, idp, provider)
+	_, err := provider.FinishLogin(context.Background(), login, code)
+
+	assert.ErrorIs(t, err, ErrOIDCRejected)
+}
+
+func TestNewOIDCProviderWhenDiscoveryFails(t *testing.T) {
+	idp, err := oidctest.NewProvider("todo")
+	require.NoError(t, err)
+	idp.Close()
+
+	_, err = NewOIDCProvider(context.Background(), config.OIDCConfig{Issuer: idp.Issuer(), ClientID: "todo"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,41 @@
+// Package auth identifies who a request acts for.
+package auth
+
+import "context"
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	UserID uint64
+	Scope  Scope // what the caller may do on the user's behalf
+}
+
+// Scope limits what a principal may do. Each scope allows everything the ones before it do.
+type Scope string
+
+const (
+	ScopeReadOnly  Scope = "read-only"  // read tasks and the account
+	ScopeReadWrite Scope = "read-write" // also change tasks
+	ScopeAdmin     Scope = "admin"      // also manage API keys; users signed in with a password have it
+)
+
+var scopeRanks = map[Scope]int{ScopeReadOnly: 1, ScopeReadWrite: 2, ScopeAdmin: 3}
+
+// Allows reports whether s includes required. An unknown scope allows nothing.
+func (s Scope) Allows(required Scope) bool {
+	rank, ok := scopeRanks[s]
+	return ok && rank >= scopeRanks[required]
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a context that carries principal.
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal of the request ctx belongs to, if it was
+// authenticated.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
@@ -0,0 +1,19 @@
+package auth
+
+// Role is what a member may do in a shared project. Each role allows everything the ones before
+// it do.
+type Role string
+
+const (
+	RoleViewer Role = "viewer" // read the project's tasks
+	RoleEditor Role = "editor" // also create, change and delete them
+	RoleOwner  Role = "owner"  // also manage the members
+)
+
+var roleRanks = map[Role]int{RoleViewer: 1, RoleEditor: 2, RoleOwner: 3}
+
+// Allows reports whether r includes required. An unknown role allows nothing.
+func (r Role) Allows(required Role) bool {
+	rank, ok := roleRanks[r]
+	return ok && rank >= roleRanks[required]
+}
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// ErrUnsealable reports a sealed value that was not sealed with this key or has been altered.
+var ErrUnsealable = errors.New("sealed value cannot be opened")
+
+// Sealer encrypts and authenticates values the server hands to a browser to keep, so they come
+// back unread and unchanged. Every server given the same key material can open what another
+// sealed.
+type Sealer struct {
+	aead cipher.AEAD
+}
+
+// NewSealer derives an AES-256-GCM key for one purpose from key, so values sealed for one purpose
+// cannot be passed off as another's.
+func NewSealer(key []byte, purpose string) (*Sealer, error) {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(purpose))
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up sealing: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up sealing: %w", err)
+	}
+	return &Sealer{aead: aead}, nil
+}
+
+// Seal returns value encrypted under a fresh nonce, encoded for use in a cookie.
+func (s *Sealer) Seal(value []byte) (string, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(s.aead.Seal(nonce, nonce, value, nil)), nil
+}
+
+// Open returns the value sealed, or ErrUnsealable.
+func (s *Sealer) Open(sealed string) ([]byte, error) {
+	data, err := base64.RawURLEncoding.DecodeString(sealed)
+	if err != nil || len(data) < s.aead.NonceSize() {
+		return nil, ErrUnsealable
+	}
+	nonce, ciphertext := data[:s.aead.NonceSize()], data[s.aead.NonceSize():]
+	value, err := s.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, ErrUnsealable
+	}
+	return value, nil
+}
+
+// NewSealer returns a Sealer keyed by the same secret or signing key the tokens are signed with,
+// so sealed values work across servers exactly when tokens do.
+func (a *TokenAuthenticator) NewSealer(purpose string) (*Sealer, error) {
+	key := a.secret
+	if key == nil {
+		der, err := x509.MarshalPKCS8PrivateKey(a.signingKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read signing key: %w", err)
+		}
+		key = der
+	}
+	return NewSealer(key, purpose)
+}
@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSealer(t *testing.T) {
+	sealer, err := NewSealer([]byte(testSecret), "oidc login")
+	require.NoError(t, err)
+
+	sealed, err := sealer.Seal([]byte(`{"verifier":"v1"}`))
+	require.NoError(t, err)
+	assert.NotContains(t, sealed, "v1")
+	value, err := sealer.Open(sealed)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"verifier":"v1"}`, string(value))
+
+	again, err := sealer.Seal([]byte(`{"verifier":"v1"}`))
+	require.NoError(t, err)
+	assert.NotEqual(t, sealed, again, "every seal takes a fresh nonce")
+
+	tampered := []byte(sealed)
+	tampered[len(tampered)-1] ^= 1
+	for _, bad := range []string{string(tampered), "", "!", strings.Repeat("A", 8)} {
+		_, err = sealer.Open(bad)
+		assert.ErrorIs(t, err, ErrUnsealable, bad)
+	}
+
+	otherPurpose, err := NewSealer([]byte(testSecret), "something else")
+	require.NoError(t, err)
+	_, err = otherPurpose.Open(sealed)
+	assert.ErrorIs(t, err, ErrUnsealable)
+}
+
+func TestTokenAuthenticatorSealerFollowsKeys(t *testing.T) {
+	cfg := authConfig()
+	cfg.SigningKeyFile = writeSigningKey(t, newRSAKey(t))
+	tokens, err := NewTokenAuthenticator(cfg)
+	require.NoError(t, err)
+	sameKey, err := NewTokenAuthenticator(cfg)
+	require.NoError(t, err)
+
+	sealer, err := tokens.NewSealer("oidc login")
+	require.NoError(t, err)
+	sealed, err := sealer.Seal([]byte("s1"))
+	require.NoError(t, err)
+
+	other, err := sameKey.NewSealer("oidc login")
+	require.NoError(t, err)
+	value, err := other.Open(sealed)
+	assert.NoError(t, err)
+	assert.Equal(t, "s1", string(value))
+}
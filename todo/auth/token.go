@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"mkmgo-todo/todo/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// clockSkew is how far the clocks of the servers issuing and checking a token may disagree.
+const clockSkew = 30 * time.Second
+
+type LoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type TokenResponse struct {
+	AccessToken string `json:"accessToken"`
+	TokenType   string `json:"tokenType"`
+	ExpiresIn   int64  `json:"expiresIn"` // seconds
+}
+
+// TokenAuthenticator issues signed JWT access tokens and accepts them as Bearer credentials. It
+// signs with RS256 when it has a signing key and with HS256 otherwise, and accepts either kind
+// of token it has a key for.
+type TokenAuthenticator struct {
+	issuer       string
+	ttl          time.Duration
+	secret       []byte // HS256 key, nil when HS256 is off
+	signingKey   *rsa.PrivateKey
+	signingKeyID string
+	jwks         *jwksFile // nil without a JWKS file
+	methods      []string
+	now          func() time.Time
+}
+
+// NewTokenAuthenticator loads the keys cfg names. Without a secret or a signing key it makes up
+// an HS256 secret, so tokens stop working when the process exits.
+func NewTokenAuthenticator(cfg config.AuthConfig) (*TokenAuthenticator, error) {
+	a := &TokenAuthenticator{issuer: cfg.Issuer, ttl: cfg.TokenTTL, now: time.Now}
+	if cfg.Secret != "" {
+		a.secret = []byte(cfg.Secret)
+	}
+	if cfg.SigningKeyFile != "" {
+		pemData, err := os.ReadFile(cfg.SigningKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read signing key: %w", err)
+		}
+		a.signingKey, err = jwt.ParseRSAPrivateKeyFromPEM(pemData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse signing key %s: %w", cfg.SigningKeyFile, err)
+		}
+		a.signingKeyID = cfg.SigningKeyID
+		if a.signingKeyID == "" {
+			a.signingKeyID = thumbprint(&a.signingKey.PublicKey)
+		}
+	}
+	if cfg.JWKSFile != "" {
+		jwks, err := openJWKSFile(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		a.jwks = jwks
+	}
+	if a.secret == nil && a.signingKey == nil {
+		a.secret = make([]byte, config.MinSecretLength)
+		if _, err := rand.Read(a.secret); err != nil {
+			return nil, fmt.Errorf("failed to generate token secret: %w", err)
+		}
+	}
+
+	if a.secret != nil {
+		a.methods = append(a.methods, jwt.SigningMethodHS256.Alg())
+	}
+	if a.signingKey != nil || a.jwks != nil {
+		a.methods = append(a.methods, jwt.SigningMethodRS256.Alg())
+	}
+	return a, nil
+}
+
+func (a *TokenAuthenticator) Scheme() string {
+	return "Bearer"
+}
+
+// IssueToken returns an access token for the user and when it expires.
+func (a *TokenAuthenticator) IssueToken(userID uint64) (string, time.Time, error) {
+	now := a.now()
+	expiresAt := now.Add(a.ttl)
+	claims := jwt.RegisteredClaims{
+		Issuer:    a.issuer,
+		Subject:   strconv.FormatUint(userID, 10),
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	}
+
+	var signed string
+	var err error
+	if a.signingKey != nil {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = a.signingKeyID
+		signed, err = token.SignedString(a.signingKey)
+	} else {
+		signed, err = jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(a.secret)
+	}
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to sign token: %w", err)
+	}
+	return signed, expiresAt, nil
+}
+
+var errUnknownKey = errors.New("token is signed with an unknown key")
+
+func (a *TokenAuthenticator) Authenticate(ctx context.Context, credentials string) (Principal, error) {
+	var claims jwt.RegisteredClaims
+	_, err := jwt.ParseWithClaims(credentials, &claims, func(token *jwt.Token) (any, error) {
+		// the parser has already refused algorithms missing from a.methods
+		if token.Method == jwt.SigningMethodHS256 {
+			return a.secret, nil
+		}
+		kid, _ := token.Header["kid"].(string)
+		if a.signingKey != nil && kid == a.signingKeyID {
+			return &a.signingKey.PublicKey, nil
+		}
+		if a.jwks != nil {
+			if key, ok := a.jwks.key(ctx, kid, a.now()); ok {
+				return key, nil
+			}
+		}
+		return nil, errUnknownKey
+	},
+		jwt.WithValidMethods(a.methods),
+		jwt.WithIssuer(a.issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(clockSkew),
+		jwt.WithTimeFunc(a.now),
+	)
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+	userID, err := strconv.ParseUint(claims.Subject, 10, 64)
+	if err != nil || userID == 0 {
+		return Principal{}, fmt.Errorf("%w: token subject %q is not a user ID", ErrInvalidCredentials, claims.Subject)
+	}
+	return Principal{UserID: userID, Scope: ScopeAdmin}, nil
+}
@@ -0,0 +1,219 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"mkmgo-todo/todo/config"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return key
+}
+
+// writeSigningKey stores key as a PKCS#8 PEM file.
+func writeSigningKey(t *testing.T, key *rsa.PrivateKey) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "signing.pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+	return path
+}
+
+// writeJWKS writes a key set of the public halves of keys, by kid, to path.
+func writeJWKS(t *testing.T, path string, keys map[string]*rsa.PrivateKey) {
+	set := struct {
+		Keys []jwk `json:"keys"`
+	}{Keys: []jwk{}}
+	for kid, key := range keys {
+		set.Keys = append(set.Keys, jwk{
+			Kty: "RSA", Use: "sig", Kid: kid,
+			N: base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	data, err := json.Marshal(set)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+}
+
+func authConfig() config.AuthConfig {
+	return config.AuthConfig{Issuer: "mkmgo-todo", TokenTTL: time.Hour}
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.Claims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func claimsFor(userID string, expiresAt time.Time) jwt.RegisteredClaims {
+	return jwt.RegisteredClaims{Issuer: "mkmgo-todo", Subject: userID, ExpiresAt: jwt.NewNumericDate(expiresAt)}
+}
+
+/* Unit test for TokenAuthenticator */
+
+func TestTokenAuthenticatorHS256(t *testing.T) {
+	cfg := authConfig()
+	cfg.Secret = testSecret
+	tokens, err := NewTokenAuthenticator(cfg)
+	require.NoError(t, err)
+
+	token, expiresAt, err := tokens.IssueToken(7)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), expiresAt, time.Minute)
+
+	principal, err := tokens.Authenticate(context.Background(), token)
+	assert.NoError(t, err)
+	assert.Equal(t, Principal{UserID: 7, Scope: ScopeAdmin}, principal)
+
+	// a server with another secret does not accept it
+	cfg.Secret = strings.Repeat("x", 32)
+	other, err := NewTokenAuthenticator(cfg)
+	require.NoError(t, err)
+	_, err = other.Authenticate(context.Background(), token)
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
+
+func TestTokenAuthenticatorWithoutKeysMakesUpSecret(t *testing.T) {
+	tokens, err := NewTokenAuthenticator(authConfig())
+	require.NoError(t, err)
+
+	token, _, err := tokens.IssueToken(7)
+	require.NoError(t, err)
+	_, err = tokens.Authenticate(context.Background(), token)
+	assert.NoError(t, err)
+}
+
+func TestTokenAuthenticatorRejectsBadTokens(t *testing.T) {
+	cfg := authConfig()
+	cfg.Secret = testSecret
+	tokens, err := NewTokenAuthenticator(cfg)
+	require.NoError(t, err)
+	sign := func(claims jwt.Claims) string {
+		signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testSecret))
+		require.NoError(t, err)
+		return signed
+	}
+	unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, claimsFor("7", time.Now().Add(time.Hour))).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+	otherIssuer := claimsFor("7", time.Now().Add(time.Hour))
+	otherIssuer.Issuer = "someone-else"
+
+	tests := map[string]string{
+		"expired":        sign(claimsFor("7", time.Now().Add(-time.Hour))),
+		"no expiry":      sign(jwt.RegisteredClaims{Issuer: "mkmgo-todo", Subject: "7"}),
+		"other issuer":   sign(otherIssuer),
+		"bad subject":    sign(claimsFor("ana", time.Now().Add(time.Hour))),
+		"unsigned":       unsigned,
+		"not a token":    "abc.def.ghi",
+		"RS256 for HMAC": signRS256(t, newRSAKey(t), "k1", claimsFor("7", time.Now().Add(time.Hour))),
+	}
+	for name, token := range tests {
+		_, err := tokens.Authenticate(context.Background(), token)
+		assert.ErrorIs(t, err, ErrInvalidCredentials, name)
+	}
+}
+
+func TestTokenAuthenticatorRS256(t *testing.T) {
+	key := newRSAKey(t)
+	cfg := authConfig()
+	cfg.SigningKeyFile = writeSigningKey(t, key)
+	tokens, err := NewTokenAuthenticator(cfg)
+	require.NoError(t, err)
+
+	token, _, err := tokens.IssueToken(7)
+	require.NoError(t, err)
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, &jwt.RegisteredClaims{})
+	require.NoError(t, err)
+	assert.Equal(t, "RS256", parsed.Method.Alg())
+	assert.Equal(t, thumbprint(&key.PublicKey), parsed.Header["kid"])
+
+	principal, err := tokens.Authenticate(context.Background(), token)
+	assert.NoError(t, err)
+	assert.Equal(t, Principal{UserID: 7, Scope: ScopeAdmin}, principal)
+
+	// the public key must not double as an HMAC secret
+	publicDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, claimsFor("1", time.Now().Add(time.Hour)))
+	forged.Header["kid"] = parsed.Header["kid"]
+	forgedToken, err := forged.SignedString(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}))
+	require.NoError(t, err)
+	_, err = tokens.Authenticate(context.Background(), forgedToken)
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
+
+func TestTokenAuthenticatorRotatesJWKS(t *testing.T) {
+	oldKey, newKey := newRSAKey(t), newRSAKey(t)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, path, map[string]*rsa.PrivateKey{"old": oldKey})
+	cfg := authConfig()
+	cfg.JWKSFile = path
+	tokens, err := NewTokenAuthenticator(cfg)
+	require.NoError(t, err)
+	now := time.Now()
+	tokens.now = func() time.Time { return now }
+
+	oldToken := signRS256(t, oldKey, "old", claimsFor("7", now.Add(time.Hour)))
+	newToken := signRS256(t, newKey, "new", claimsFor("8", now.Add(time.Hour)))
+	_, err = tokens.Authenticate(context.Background(), oldToken)
+	assert.NoError(t, err)
+	_, err = tokens.Authenticate(context.Background(), newToken)
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	writeJWKS(t, path, map[string]*rsa.PrivateKey{"new": newKey})
+	require.NoError(t, os.Chtimes(path, now, now.Add(time.Minute)))
+
+	// the file is not looked at again until the check interval has passed
+	_, err = tokens.Authenticate(context.Background(), oldToken)
+	assert.NoError(t, err)
+
+	now = now.Add(jwksCheckInterval)
+	principal, err := tokens.Authenticate(context.Background(), newToken)
+	assert.NoError(t, err)
+	assert.Equal(t, Principal{UserID: 8, Scope: ScopeAdmin}, principal)
+	_, err = tokens.Authenticate(context.Background(), oldToken)
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	// a broken file keeps the keys read before
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0o600))
+	require.NoError(t, os.Chtimes(path, now, now.Add(2*time.Minute)))
+	now = now.Add(jwksCheckInterval)
+	_, err = tokens.Authenticate(context.Background(), newToken)
+	assert.NoError(t, err)
+}
+
+func TestNewTokenAuthenticatorWhenKeyFilesInvalid(t *testing.T) {
+	cfg := authConfig()
+	cfg.SigningKeyFile = filepath.Join(t.TempDir(), "missing.pem")
+	_, err := NewTokenAuthenticator(cfg)
+	assert.Error(t, err)
+
+	cfg = authConfig()
+	cfg.JWKSFile = filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(cfg.JWKSFile, []byte(`{"keys":[{"kty":"RSA","n":"AQAB","e":"AQAB"}]}`), 0o600))
+	_, err = NewTokenAuthenticator(cfg)
+	assert.ErrorContains(t, err, "shorter than 2048 bits")
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mkmgo-todo/todo/migration"
+	"mkmgo-todo/todo/task"
+	"mkmgo-todo/todo/user"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	migrateUsage     = "usage: todo [flags] migrate up | down | status | to <version>"
+	assignTasksUsage = "usage: todo [flags] assign-tasks <email>"
+)
+
+// runCommand runs the subcommand named by args instead of serving.
+func runCommand(ctx context.Context, db *gorm.DB, migrator *migration.Migrator, args []string) error {
+	switch args[0] {
+	case "migrate":
+		return runMigrate(ctx, migrator, args[1:], os.Stdout)
+	case "assign-tasks":
+		if err := migrator.Check(ctx); err != nil {
+			return err
+		}
+		return runAssignTasks(ctx, task.NewTaskRepositoryImpl(db), user.NewUserRepositoryImpl(db), args[1:], os.Stdout)
+	default:
+		return fmt.Errorf("unknown command %q\n%s\n%s", args[0], migrateUsage, assignTasksUsage)
+	}
+}
+
+func runMigrate(ctx context.Context, migrator *migration.Migrator, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing migrate action\n%s", migrateUsage)
+	}
+	switch {
+	case args[0] == "up" && len(args) == 1:
+		if err := migrator.Up(ctx); err != nil {
+			return err
+		}
+	case args[0] == "down" && len(args) == 1:
+		if err := migrator.Down(ctx); err != nil {
+			return err
+		}
+	case args[0] == "to" && len(args) == 2:
+		version, err := strconv.ParseUint(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid version %q\n%s", args[1], migrateUsage)
+		}
+		if err := migrator.To(ctx, version); err != nil {
+			return err
+		}
+	case args[0] == "status" && len(args) == 1:
+	default:
+		return fmt.Errorf("invalid migrate action %q\n%s", strings.Join(args, " "), migrateUsage)
+	}
+	return printMigrationStatus(ctx, migrator, out)
+}
+
+func printMigrationStatus(ctx context.Context, migrator *migration.Migrator, out io.Writer) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, status := range statuses {
+		appliedAt := "pending"
+		if status.AppliedAt != nil {
+			appliedAt = status.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+	}
+	return w.Flush()
+}
+
+// runAssignTasks hands the tasks created before user accounts existed, which nobody can see, to
+// the account with the given email.
+func runAssignTasks(ctx context.Context, tasks *task.TaskRepositoryImpl, users *user.UserRepositoryImpl, args []string, out io.Writer) error {
+	if len(args) != 1 {
+		return fmt.Errorf("missing email\n%s", assignTasksUsage)
+	}
+	owner, err := users.GetUserByEmail(ctx, strings.ToLower(args[0]))
+	if err != nil {
+		return fmt.Errorf("%s: %w", args[0], err)
+	}
+	assigned, err := tasks.AssignUnownedTasks(ctx, owner.ID)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "assigned %d task(s) to %s\n", assigned, owner.Email)
+	return nil
+}
@@ -0,0 +1,360 @@
+// Package config loads the server configuration. Every setting has a default and can be overridden
+// by a YAML or TOML file, then by an environment variable, then by a command-line flag.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/rs/zerolog"
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	Server     ServerConfig     `yaml:"server" toml:"server"`
+	Database   DatabaseConfig   `yaml:"database" toml:"database"`
+	Log        LogConfig        `yaml:"log" toml:"log"`
+	Pagination PaginationConfig `yaml:"pagination" toml:"pagination"`
+	Trash      TrashConfig      `yaml:"trash" toml:"trash"`
+	Tracing    TracingConfig    `yaml:"tracing" toml:"tracing"`
+	Auth       AuthConfig       `yaml:"auth" toml:"auth"`
+
+	// Args holds the command-line arguments left after the flags, such as a subcommand.
+	Args []string `yaml:"-" toml:"-"`
+}
+
+type ServerConfig struct {
+	Addr            string        `yaml:"addr" toml:"addr"`
+	ReadTimeout     time.Duration `yaml:"readTimeout" toml:"readTimeout"`
+	WriteTimeout    time.Duration `yaml:"writeTimeout" toml:"writeTimeout"`
+	IdleTimeout     time.Duration `yaml:"idleTimeout" toml:"idleTimeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout" toml:"shutdownTimeout"`
+	// DrainDelay is how long the server keeps serving while reporting not-ready before it shuts
+	// down, giving load balancers time to notice.
+	DrainDelay time.Duration `yaml:"drainDelay" toml:"drainDelay"`
+}
+
+type DatabaseConfig struct {
+	Driver string `yaml:"driver" toml:"driver"` // sqlite or postgres
+	DSN    string `yaml:"dsn" toml:"dsn"`
+
+	// Connection pool tuning, zero leaves the database/sql default.
+	MaxOpenConns    int           `yaml:"maxOpenConns" toml:"maxOpenConns"`
+	MaxIdleConns    int           `yaml:"maxIdleConns" toml:"maxIdleConns"`
+	ConnMaxLifetime time.Duration `yaml:"connMaxLifetime" toml:"connMaxLifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"connMaxIdleTime" toml:"connMaxIdleTime"`
+}
+
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+type LogConfig struct {
+	Level string `yaml:"level" toml:"level"`
+}
+
+type PaginationConfig struct {
+	DefaultPageSize int `yaml:"defaultPageSize" toml:"defaultPageSize"`
+	MaxPageSize     int `yaml:"maxPageSize" toml:"maxPageSize"`
+}
+
+type TrashConfig struct {
+	Retention     time.Duration `yaml:"retention" toml:"retention"` // zero keeps deleted tasks forever
+	PurgeInterval time.Duration `yaml:"purgeInterval" toml:"purgeInterval"`
+}
+
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter" toml:"exporter"` // none, stdout or otlp
+	Endpoint    string  `yaml:"endpoint" toml:"endpoint"` // OTLP/HTTP collector URL, empty for the OTEL_EXPORTER_OTLP_* default
+	SampleRatio float64 `yaml:"sampleRatio" toml:"sampleRatio"`
+}
+
+const (
+	TracingNone   = "none"
+	TracingStdout = "stdout"
+	TracingOTLP   = "otlp"
+)
+
+// AuthConfig controls the access tokens issued at login. Tokens are signed with RS256 when a
+// signing key is set and with HS256 otherwise.
+type AuthConfig struct {
+	Issuer   string        `yaml:"issuer" toml:"issuer"`
+	TokenTTL time.Duration `yaml:"tokenTTL" toml:"tokenTTL"`
+	// Secret is the HS256 key, at least 32 bytes. Empty disables HS256, or when no signing key is
+	// set either, makes up a key that lasts until the server stops.
+	Secret         string `yaml:"secret" toml:"secret"`
+	SigningKeyFile string `yaml:"signingKeyFile" toml:"signingKeyFile"` // PEM RSA private key
+	SigningKeyID   string `yaml:"signingKeyID" toml:"signingKeyID"`     // kid header, defaults to the key's JWK thumbprint
+	// JWKSFile holds more RS256 public keys to accept, such as the next or previous signing key.
+	// It is read again whenever it changes.
+	JWKSFile string `yaml:"jwksFile" toml:"jwksFile"`
+
+	OIDC OIDCConfig `yaml:"oidc" toml:"oidc"`
+}
+
+// OIDCConfig names an OpenID Connect provider users may sign in with instead of a password.
+// Single sign-on is off while Issuer is empty.
+type OIDCConfig struct {
+	Issuer       string `yaml:"issuer" toml:"issuer"` // discovery document at Issuer/.well-known/openid-configuration
+	ClientID     string `yaml:"clientID" toml:"clientID"`
+	ClientSecret string `yaml:"clientSecret" toml:"clientSecret"` // empty for a public client
+	RedirectURL  string `yaml:"redirectURL" toml:"redirectURL"`   // this server's /todo/oidc/callback URL
+}
+
+// MinSecretLength is the shortest HS256 secret accepted, the size of the SHA-256 output.
+const MinSecretLength = 32
+
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Addr:            "localhost:8080",
+			ReadTimeout:     10 * time.Second,
+			WriteTimeout:    10 * time.Second,
+			IdleTimeout:     60 * time.Second,
+			ShutdownTimeout: 5 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Driver: DriverSQLite,
+			DSN:    "todo/gorm.db",
+		},
+		Log: LogConfig{
+			Level: "info",
+		},
+		Pagination: PaginationConfig{
+			DefaultPageSize: 10,
+			MaxPageSize:     100,
+		},
+		Trash: TrashConfig{
+			Retention:     30 * 24 * time.Hour,
+			PurgeInterval: time.Hour,
+		},
+		Tracing: TracingConfig{
+			Exporter:    TracingNone,
+			SampleRatio: 1,
+		},
+		Auth: AuthConfig{
+			Issuer:   "mkmgo-todo",
+			TokenTTL: time.Hour,
+		},
+	}
+}
+
+// setting ties a field to the environment variable and flag that override it.
+type setting struct {
+	env   string
+	flag  string
+	usage string
+	field func(*Config) any
+}
+
+var settings = []setting{
+	{"TODO_ADDR", "addr", "listen address", func(c *Config) any { return &c.Server.Addr }},
+	{"TODO_READ_TIMEOUT", "read-timeout", "maximum duration for reading a request", func(c *Config) any { return &c.Server.ReadTimeout }},
+	{"TODO_WRITE_TIMEOUT", "write-timeout", "maximum duration for writing a response", func(c *Config) any { return &c.Server.WriteTimeout }},
+	{"TODO_IDLE_TIMEOUT", "idle-timeout", "how long idle keep-alive connections stay open", func(c *Config) any { return &c.Server.IdleTimeout }},
+	{"TODO_SHUTDOWN_TIMEOUT", "shutdown-timeout", "how long to wait for requests to finish on shutdown", func(c *Config) any { return &c.Server.ShutdownTimeout }},
+	{"TODO_DRAIN_DELAY", "drain-delay", "how long to report not-ready before shutting down", func(c *Config) any { return &c.Server.DrainDelay }},
+	{"TODO_DB_DRIVER", "db-driver", "database driver: sqlite or postgres", func(c *Config) any { return &c.Database.Driver }},
+	{"TODO_DB_DSN", "db-dsn", "database data source name: a file path for sqlite, a URL or key=value string for postgres", func(c *Config) any { return &c.Database.DSN }},
+	{"TODO_DB_MAX_OPEN_CONNS", "db-max-open-conns", "maximum open database connections, 0 for unlimited", func(c *Config) any { return &c.Database.MaxOpenConns }},
+	{"TODO_DB_MAX_IDLE_CONNS", "db-max-idle-conns", "maximum idle database connections, 0 for the default", func(c *Config) any { return &c.Database.MaxIdleConns }},
+	{"TODO_DB_CONN_MAX_LIFETIME", "db-conn-max-lifetime", "how long a database connection may be reused, 0 for forever", func(c *Config) any { return &c.Database.ConnMaxLifetime }},
+	{"TODO_DB_CONN_MAX_IDLE_TIME", "db-conn-max-idle-time", "how long a database connection may sit idle, 0 for forever", func(c *Config) any { return &c.Database.ConnMaxIdleTime }},
+	{"TODO_LOG_LEVEL", "log-level", "log level: trace, debug, info, warn, error", func(c *Config) any { return &c.Log.Level }},
+	{"TODO_DEFAULT_PAGE_SIZE", "default-page-size", "page size when a listing asks for none", func(c *Config) any { return &c.Pagination.DefaultPageSize }},
+	{"TODO_MAX_PAGE_SIZE", "max-page-size", "largest page size a listing may ask for", func(c *Config) any { return &c.Pagination.MaxPageSize }},
+	{"TODO_TRASH_RETENTION", "trash-retention", "how long deleted tasks stay in the trash, 0 for forever", func(c *Config) any { return &c.Trash.Retention }},
+	{"TODO_TRASH_PURGE_INTERVAL", "trash-purge-interval", "how often expired tasks are purged from the trash", func(c *Config) any { return &c.Trash.PurgeInterval }},
+	{"TODO_TRACING_EXPORTER", "tracing-exporter", "where to send traces: none, stdout or otlp", func(c *Config) any { return &c.Tracing.Exporter }},
+	{"TODO_TRACING_ENDPOINT", "tracing-endpoint", "OTLP/HTTP collector URL, such as http://localhost:4318", func(c *Config) any { return &c.Tracing.Endpoint }},
+	{"TODO_TRACING_SAMPLE_RATIO", "tracing-sample-ratio", "fraction of new traces to record, from 0 to 1", func(c *Config) any { return &c.Tracing.SampleRatio }},
+	{"TODO_AUTH_ISSUER", "auth-issuer", "iss claim of issued access tokens", func(c *Config) any { return &c.Auth.Issuer }},
+	{"TODO_AUTH_TOKEN_TTL", "auth-token-ttl", "how long issued access tokens stay valid", func(c *Config) any { return &c.Auth.TokenTTL }},
+	{"TODO_AUTH_SECRET", "auth-secret", "HS256 token key of at least 32 bytes, better set in the environment", func(c *Config) any { return &c.Auth.Secret }},
+	{"TODO_AUTH_SIGNING_KEY_FILE", "auth-signing-key-file", "PEM RSA private key to sign RS256 tokens with", func(c *Config) any { return &c.Auth.SigningKeyFile }},
+	{"TODO_AUTH_SIGNING_KEY_ID", "auth-signing-key-id", "kid of the signing key, defaults to its JWK thumbprint", func(c *Config) any { return &c.Auth.SigningKeyID }},
+	{"TODO_AUTH_JWKS_FILE", "auth-jwks-file", "JWKS file of further RS256 public keys to accept", func(c *Config) any { return &c.Auth.JWKSFile }},
+	{"TODO_OIDC_ISSUER", "oidc-issuer", "OpenID Connect provider URL to sign in with, empty to turn single sign-on off", func(c *Config) any { return &c.Auth.OIDC.Issuer }},
+	{"TODO_OIDC_CLIENT_ID", "oidc-client-id", "client ID registered with the OpenID Connect provider", func(c *Config) any { return &c.Auth.OIDC.ClientID }},
+	{"TODO_OIDC_CLIENT_SECRET", "oidc-client-secret", "client secret registered with the OpenID Connect provider, better set in the environment", func(c *Config) any { return &c.Auth.OIDC.ClientSecret }},
+	{"TODO_OIDC_REDIRECT_URL", "oidc-redirect-url", "URL of this server's /todo/oidc/callback the provider sends users back to", func(c *Config) any { return &c.Auth.OIDC.RedirectURL }},
+}
+
+// Load builds the configuration from the defaults, the file named by -config or TODO_CONFIG, the
+// environment and the command-line arguments (without the program name), in rising precedence.
+func Load(args []string, getenv func(string) string) (*Config, error) {
+	fs := flag.NewFlagSet("todo", flag.ContinueOnError)
+	path := fs.String("config", getenv("TODO_CONFIG"), "path to a YAML or TOML configuration file")
+	flags := make(map[string]string)
+	for _, s := range settings {
+		fs.Func(s.flag, s.usage+" (env "+s.env+")", func(value string) error {
+			flags[s.flag] = value
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	cfg := Default()
+	if *path != "" {
+		if err := loadFile(*path, &cfg); err != nil {
+			return nil, err
+		}
+	}
+	for _, s := range settings {
+		if value := getenv(s.env); value != "" {
+			if err := set(s.field(&cfg), value); err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", s.env, err)
+			}
+		}
+		if value, ok := flags[s.flag]; ok {
+			if err := set(s.field(&cfg), value); err != nil {
+				return nil, fmt.Errorf("invalid -%s: %w", s.flag, err)
+			}
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		cfg.Args = fs.Args()
+	}
+	return &cfg, nil
+}
+
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+	case ".toml":
+		meta, err := toml.Decode(string(data), cfg)
+		if err != nil {
+			return fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("failed to parse config file %s: unknown key %s", path, undecoded[0])
+		}
+	default:
+		return fmt.Errorf("config file %s must end in .yaml, .yml or .toml", path)
+	}
+	return nil
+}
+
+func set(field any, value string) error {
+	switch field := field.(type) {
+	case *string:
+		*field = value
+	case *int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", value)
+		}
+		*field = n
+	case *float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		*field = f
+	case *time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration", value)
+		}
+		*field = d
+	default:
+		panic(fmt.Sprintf("config: unsupported setting type %T", field))
+	}
+	return nil
+}
+
+// Validate reports every invalid setting at once.
+func (c *Config) Validate() error {
+	var problems []string
+	if c.Server.Addr == "" {
+		problems = append(problems, "server.addr must not be empty")
+	}
+	if c.Server.ReadTimeout <= 0 || c.Server.WriteTimeout <= 0 || c.Server.IdleTimeout <= 0 || c.Server.ShutdownTimeout <= 0 {
+		problems = append(problems, "server timeouts must be positive")
+	}
+	if c.Server.DrainDelay < 0 {
+		problems = append(problems, "server.drainDelay must not be negative")
+	}
+	if c.Database.Driver != DriverSQLite && c.Database.Driver != DriverPostgres {
+		problems = append(problems, fmt.Sprintf("database.driver %q is not supported, must be sqlite or postgres", c.Database.Driver))
+	}
+	if c.Database.DSN == "" {
+		problems = append(problems, "database.dsn must not be empty")
+	}
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 || c.Database.ConnMaxLifetime < 0 || c.Database.ConnMaxIdleTime < 0 {
+		problems = append(problems, "database pool settings must not be negative")
+	}
+	if _, err := zerolog.ParseLevel(c.Log.Level); err != nil || c.Log.Level == "" {
+		problems = append(problems, fmt.Sprintf("log.level %q is not a log level", c.Log.Level))
+	}
+	if c.Pagination.MaxPageSize < 1 {
+		problems = append(problems, "pagination.maxPageSize must be at least 1")
+	}
+	if c.Pagination.DefaultPageSize < 1 || c.Pagination.DefaultPageSize > c.Pagination.MaxPageSize {
+		problems = append(problems, "pagination.defaultPageSize must be between 1 and pagination.maxPageSize")
+	}
+	if c.Trash.Retention < 0 {
+		problems = append(problems, "trash.retention must not be negative")
+	}
+	if c.Trash.PurgeInterval <= 0 {
+		problems = append(problems, "trash.purgeInterval must be positive")
+	}
+	switch c.Tracing.Exporter {
+	case TracingNone, TracingStdout, TracingOTLP:
+	default:
+		problems = append(problems, fmt.Sprintf("tracing.exporter %q is not supported, must be none, stdout or otlp", c.Tracing.Exporter))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		problems = append(problems, "tracing.sampleRatio must be between 0 and 1")
+	}
+	if c.Auth.Issuer == "" {
+		problems = append(problems, "auth.issuer must not be empty")
+	}
+	if c.Auth.TokenTTL <= 0 {
+		problems = append(problems, "auth.tokenTTL must be positive")
+	}
+	if c.Auth.Secret != "" && len(c.Auth.Secret) < MinSecretLength {
+		problems = append(problems, fmt.Sprintf("auth.secret must be at least %d bytes", MinSecretLength))
+	}
+	if c.Auth.SigningKeyID != "" && c.Auth.SigningKeyFile == "" {
+		problems = append(problems, "auth.signingKeyID needs auth.signingKeyFile")
+	}
+	if c.Auth.OIDC.Issuer != "" && (c.Auth.OIDC.ClientID == "" || c.Auth.OIDC.RedirectURL == "") {
+		problems = append(problems, "auth.oidc.clientID and auth.oidc.redirectURL must be set with auth.oidc.issuer")
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// LogLevel returns the parsed log level. It is only valid after Validate succeeded.
+func (c *Config) LogLevel() zerolog.Level {
+	level, _ := zerolog.ParseLevel(c.Log.Level)
+	return level
+}
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func env(values map[string]string) func(string) string {
+	return func(key string) string { return values[key] }
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+/* Unit test for Load */
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := Load(nil, env(nil))
+
+	assert.NoError(t, err)
+	assert.Equal(t, Default(), *cfg)
+}
+
+func TestLoadYAMLFile(t *testing.T) {
+	path := writeFile(t, "todo.yaml", `
+server:
+  addr: ":9090"
+  shutdownTimeout: 15s
+database:
+  dsn: /var/lib/todo/todo.db
+pagination:
+  defaultPageSize: 25
+`)
+
+	cfg, err := Load([]string{"-config", path}, env(nil))
+
+	assert.NoError(t, err)
+	assert.Equal(t, ":9090", cfg.Server.Addr)
+	assert.Equal(t, 15*time.Second, cfg.Server.ShutdownTimeout)
+	assert.Equal(t, "/var/lib/todo/todo.db", cfg.Database.DSN)
+	assert.Equal(t, 25, cfg.Pagination.DefaultPageSize)
+	assert.Equal(t, 10*time.Second, cfg.Server.ReadTimeout)
+}
+
+func TestLoadTOMLFile(t *testing.T) {
+	path := writeFile(t, "todo.toml", `
+[server]
+addr = ":9090"
+readTimeout = "3s"
+
+[trash]
+retention = "48h"
+`)
+
+	cfg, err := Load(nil, env(map[string]string{"TODO_CONFIG": path}))
+
+	assert.NoError(t, err)
+	assert.Equal(t, ":9090", cfg.Server.Addr)
+	assert.Equal(t, 3*time.Second, cfg.Server.ReadTimeout)
+	assert.Equal(t, 48*time.Hour, cfg.Trash.Retention)
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, "todo.yaml", `
+server:
+  addr: ":7000"
+log:
+  level: warn
+pagination:
+  maxPageSize: 50
+`)
+
+	cfg, err := Load(
+		[]string{"-config", path, "-addr", ":9000"},
+		env(map[string]string{"TODO_ADDR": ":8000", "TODO_LOG_LEVEL": "debug"}),
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, ":9000", cfg.Server.Addr)
+	assert.Equal(t, "debug", cfg.Log.Level)
+	assert.Equal(t, 50, cfg.Pagination.MaxPageSize)
+}
+
+func TestLoadWhenFileHasUnknownKey(t *testing.T) {
+	for name, content := range map[string]string{
+		"todo.yaml": "server:\n  adress: \":9090\"\n",
+		"todo.toml": "[server]\nadress = \":9090\"\n",
+	} {
+		_, err := Load([]string{"-config", writeFile(t, name, content)}, env(nil))
+
+		assert.ErrorContains(t, err, "adress", name)
+	}
+}
+
+func TestLoadWhenFileMissingOrUnsupported(t *testing.T) {
+	_, err := Load([]string{"-config", filepath.Join(t.TempDir(), "missing.yaml")}, env(nil))
+	assert.Error(t, err)
+
+	_, err = Load([]string{"-config", writeFile(t, "todo.json", "{}")}, env(nil))
+	assert.ErrorContains(t, err, ".toml")
+}
+
+func TestLoadWhenValueMalformed(t *testing.T) {
+	_, err := Load(nil, env(map[string]string{"TODO_SHUTDOWN_TIMEOUT": "soon"}))
+	assert.ErrorContains(t, err, "TODO_SHUTDOWN_TIMEOUT")
+
+	_, err = Load([]string{"-max-page-size", "lots"}, env(nil))
+	assert.ErrorContains(t, err, "-max-page-size")
+
+	_, err = Load([]string{"-tracing-sample-ratio", "half"}, env(nil))
+	assert.ErrorContains(t, err, "-tracing-sample-ratio")
+
+	_, err = Load([]string{"-no-such-flag"}, env(nil))
+	assert.Error(t, err)
+}
+
+/* Unit test for Validate */
+
+func TestValidate(t *testing.T) {
+	cfg := Default()
+	cfg.Database.Driver = "oracle"
+	cfg.Log.Level = "loud"
+	cfg.Pagination.DefaultPageSize = 500
+	cfg.Server.ShutdownTimeout = 0
+	cfg.Server.DrainDelay = -time.Second
+	cfg.Tracing.Exporter = "zipkin"
+	cfg.Tracing.SampleRatio = 2
+	cfg.Auth.TokenTTL = 0
+	cfg.Auth.Secret = "short"
+	cfg.Auth.OIDC.Issuer = "https://sso.example.com"
+
+	err := cfg.Validate()
+
+	assert.ErrorContains(t, err, "database.driver")
+	assert.ErrorContains(t, err, "log.level")
+	assert.ErrorContains(t, err, "pagination.defaultPageSize")
+	assert.ErrorContains(t, err, "server timeouts")
+	assert.ErrorContains(t, err, "server.drainDelay")
+	assert.ErrorContains(t, err, "tracing.exporter")
+	assert.ErrorContains(t, err, "tracing.sampleRatio")
+	assert.ErrorContains(t, err, "auth.tokenTTL")
+	assert.ErrorContains(t, err, "auth.secret")
+	assert.ErrorContains(t, err, "auth.oidc.clientID")
+}
+
+func TestLoadKeepsArguments(t *testing.T) {
+	cfg, err := Load([]string{"-addr", ":9000", "migrate", "to", "3"}, env(nil))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"migrate", "to", "3"}, cfg.Args)
+}
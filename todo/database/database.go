@@ -0,0 +1,56 @@
+// Package database opens the task store on one of the supported backends.
+package database
+
+import (
+	"fmt"
+	"mkmgo-todo/todo/config"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Open connects to the configured database and tunes its connection pool.
+//
+// Timestamps are written in UTC at microsecond precision, the finest Postgres keeps. SQLite compares
+// them as text, so a single zone and precision is what makes range filters, sorting and cursors
+// behave the same on both backends.
+func Open(cfg config.DatabaseConfig) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	switch cfg.Driver {
+	case config.DriverSQLite:
+		dialector = sqlite.Open(cfg.DSN)
+	case config.DriverPostgres:
+		dialector = postgres.Open(cfg.DSN)
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", cfg.Driver)
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{
+		TranslateError: true,
+		NowFunc:        Now,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s database: %w", cfg.Driver, err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s database: %w", cfg.Driver, err)
+	}
+	if cfg.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	return db, nil
+}
+
+// Now is the clock GORM uses for CreatedAt, UpdatedAt and DeletedAt.
+func Now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
@@ -0,0 +1,34 @@
+package database
+
+import (
+	"mkmgo-todo/todo/config"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenSQLite(t *testing.T) {
+	db, err := Open(config.DatabaseConfig{Driver: config.DriverSQLite, DSN: filepath.Join(t.TempDir(), "todo.db"), MaxOpenConns: 3})
+	assert.NoError(t, err)
+
+	sqlDB, err := db.DB()
+	assert.NoError(t, err)
+	defer sqlDB.Close()
+	assert.NoError(t, sqlDB.Ping())
+	assert.Equal(t, 3, sqlDB.Stats().MaxOpenConnections)
+}
+
+func TestOpenWhenDriverUnknown(t *testing.T) {
+	_, err := Open(config.DatabaseConfig{Driver: "oracle", DSN: "x"})
+
+	assert.ErrorContains(t, err, "oracle")
+}
+
+func TestNowIsUTCMicroseconds(t *testing.T) {
+	now := Now()
+
+	assert.Equal(t, time.UTC, now.Location())
+	assert.Zero(t, now.Nanosecond()%1000)
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"mkmgo-todo/todo/apikey"
+	"mkmgo-todo/todo/task"
+)
+
+type APIKeyService interface {
+	CreateAPIKey(ctx context.Context, request *apikey.CreateAPIKeyRequest) (*apikey.CreateAPIKeyResponse, error)
+	GetAPIKeys(ctx context.Context) ([]apikey.APIKeyResponse, error)
+	UpdateAPIKey(ctx context.Context, request *apikey.UpdateAPIKeyRequest) (*apikey.APIKeyResponse, error)
+	RevokeAPIKey(ctx context.Context, id uint64) error
+}
+
+type APIKeyHandler struct {
+	apiKeySvc APIKeyService
+}
+
+func NewAPIKeyHandler(service APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{apiKeySvc: service}
+}
+
+// CreateAPIKeyHandler creates a key and returns it. The key is never shown again.
+func (h *APIKeyHandler) CreateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	var req apikey.CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, r, "request body is not valid JSON")
+		return
+	}
+	res, err := h.apiKeySvc.CreateAPIKey(r.Context(), &req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeResponse(w, http.StatusCreated, res)
+}
+
+func (h *APIKeyHandler) GetAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	res, err := h.apiKeySvc.GetAPIKeys(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeResponse(w, http.StatusOK, map[string]any{"items": res})
+}
+
+// UpdateAPIKeyHandler relabels a key.
+func (h *APIKeyHandler) UpdateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	var req apikey.UpdateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, r, "request body is not valid JSON")
+		return
+	}
+	id, err := getIDFromRequest(r)
+	if err != nil {
+		writeInvalidAPIKeyID(w, r)
+		return
+	}
+	req.ID = id
+
+	res, err := h.apiKeySvc.UpdateAPIKey(r.Context(), &req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeResponse(w, http.StatusOK, res)
+}
+
+func (h *APIKeyHandler) RevokeAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromRequest(r)
+	if err != nil {
+		writeInvalidAPIKeyID(w, r)
+		return
+	}
+	if err := h.apiKeySvc.RevokeAPIKey(r.Context(), id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeResponse(w, http.StatusOK, fmt.Sprintf("API key %d revoked", id))
+}
+
+func writeInvalidAPIKeyID(w http.ResponseWriter, r *http.Request) {
+	writeBadRequest(w, r, "invalid API key ID", task.FieldError{Field: "id", Message: "must be a positive integer"})
+}
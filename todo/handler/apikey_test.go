@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mkmgo-todo/todo/apikey"
+	"mkmgo-todo/todo/auth"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+	Mock apikey/service.go
+*/
+
+type MockAPIKeyService struct {
+	CreateAPIKeyFunc func(ctx context.Context, request *apikey.CreateAPIKeyRequest) (*apikey.CreateAPIKeyResponse, error)
+	GetAPIKeysFunc   func(ctx context.Context) ([]apikey.APIKeyResponse, error)
+	UpdateAPIKeyFunc func(ctx context.Context, request *apikey.UpdateAPIKeyRequest) (*apikey.APIKeyResponse, error)
+	RevokeAPIKeyFunc func(ctx context.Context, id uint64) error
+}
+
+func (m *MockAPIKeyService) CreateAPIKey(ctx context.Context, request *apikey.CreateAPIKeyRequest) (*apikey.CreateAPIKeyResponse, error) {
+	if m.CreateAPIKeyFunc != nil {
+		return m.CreateAPIKeyFunc(ctx, request)
+	}
+	return nil, nil
+}
+
+func (m *MockAPIKeyService) GetAPIKeys(ctx context.Context) ([]apikey.APIKeyResponse, error) {
+	if m.GetAPIKeysFunc != nil {
+		return m.GetAPIKeysFunc(ctx)
+	}
+	return nil, nil
+}
+
+func (m *MockAPIKeyService) UpdateAPIKey(ctx context.Context, request *apikey.UpdateAPIKeyRequest) (*apikey.APIKeyResponse, error) {
+	if m.UpdateAPIKeyFunc != nil {
+		return m.UpdateAPIKeyFunc(ctx, request)
+	}
+	return nil, nil
+}
+
+func (m *MockAPIKeyService) RevokeAPIKey(ctx context.Context, id uint64) error {
+	if m.RevokeAPIKeyFunc != nil {
+		return m.RevokeAPIKeyFunc(ctx, id)
+	}
+	return nil
+}
+
+/*
+	Unit test for handler/apikey.go
+*/
+
+func TestCreateAPIKeyHandler(t *testing.T) {
+	mockService := &MockAPIKeyService{
+		CreateAPIKeyFunc: func(ctx context.Context, request *apikey.CreateAPIKeyRequest) (*apikey.CreateAPIKeyResponse, error) {
+			assert.Equal(t, &apikey.CreateAPIKeyRequest{Label: "ci", Scope: auth.ScopeReadWrite}, request)
+			return &apikey.CreateAPIKeyResponse{
+				APIKeyResponse: apikey.APIKeyResponse{ID: 1, Label: request.Label, Prefix: "todo_000000000001", Scope: request.Scope},
+				Key:            "todo_000000000001_secret",
+			}, nil
+		},
+	}
+
+	handler := NewAPIKeyHandler(mockService)
+	r := httptest.NewRequest(http.MethodPost, "/todo/api-keys", bytes.NewBufferString(`{"label":"ci","scope":"read-write"}`))
+	w := httptest.NewRecorder()
+	handler.CreateAPIKeyHandler(w, r)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	var respBody map[string]interface{}
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&respBody))
+	assert.Equal(t, "todo_000000000001_secret", respBody["key"])
+	assert.Equal(t, "todo_000000000001", respBody["prefix"])
+}
+
+func TestGetAPIKeysHandler(t *testing.T) {
+	mockService := &MockAPIKeyService{
+		GetAPIKeysFunc: func(ctx context.Context) ([]apikey.APIKeyResponse, error) {
+			return []apikey.APIKeyResponse{{ID: 1, Label: "ci", Prefix: "todo_000000000001", Scope: auth.ScopeReadOnly}}, nil
+		},
+	}
+
+	handler := NewAPIKeyHandler(mockService)
+	w := httptest.NewRecorder()
+	handler.GetAPIKeysHandler(w, httptest.NewRequest(http.MethodGet, "/todo/api-keys", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var respBody struct {
+		Items []map[string]interface{} `json:"items"`
+	}
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&respBody))
+	assert.Len(t, respBody.Items, 1)
+	assert.NotContains(t, respBody.Items[0], "key")
+}
+
+func TestUpdateAPIKeyHandler(t *testing.T) {
+	mockService := &MockAPIKeyService{
+		UpdateAPIKeyFunc: func(ctx context.Context, request *apikey.UpdateAPIKeyRequest) (*apikey.APIKeyResponse, error) {
+			return &apikey.APIKeyResponse{ID: request.ID, Label: request.Label}, nil
+		},
+	}
+
+	handler := NewAPIKeyHandler(mockService)
+	r := httptest.NewRequest(http.MethodPatch, "/todo/api-keys/1", bytes.NewBufferString(`{"label":"deploy"}`))
+	r = mux.SetURLVars(r, map[string]string{"id": "1"})
+	w := httptest.NewRecorder()
+	handler.UpdateAPIKeyHandler(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var respBody map[string]interface{}
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&respBody))
+	assert.Equal(t, "deploy", respBody["label"])
+}
+
+func TestRevokeAPIKeyHandler(t *testing.T) {
+	mockService := &MockAPIKeyService{
+		RevokeAPIKeyFunc: func(ctx context.Context, id uint64) error {
+			if id != 1 {
+				return apikey.ErrAPIKeyNotFound
+			}
+			return nil
+		},
+	}
+	handler := NewAPIKeyHandler(mockService)
+
+	for id, status := range map[string]int{"1": http.StatusOK, "2": http.StatusNotFound, "x": http.StatusBadRequest} {
+		r := httptest.NewRequest(http.MethodDelete, "/todo/api-keys/"+id, nil)
+		r = mux.SetURLVars(r, map[string]string{"id": id})
+		w := httptest.NewRecorder()
+		handler.RevokeAPIKeyHandler(w, r)
+
+		assert.Equal(t, status, w.Code, id)
+	}
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"mkmgo-todo/todo/auth"
+
+	"github.com/rs/zerolog"
+)
+
+const problemTypeUnauthorized = "urn:mkmgo-todo:problem:unauthorized"
+
+// Authenticate admits only requests whose Authorization header one of the authenticators accepts,
+// and puts the principal it names into the request context. Several authenticators may share a
+// scheme, such as Bearer access tokens and API keys; each is tried in turn. Anything else is
+// answered with 401 and a challenge for every scheme that would have been accepted.
+func Authenticate(authenticators ...auth.Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scheme, credentials, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+			rejected := ""
+			for _, authenticator := range authenticators {
+				if !strings.EqualFold(authenticator.Scheme(), scheme) {
+					continue
+				}
+				principal, err := authenticator.Authenticate(r.Context(), strings.TrimSpace(credentials))
+				if errors.Is(err, auth.ErrInvalidCredentials) {
+					rejected = authenticator.Scheme()
+					continue
+				}
+				if err != nil {
+					writeError(w, r, err)
+					return
+				}
+				log := zerolog.Ctx(r.Context()).With().Uint64("userId", principal.UserID).Logger()
+				ctx := auth.WithPrincipal(log.WithContext(r.Context()), principal)
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+			if rejected != "" {
+				writeUnauthorized(w, r, authenticators, rejected, "invalid credentials")
+				return
+			}
+			writeUnauthorized(w, r, authenticators, "", "authentication required")
+		})
+	}
+}
+
+// Authorize admits requests whose principal may make them: reading needs the read-only scope and
+// anything else read-write. It must run after Authenticate.
+func Authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		required := auth.ScopeReadWrite
+		if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			required = auth.ScopeReadOnly
+		}
+		requireScope(w, r, next, required)
+	})
+}
+
+// RequireScope admits only requests whose principal has the given scope. It must run after
+// Authenticate.
+func RequireScope(required auth.Scope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requireScope(w, r, next, required)
+		})
+	}
+}
+
+func requireScope(w http.ResponseWriter, r *http.Request, next http.Handler, required auth.Scope) {
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		writeError(w, r, errors.New("no principal in context, route is missing authentication"))
+		return
+	}
+	if !principal.Scope.Allows(required) {
+		// only API keys, sent as Bearer credentials, have scopes narrower than admin
+		w.Header().Set("WWW-Authenticate", `Bearer realm="todo", error="insufficient_scope", scope="`+string(required)+`"`)
+		writeProblem(w, newProblem(r, problemTypeForbidden, http.StatusForbidden, "this credential needs the "+string(required)+" scope"))
+		return
+	}
+	next.ServeHTTP(w, r)
+}
+
+// writeUnauthorized answers 401 with a challenge for each scheme of the authenticators. The
+// scheme that rejected the credentials, if any, says so where it can.
+func writeUnauthorized(w http.ResponseWriter, r *http.Request, authenticators []auth.Authenticator, rejected, detail string) {
+	challenged := make(map[string]bool)
+	for _, authenticator := range authenticators {
+		scheme := authenticator.Scheme()
+		if challenged[scheme] {
+			continue
+		}
+		challenged[scheme] = true
+		challenge := scheme + ` realm="todo"`
+		if scheme == rejected && strings.EqualFold(scheme, "Bearer") {
+			challenge += `, error="invalid_token"` // RFC 6750
+		}
+		w.Header().Add("WWW-Authenticate", challenge)
+	}
+	writeProblem(w, newProblem(r, problemTypeUnauthorized, http.StatusUnauthorized, detail))
+}
@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"mkmgo-todo/todo/auth"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+	Mock auth/authenticator.go
+*/
+
+type MockAuthenticator struct {
+	SchemeName       string
+	AuthenticateFunc func(ctx context.Context, credentials string) (auth.Principal, error)
+}
+
+func (m *MockAuthenticator) Scheme() string {
+	return m.SchemeName
+}
+
+func (m *MockAuthenticator) Authenticate(ctx context.Context, credentials string) (auth.Principal, error) {
+	if m.AuthenticateFunc != nil {
+		return m.AuthenticateFunc(ctx, credentials)
+	}
+	return auth.Principal{}, nil
+}
+
+/*
+	Unit test for handler/authenticate.go
+*/
+
+func TestAuthenticate(t *testing.T) {
+	basic := &MockAuthenticator{
+		SchemeName: "Basic",
+		AuthenticateFunc: func(ctx context.Context, credentials string) (auth.Principal, error) {
+			switch credentials {
+			case "good":
+				return auth.Principal{UserID: 7}, nil
+			case "broken":
+				return auth.Principal{}, errors.New("database is locked")
+			}
+			return auth.Principal{}, auth.ErrInvalidCredentials
+		},
+	}
+	var seen *auth.Principal
+	protected := Authenticate(basic)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := auth.PrincipalFromContext(r.Context())
+		seen = &principal
+	}))
+
+	tests := []struct {
+		authorization string
+		status        int
+	}{
+		{"basic good", http.StatusOK},
+		{"Basic bad", http.StatusUnauthorized},
+		{"Digest good", http.StatusUnauthorized},
+		{"", http.StatusUnauthorized},
+		{"Basic broken", http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		seen = nil
+		r := httptest.NewRequest(http.MethodGet, "/todo/tasks", nil)
+		if tt.authorization != "" {
+			r.Header.Set("Authorization", tt.authorization)
+		}
+		w := httptest.NewRecorder()
+		protected.ServeHTTP(w, r)
+
+		assert.Equal(t, tt.status, w.Code, tt.authorization)
+		if tt.status == http.StatusOK {
+			assert.Equal(t, &auth.Principal{UserID: 7}, seen)
+			continue
+		}
+		assert.Nil(t, seen, tt.authorization)
+		if tt.status == http.StatusUnauthorized {
+			assert.Equal(t, `Basic realm="todo"`, w.Header().Get("WWW-Authenticate"))
+			var problem Problem
+			assert.NoError(t, json.NewDecoder(w.Body).Decode(&problem))
+			assert.Equal(t, problemTypeUnauthorized, problem.Type)
+		}
+	}
+}
+
+func TestAuthenticateChallengesInvalidBearerToken(t *testing.T) {
+	bearer := &MockAuthenticator{
+		SchemeName: "Bearer",
+		AuthenticateFunc: func(ctx context.Context, credentials string) (auth.Principal, error) {
+			return auth.Principal{}, auth.ErrInvalidCredentials
+		},
+	}
+	basic := &MockAuthenticator{SchemeName: "Basic"}
+	protected := Authenticate(bearer, basic)(http.NotFoundHandler())
+
+	r := httptest.NewRequest(http.MethodGet, "/todo/tasks", nil)
+	w := httptest.NewRecorder()
+	protected.ServeHTTP(w, r)
+	assert.Equal(t, []string{`Bearer realm="todo"`, `Basic realm="todo"`}, w.Header().Values("WWW-Authenticate"))
+
+	r.Header.Set("Authorization", "Bearer expired")
+	w = httptest.NewRecorder()
+	protected.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, []string{`Bearer realm="todo", error="invalid_token"`, `Basic realm="todo"`}, w.Header().Values("WWW-Authenticate"))
+}
+
+func TestAuthenticateTriesEveryAuthenticatorOfScheme(t *testing.T) {
+	tokens := &MockAuthenticator{
+		SchemeName: "Bearer",
+		AuthenticateFunc: func(ctx context.Context, credentials string) (auth.Principal, error) {
+			return auth.Principal{}, auth.ErrInvalidCredentials
+		},
+	}
+	keys := &MockAuthenticator{
+		SchemeName: "Bearer",
+		AuthenticateFunc: func(ctx context.Context, credentials string) (auth.Principal, error) {
+			return auth.Principal{UserID: 7, Scope: auth.ScopeReadOnly}, nil
+		},
+	}
+	var seen auth.Principal
+	protected := Authenticate(tokens, keys)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = auth.PrincipalFromContext(r.Context())
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/todo/tasks", nil)
+	r.Header.Set("Authorization", "Bearer todo_key")
+	w := httptest.NewRecorder()
+	protected.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, auth.Principal{UserID: 7, Scope: auth.ScopeReadOnly}, seen)
+}
+
+func TestAuthorize(t *testing.T) {
+	tests := []struct {
+		scope  auth.Scope
+		method string
+		status int
+	}{
+		{auth.ScopeReadOnly, http.MethodGet, http.StatusOK},
+		{auth.ScopeReadOnly, http.MethodPost, http.StatusForbidden},
+		{auth.ScopeReadOnly, http.MethodDelete, http.StatusForbidden},
+		{auth.ScopeReadWrite, http.MethodPatch, http.StatusOK},
+		{auth.ScopeAdmin, http.MethodPut, http.StatusOK},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, "/todo/tasks", nil)
+		r = r.WithContext(auth.WithPrincipal(r.Context(), auth.Principal{UserID: 7, Scope: tt.scope}))
+		w := httptest.NewRecorder()
+		Authorize(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(w, r)
+
+		assert.Equal(t, tt.status, w.Code, string(tt.scope)+" "+tt.method)
+		if tt.status == http.StatusForbidden {
+			assert.Equal(t, `Bearer realm="todo", error="insufficient_scope", scope="read-write"`, w.Header().Get("WWW-Authenticate"))
+			var problem Problem
+			assert.NoError(t, json.NewDecoder(w.Body).Decode(&problem))
+			assert.Equal(t, problemTypeForbidden, problem.Type)
+		}
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	adminOnly := RequireScope(auth.ScopeAdmin)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for scope, status := range map[auth.Scope]int{auth.ScopeReadWrite: http.StatusForbidden, auth.ScopeAdmin: http.StatusOK} {
+		r := httptest.NewRequest(http.MethodGet, "/todo/api-keys", nil)
+		r = r.WithContext(auth.WithPrincipal(r.Context(), auth.Principal{UserID: 7, Scope: scope}))
+		w := httptest.NewRecorder()
+		adminOnly.ServeHTTP(w, r)
+
+		assert.Equal(t, status, w.Code, scope)
+	}
+
+	// without Authenticate in front the route is misconfigured, not open
+	w := httptest.NewRecorder()
+	adminOnly.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/todo/api-keys", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"mkmgo-todo/todo/auth"
+)
+
+// oidcCookie keeps a sign-in in progress in the browser, sealed, so that whichever server the
+// provider sends it back to can finish it while the browser can neither read nor change it.
+const (
+	oidcCookieName = "todo_oidc_login"
+	oidcCookiePath = "/todo/oidc"
+	oidcLoginTTL   = 10 * time.Minute
+)
+
+type OIDCProvider interface {
+	StartLogin() (auth.OIDCLogin, error)
+	FinishLogin(ctx context.Context, login auth.OIDCLogin, code string) (auth.Identity, error)
+}
+
+type Sealer interface {
+	Seal(value []byte) (string, error)
+	Open(sealed string) ([]byte, error)
+}
+
+type UserProvisioner interface {
+	ProvisionUser(ctx context.Context, identity auth.Identity) (uint64, error)
+	LinkUser(ctx context.Context, userID uint64, identity auth.Identity) error
+}
+
+// pendingLogin is what the sign-in cookie keeps: the login to finish and, when a signed-in user
+// started it to link their account, who they are.
+type pendingLogin struct {
+	auth.OIDCLogin
+	LinkUserID uint64 `json:",omitempty"`
+}
+
+type LinkResponse struct {
+	AuthorizationURL string `json:"authorizationUrl"`
+}
+
+type OIDCHandler struct {
+	provider OIDCProvider
+	users    UserProvisioner
+	issuer   TokenIssuer
+	sealer   Sealer
+	now      func() time.Time
+}
+
+func NewOIDCHandler(provider OIDCProvider, users UserProvisioner, issuer TokenIssuer, sealer Sealer) *OIDCHandler {
+	return &OIDCHandler{provider: provider, users: users, issuer: issuer, sealer: sealer, now: time.Now}
+}
+
+// LoginHandler sends the browser to the provider to sign in.
+func (h *OIDCHandler) LoginHandler(w http.ResponseWriter, r *http.Request) {
+	login, ok := h.startLogin(w, r, 0)
+	if !ok {
+		return
+	}
+	http.Redirect(w, r, login.URL, http.StatusFound)
+}
+
+// LinkHandler starts a sign-in that links the provider's user to the caller's account instead of
+// signing in as them. It answers with the URL to send the browser to, since a script holding the
+// caller's credentials makes the request.
+func (h *OIDCHandler) LinkHandler(w http.ResponseWriter, r *http.Request) {
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		writeError(w, r, errors.New("no principal in context, route is missing authentication"))
+		return
+	}
+	login, ok := h.startLogin(w, r, principal.UserID)
+	if !ok {
+		return
+	}
+	writeResponse(w, http.StatusOK, LinkResponse{AuthorizationURL: login.URL})
+}
+
+// startLogin starts a sign-in with the provider and keeps it in the sign-in cookie. It writes the
+// error response itself when it fails.
+func (h *OIDCHandler) startLogin(w http.ResponseWriter, r *http.Request, linkUserID uint64) (auth.OIDCLogin, bool) {
+	login, err := h.provider.StartLogin()
+	if err != nil {
+		writeError(w, r, err)
+		return login, false
+	}
+	// the URL goes to the provider anyway and need not be kept
+	kept := pendingLogin{OIDCLogin: login, LinkUserID: linkUserID}
+	kept.URL = ""
+	value, err := json.Marshal(kept)
+	if err != nil {
+		writeError(w, r, err)
+		return login, false
+	}
+	sealed, err := h.sealer.Seal(value)
+	if err != nil {
+		writeError(w, r, err)
+		return login, false
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcCookieName,
+		Value:    sealed,
+		Path:     oidcCookiePath,
+		MaxAge:   int(oidcLoginTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode, // the provider's redirect back is a top-level navigation
+	})
+	return login, true
+}
+
+// CallbackHandler finishes a sign-in when the provider sends the browser back, creating the
+// account on the user's first sign-in, and responds with an access token like password login. A
+// sign-in started by LinkHandler links the provider's user to the account that started it first.
+func (h *OIDCHandler) CallbackHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	login, ok := h.readLoginCookie(r)
+	http.SetCookie(w, &http.Cookie{Name: oidcCookieName, Path: oidcCookiePath, MaxAge: -1, HttpOnly: true, Secure: true})
+	if !ok || subtle.ConstantTimeCompare([]byte(query.Get("state")), []byte(login.State)) != 1 {
+		writeBadRequest(w, r, "sign-in expired or was started in another browser")
+		return
+	}
+	if query.Get("error") != "" {
+		writeUnauthorized(w, r, nil, "", "single sign-on was refused: "+query.Get("error"))
+		return
+	}
+
+	identity, err := h.provider.FinishLogin(r.Context(), login.OIDCLogin, query.Get("code"))
+	if errors.Is(err, auth.ErrOIDCRejected) {
+		writeUnauthorized(w, r, nil, "", "single sign-on failed")
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	userID := login.LinkUserID
+	if userID != 0 {
+		err = h.users.LinkUser(r.Context(), userID, identity)
+	} else {
+		userID, err = h.users.ProvisionUser(r.Context(), identity)
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeToken(w, r, h.issuer, h.now(), userID)
+}
+
+func (h *OIDCHandler) readLoginCookie(r *http.Request) (pendingLogin, bool) {
+	var login pendingLogin
+	cookie, err := r.Cookie(oidcCookieName)
+	if err != nil {
+		return login, false
+	}
+	value, err := h.sealer.Open(cookie.Value)
+	if err != nil || json.Unmarshal(value, &login) != nil || login.State == "" {
+		return login, false
+	}
+	return login, true
+}
@@ -0,0 +1,226 @@
+package handler
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"mkmgo-todo/todo/auth"
+	"mkmgo-todo/todo/user"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+/*
+	Mock auth/oidc.go and user/service.go
+*/
+
+type MockOIDCProvider struct {
+	StartLoginFunc  func() (auth.OIDCLogin, error)
+	FinishLoginFunc func(ctx context.Context, login auth.OIDCLogin, code string) (auth.Identity, error)
+}
+
+func (m *MockOIDCProvider) StartLogin() (auth.OIDCLogin, error) {
+	if m.StartLoginFunc != nil {
+		return m.StartLoginFunc()
+	}
+	return auth.OIDCLogin{URL: "https://sso.example.com/authorize?state=s1", State: "s1", Nonce: "n1", Verifier: "v1"}, nil
+}
+
+func (m *MockOIDCProvider) FinishLogin(ctx context.Context, login auth.OIDCLogin, code string) (auth.Identity, error) {
+	if m.FinishLoginFunc != nil {
+		return m.FinishLoginFunc(ctx, login, code)
+	}
+	return auth.Identity{}, nil
+}
+
+type MockUserProvisioner struct {
+	ProvisionUserFunc func(ctx context.Context, identity auth.Identity) (uint64, error)
+	LinkUserFunc      func(ctx context.Context, userID uint64, identity auth.Identity) error
+}
+
+func (m *MockUserProvisioner) ProvisionUser(ctx context.Context, identity auth.Identity) (uint64, error) {
+	if m.ProvisionUserFunc != nil {
+		return m.ProvisionUserFunc(ctx, identity)
+	}
+	return 0, nil
+}
+
+func newTestSealer(t *testing.T) *auth.Sealer {
+	sealer, err := auth.NewSealer([]byte("0123456789abcdef0123456789abcdef"), "oidc login")
+	require.NoError(t, err)
+	return sealer
+}
+
+func (m *MockUserProvisioner) LinkUser(ctx context.Context, userID uint64, identity auth.Identity) error {
+	if m.LinkUserFunc != nil {
+		return m.LinkUserFunc(ctx, userID, identity)
+	}
+	return nil
+}
+
+// startLogin runs LoginHandler and returns the cookie it sets.
+func startLogin(t *testing.T, handler *OIDCHandler) *http.Cookie {
+	w := httptest.NewRecorder()
+	handler.LoginHandler(w, httptest.NewRequest(http.MethodGet, "/todo/oidc/login", nil))
+
+	require.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "https://sso.example.com/authorize?state=s1", w.Header().Get("Location"))
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.True(t, cookies[0].HttpOnly)
+	assert.True(t, cookies[0].Secure)
+	return cookies[0]
+}
+
+func callback(handler *OIDCHandler, query string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/todo/oidc/callback?"+query, nil)
+	if cookie != nil {
+		r.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	handler.CallbackHandler(w, r)
+	return w
+}
+
+/*
+	Unit test for handler/oidc.go
+*/
+
+func TestOIDCCallbackHandler(t *testing.T) {
+	now := time.Now()
+	provider := &MockOIDCProvider{
+		FinishLoginFunc: func(ctx context.Context, login auth.OIDCLogin, code string) (auth.Identity, error) {
+			assert.Equal(t, auth.OIDCLogin{State: "s1", Nonce: "n1", Verifier: "v1"}, login)
+			assert.Equal(t, "c1", code)
+			return auth.Identity{Issuer: "https://sso.example.com", Subject: "u-1"}, nil
+		},
+	}
+	users := &MockUserProvisioner{
+		ProvisionUserFunc: func(ctx context.Context, identity auth.Identity) (uint64, error) {
+			return 7, nil
+		},
+	}
+	issuer := &MockTokenIssuer{
+		IssueTokenFunc: func(userID uint64) (string, time.Time, error) {
+			assert.Equal(t, uint64(7), userID)
+			return "token-for-7", now.Add(time.Hour), nil
+		},
+	}
+	handler := NewOIDCHandler(provider, users, issuer, newTestSealer(t))
+	handler.now = func() time.Time { return now }
+
+	w := callback(handler, "code=c1&state=s1", startLogin(t, handler))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"accessToken":"token-for-7","tokenType":"Bearer","expiresIn":3600}`, w.Body.String())
+	assert.Equal(t, -1, w.Result().Cookies()[0].MaxAge, "the sign-in cookie is cleared")
+}
+
+func TestOIDCCallbackHandlerWhenStateInvalid(t *testing.T) {
+	provider := &MockOIDCProvider{
+		FinishLoginFunc: func(ctx context.Context, login auth.OIDCLogin, code string) (auth.Identity, error) {
+			t.Fatal("the code must not be redeemed")
+			return auth.Identity{}, nil
+		},
+	}
+	handler := NewOIDCHandler(provider, &MockUserProvisioner{}, &MockTokenIssuer{}, newTestSealer(t))
+	cookie := startLogin(t, handler)
+
+	assert.Equal(t, http.StatusBadRequest, callback(handler, "code=c1&state=forged", cookie).Code)
+	assert.Equal(t, http.StatusBadRequest, callback(handler, "code=c1&state=s1", nil).Code)
+	assert.Equal(t, http.StatusBadRequest, callback(handler, "code=c1&state=s1", &http.Cookie{Name: oidcCookieName, Value: "!"}).Code)
+	assert.Equal(t, http.StatusUnauthorized, callback(handler, "error=access_denied&state=s1", cookie).Code)
+}
+
+func TestOIDCCallbackHandlerWhenCookieForged(t *testing.T) {
+	provider := &MockOIDCProvider{
+		FinishLoginFunc: func(ctx context.Context, login auth.OIDCLogin, code string) (auth.Identity, error) {
+			t.Fatal("the code must not be redeemed")
+			return auth.Identity{}, nil
+		},
+	}
+	handler := NewOIDCHandler(provider, &MockUserProvisioner{}, &MockTokenIssuer{}, newTestSealer(t))
+	cookie := startLogin(t, handler)
+	decoded, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+	require.NoError(t, err)
+	assert.False(t, json.Valid(decoded), "the login is not readable")
+
+	// a login of the attacker's choosing, encoded the way an unsealed cookie would be
+	forged, err := json.Marshal(auth.OIDCLogin{State: "s2", Nonce: "n2", Verifier: "v2"})
+	require.NoError(t, err)
+	forgedCookie := &http.Cookie{Name: oidcCookieName, Value: base64.RawURLEncoding.EncodeToString(forged)}
+	assert.Equal(t, http.StatusBadRequest, callback(handler, "code=c1&state=s2", forgedCookie).Code)
+
+	// a login sealed by a server with other keys
+	otherSealer, err := auth.NewSealer([]byte("fedcba9876543210fedcba9876543210"), "oidc login")
+	require.NoError(t, err)
+	sealed, err := otherSealer.Seal(forged)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, callback(handler, "code=c1&state=s2", &http.Cookie{Name: oidcCookieName, Value: sealed}).Code)
+}
+
+func TestOIDCCallbackHandlerWhenRejected(t *testing.T) {
+	provider := &MockOIDCProvider{
+		FinishLoginFunc: func(ctx context.Context, login auth.OIDCLogin, code string) (auth.Identity, error) {
+			return auth.Identity{}, auth.ErrOIDCRejected
+		},
+	}
+	handler := NewOIDCHandler(provider, &MockUserProvisioner{}, &MockTokenIssuer{}, newTestSealer(t))
+
+	w := callback(handler, "code=c1&state=s1", startLogin(t, handler))
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestOIDCCallbackHandlerWhenAccountTaken(t *testing.T) {
+	users := &MockUserProvisioner{
+		ProvisionUserFunc: func(ctx context.Context, identity auth.Identity) (uint64, error) {
+			return 0, user.ErrIdentityTaken
+		},
+	}
+	handler := NewOIDCHandler(&MockOIDCProvider{}, users, &MockTokenIssuer{}, newTestSealer(t))
+
+	w := callback(handler, "code=c1&state=s1", startLogin(t, handler))
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestOIDCLinkHandler(t *testing.T) {
+	var linked uint64
+	users := &MockUserProvisioner{
+		ProvisionUserFunc: func(ctx context.Context, identity auth.Identity) (uint64, error) {
+			t.Fatal("a link must not sign in as the provider's user")
+			return 0, nil
+		},
+		LinkUserFunc: func(ctx context.Context, userID uint64, identity auth.Identity) error {
+			assert.Equal(t, "u-1", identity.Subject)
+			linked = userID
+			return nil
+		},
+	}
+	provider := &MockOIDCProvider{
+		FinishLoginFunc: func(ctx context.Context, login auth.OIDCLogin, code string) (auth.Identity, error) {
+			assert.Equal(t, auth.OIDCLogin{State: "s1", Nonce: "n1", Verifier: "v1"}, login)
+			return auth.Identity{Issuer: "https://sso.example.com", Subject: "u-1"}, nil
+		},
+	}
+	handler := NewOIDCHandler(provider, users, &MockTokenIssuer{}, newTestSealer(t))
+
+	r := httptest.NewRequest(http.MethodPost, "/todo/oidc/link", nil)
+	r = r.WithContext(auth.WithPrincipal(r.Context(), auth.Principal{UserID: 7, Scope: auth.ScopeAdmin}))
+	w := httptest.NewRecorder()
+	handler.LinkHandler(w, r)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"authorizationUrl":"https://sso.example.com/authorize?state=s1"}`, w.Body.String())
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+
+	assert.Equal(t, http.StatusOK, callback(handler, "code=c1&state=s1", cookies[0]).Code)
+	assert.Equal(t, uint64(7), linked)
+}
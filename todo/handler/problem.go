@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"mkmgo-todo/todo/middleware"
+	"mkmgo-todo/todo/task"
+	"net/http"
+
+	"github.com/rs/zerolog"
+)
+
+const problemContentType = "application/problem+json"
+
+// Problem types identify the class of error independently of the human readable detail.
+// about:blank is used for plain HTTP errors that need no further explanation.
+const (
+	problemTypeBlank              = "about:blank"
+	problemTypeBadRequest         = "urn:mkmgo-todo:problem:bad-request"
+	problemTypeNotFound           = "urn:mkmgo-todo:problem:not-found"
+	problemTypeValidation         = "urn:mkmgo-todo:problem:validation"
+	problemTypeConflict           = "urn:mkmgo-todo:problem:conflict"
+	problemTypePreconditionFailed = "urn:mkmgo-todo:problem:precondition-failed"
+	problemTypeForbidden          = "urn:mkmgo-todo:problem:forbidden"
+	problemTypeInternal           = "urn:mkmgo-todo:problem:internal"
+)
+
+// Problem is an RFC 7807 problem details document.
+type Problem struct {
+	Type      string            `json:"type"`
+	Title     string            `json:"title"`
+	Status    int               `json:"status"`
+	Detail    string            `json:"detail,omitempty"`
+	Instance  string            `json:"instance,omitempty"`
+	Errors    []task.FieldError `json:"errors,omitempty"`
+	RequestID string            `json:"requestId,omitempty"`
+}
+
+func newProblem(r *http.Request, problemType string, status int, detail string) Problem {
+	return Problem{
+		Type:      problemType,
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  r.URL.RequestURI(),
+		RequestID: middleware.RequestIDFromContext(r.Context()),
+	}
+}
+
+func writeProblem(w http.ResponseWriter, problem Problem) {
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
+
+// writeBadRequest reports a request the handler could not even turn into a service call.
+func writeBadRequest(w http.ResponseWriter, r *http.Request, detail string, fields ...task.FieldError) {
+	problem := newProblem(r, problemTypeBadRequest, http.StatusBadRequest, detail)
+	problem.Errors = fields
+	writeProblem(w, problem)
+}
+
+func writeInvalidID(w http.ResponseWriter, r *http.Request) {
+	writeBadRequest(w, r, "invalid task ID", task.FieldError{Field: "id", Message: "must be a positive integer"})
+}
+
+// writeError responds with the problem matching the error kind. Errors of no known kind are
+// internal: they are logged and the client only learns that something went wrong.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var problem Problem
+	switch {
+	case errors.Is(err, task.ErrNotFound):
+		problem = newProblem(r, problemTypeNotFound, http.StatusNotFound, err.Error())
+	case errors.Is(err, task.ErrValidation):
+		problem = newProblem(r, problemTypeValidation, http.StatusUnprocessableEntity, err.Error())
+		var validationErr *task.ValidationError
+		if errors.As(err, &validationErr) {
+			problem.Errors = validationErr.Fields
+		}
+	case errors.Is(err, task.ErrConflict):
+		problem = newProblem(r, problemTypeConflict, http.StatusConflict, err.Error())
+	case errors.Is(err, task.ErrPreconditionFailed):
+		problem = newProblem(r, problemTypePreconditionFailed, http.StatusPreconditionFailed, err.Error())
+	case errors.Is(err, task.ErrForbidden):
+		problem = newProblem(r, problemTypeForbidden, http.StatusForbidden, err.Error())
+	default:
+		zerolog.Ctx(r.Context()).Error().Err(err).Str("path", r.URL.Path).Msg("request failed")
+		problem = newProblem(r, problemTypeInternal, http.StatusInternalServerError, "internal server error")
+	}
+	writeProblem(w, problem)
+}
+
+// NotFoundHandler answers requests no route matched.
+func NotFoundHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeProblem(w, newProblem(r, problemTypeNotFound, http.StatusNotFound, "no such resource"))
+	})
+}
+
+// MethodNotAllowedHandler answers requests whose path matched a route but not its method.
+func MethodNotAllowedHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeProblem(w, newProblem(r, problemTypeBlank, http.StatusMethodNotAllowed, r.Method+" is not supported on this resource"))
+	})
+}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mkmgo-todo/todo/task"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		err         error
+		status      int
+		problemType string
+	}{
+		{fmt.Errorf("%w: id 1", task.ErrTaskNotFound), http.StatusNotFound, problemTypeNotFound},
+		{task.ErrInvalidPatch, http.StatusUnprocessableEntity, problemTypeValidation},
+		{task.ErrInvalidStatusTransition, http.StatusConflict, problemTypeConflict},
+		{task.ErrTaskVersionMismatch, http.StatusPreconditionFailed, problemTypePreconditionFailed},
+		{task.ErrTaskForbidden, http.StatusForbidden, problemTypeForbidden},
+		{errors.New("database is locked"), http.StatusInternalServerError, problemTypeInternal},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/todo/tasks/1?x=y", nil)
+		w := httptest.NewRecorder()
+		writeError(w, r, tt.err)
+
+		resp := w.Result()
+		assert.Equal(t, tt.status, resp.StatusCode, tt.err.Error())
+		assert.Equal(t, problemContentType, resp.Header.Get("Content-Type"))
+
+		var problem Problem
+		err := json.NewDecoder(resp.Body).Decode(&problem)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.problemType, problem.Type)
+		assert.Equal(t, tt.status, problem.Status)
+		assert.Equal(t, http.StatusText(tt.status), problem.Title)
+		assert.Equal(t, "/todo/tasks/1?x=y", problem.Instance)
+		assert.NotContains(t, problem.Detail, "database")
+	}
+}
+
+func TestNotFoundHandler(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/todo/unknown", nil)
+	w := httptest.NewRecorder()
+	NotFoundHandler().ServeHTTP(w, r)
+
+	resp := w.Result()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, problemContentType, resp.Header.Get("Content-Type"))
+}
+
+func TestMethodNotAllowedHandler(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/todo/tasks/1", nil)
+	w := httptest.NewRecorder()
+	MethodNotAllowedHandler().ServeHTTP(w, r)
+
+	resp := w.Result()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	var problem Problem
+	err := json.NewDecoder(resp.Body).Decode(&problem)
+	assert.NoError(t, err)
+	assert.Equal(t, "POST is not supported on this resource", problem.Detail)
+}
@@ -0,0 +1,203 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"mkmgo-todo/todo/project"
+	"mkmgo-todo/todo/task"
+
+	"github.com/gorilla/mux"
+)
+
+type ProjectService interface {
+	CreateProject(ctx context.Context, request *project.CreateProjectRequest) (*project.ProjectResponse, error)
+	GetProjects(ctx context.Context) ([]project.ProjectResponse, error)
+	GetProject(ctx context.Context, id uint64) (*project.ProjectResponse, error)
+	UpdateProject(ctx context.Context, request *project.UpdateProjectRequest) (*project.ProjectResponse, error)
+	DeleteProject(ctx context.Context, id uint64) error
+	GetMembers(ctx context.Context, projectID uint64) ([]project.MemberResponse, error)
+	AddMember(ctx context.Context, request *project.AddMemberRequest) (*project.MemberResponse, error)
+	UpdateMember(ctx context.Context, request *project.UpdateMemberRequest) (*project.MemberResponse, error)
+	RemoveMember(ctx context.Context, projectID, userID uint64) error
+}
+
+type ProjectHandler struct {
+	projectSvc ProjectService
+}
+
+func NewProjectHandler(service ProjectService) *ProjectHandler {
+	return &ProjectHandler{projectSvc: service}
+}
+
+// CreateProjectHandler creates a project with the caller as its owner.
+func (h *ProjectHandler) CreateProjectHandler(w http.ResponseWriter, r *http.Request) {
+	var req project.CreateProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, r, "request body is not valid JSON")
+		return
+	}
+	res, err := h.projectSvc.CreateProject(r.Context(), &req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeResponse(w, http.StatusCreated, res)
+}
+
+// GetProjectsHandler lists the projects the caller is a member of, with their task counts.
+func (h *ProjectHandler) GetProjectsHandler(w http.ResponseWriter, r *http.Request) {
+	res, err := h.projectSvc.GetProjects(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeResponse(w, http.StatusOK, map[string]any{"items": res})
+}
+
+func (h *ProjectHandler) GetProjectHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromRequest(r)
+	if err != nil {
+		writeInvalidProjectID(w, r)
+		return
+	}
+	res, err := h.projectSvc.GetProject(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeResponse(w, http.StatusOK, res)
+}
+
+// UpdateProjectHandler renames a project.
+func (h *ProjectHandler) UpdateProjectHandler(w http.ResponseWriter, r *http.Request) {
+	var req project.UpdateProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, r, "request body is not valid JSON")
+		return
+	}
+	id, err := getIDFromRequest(r)
+	if err != nil {
+		writeInvalidProjectID(w, r)
+		return
+	}
+	req.ID = id
+
+	res, err := h.projectSvc.UpdateProject(r.Context(), &req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeResponse(w, http.StatusOK, res)
+}
+
+// DeleteProjectHandler deletes a project once it has no tasks left.
+func (h *ProjectHandler) DeleteProjectHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromRequest(r)
+	if err != nil {
+		writeInvalidProjectID(w, r)
+		return
+	}
+	if err := h.projectSvc.DeleteProject(r.Context(), id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeResponse(w, http.StatusOK, fmt.Sprintf("project %d deleted", id))
+}
+
+func (h *ProjectHandler) GetMembersHandler(w http.ResponseWriter, r *http.Request) {
+	projectID, err := getIDFromRequest(r)
+	if err != nil {
+		writeInvalidProjectID(w, r)
+		return
+	}
+	res, err := h.projectSvc.GetMembers(r.Context(), projectID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeResponse(w, http.StatusOK, map[string]any{"items": res})
+}
+
+// AddMemberHandler gives the account with the email in the body a role in the project.
+func (h *ProjectHandler) AddMemberHandler(w http.ResponseWriter, r *http.Request) {
+	var req project.AddMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, r, "request body is not valid JSON")
+		return
+	}
+	projectID, err := getIDFromRequest(r)
+	if err != nil {
+		writeInvalidProjectID(w, r)
+		return
+	}
+	req.ProjectID = projectID
+
+	res, err := h.projectSvc.AddMember(r.Context(), &req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeResponse(w, http.StatusCreated, res)
+}
+
+// UpdateMemberHandler changes the role of a member.
+func (h *ProjectHandler) UpdateMemberHandler(w http.ResponseWriter, r *http.Request) {
+	var req project.UpdateMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, r, "request body is not valid JSON")
+		return
+	}
+	projectID, err := getIDFromRequest(r)
+	if err != nil {
+		writeInvalidProjectID(w, r)
+		return
+	}
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		writeInvalidUserID(w, r)
+		return
+	}
+	req.ProjectID, req.UserID = projectID, userID
+
+	res, err := h.projectSvc.UpdateMember(r.Context(), &req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeResponse(w, http.StatusOK, res)
+}
+
+// RemoveMemberHandler takes a member out of the project; members may remove themselves to leave.
+func (h *ProjectHandler) RemoveMemberHandler(w http.ResponseWriter, r *http.Request) {
+	projectID, err := getIDFromRequest(r)
+	if err != nil {
+		writeInvalidProjectID(w, r)
+		return
+	}
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		writeInvalidUserID(w, r)
+		return
+	}
+	if err := h.projectSvc.RemoveMember(r.Context(), projectID, userID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeResponse(w, http.StatusOK, fmt.Sprintf("user %d removed from project %d", userID, projectID))
+}
+
+func getUserIDFromRequest(r *http.Request) (uint64, error) {
+	return strconv.ParseUint(mux.Vars(r)["userId"], 10, 64)
+}
+
+func writeInvalidProjectID(w http.ResponseWriter, r *http.Request) {
+	writeBadRequest(w, r, "invalid project ID", task.FieldError{Field: "id", Message: "must be a positive integer"})
+}
+
+func writeInvalidUserID(w http.ResponseWriter, r *http.Request) {
+	writeBadRequest(w, r, "invalid user ID", task.FieldError{Field: "userId", Message: "must be a positive integer"})
+}
@@ -0,0 +1,243 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mkmgo-todo/todo/auth"
+	"mkmgo-todo/todo/project"
+	"mkmgo-todo/todo/task"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+	Mock project/service.go
+*/
+
+type MockProjectService struct {
+	CreateProjectFunc func(ctx context.Context, request *project.CreateProjectRequest) (*project.ProjectResponse, error)
+	GetProjectsFunc   func(ctx context.Context) ([]project.ProjectResponse, error)
+	GetProjectFunc    func(ctx context.Context, id uint64) (*project.ProjectResponse, error)
+	UpdateProjectFunc func(ctx context.Context, request *project.UpdateProjectRequest) (*project.ProjectResponse, error)
+	DeleteProjectFunc func(ctx context.Context, id uint64) error
+	GetMembersFunc    func(ctx context.Context, projectID uint64) ([]project.MemberResponse, error)
+	AddMemberFunc     func(ctx context.Context, request *project.AddMemberRequest) (*project.MemberResponse, error)
+	UpdateMemberFunc  func(ctx context.Context, request *project.UpdateMemberRequest) (*project.MemberResponse, error)
+	RemoveMemberFunc  func(ctx context.Context, projectID, userID uint64) error
+}
+
+func (m *MockProjectService) CreateProject(ctx context.Context, request *project.CreateProjectRequest) (*project.ProjectResponse, error) {
+	if m.CreateProjectFunc != nil {
+		return m.CreateProjectFunc(ctx, request)
+	}
+	return nil, nil
+}
+
+func (m *MockProjectService) GetProjects(ctx context.Context) ([]project.ProjectResponse, error) {
+	if m.GetProjectsFunc != nil {
+		return m.GetProjectsFunc(ctx)
+	}
+	return nil, nil
+}
+
+func (m *MockProjectService) GetProject(ctx context.Context, id uint64) (*project.ProjectResponse, error) {
+	if m.GetProjectFunc != nil {
+		return m.GetProjectFunc(ctx, id)
+	}
+	return nil, nil
+}
+
+func (m *MockProjectService) UpdateProject(ctx context.Context, request *project.UpdateProjectRequest) (*project.ProjectResponse, error) {
+	if m.UpdateProjectFunc != nil {
+		return m.UpdateProjectFunc(ctx, request)
+	}
+	return nil, nil
+}
+
+func (m *MockProjectService) DeleteProject(ctx context.Context, id uint64) error {
+	if m.DeleteProjectFunc != nil {
+		return m.DeleteProjectFunc(ctx, id)
+	}
+	return nil
+}
+
+func (m *MockProjectService) GetMembers(ctx context.Context, projectID uint64) ([]project.MemberResponse, error) {
+	if m.GetMembersFunc != nil {
+		return m.GetMembersFunc(ctx, projectID)
+	}
+	return nil, nil
+}
+
+func (m *MockProjectService) AddMember(ctx context.Context, request *project.AddMemberRequest) (*project.MemberResponse, error) {
+	if m.AddMemberFunc != nil {
+		return m.AddMemberFunc(ctx, request)
+	}
+	return nil, nil
+}
+
+func (m *MockProjectService) UpdateMember(ctx context.Context, request *project.UpdateMemberRequest) (*project.MemberResponse, error) {
+	if m.UpdateMemberFunc != nil {
+		return m.UpdateMemberFunc(ctx, request)
+	}
+	return nil, nil
+}
+
+func (m *MockProjectService) RemoveMember(ctx context.Context, projectID, userID uint64) error {
+	if m.RemoveMemberFunc != nil {
+		return m.RemoveMemberFunc(ctx, projectID, userID)
+	}
+	return nil
+}
+
+/*
+	Unit test for handler/project.go
+*/
+
+func TestCreateProjectHandler(t *testing.T) {
+	mockService := &MockProjectService{
+		CreateProjectFunc: func(ctx context.Context, request *project.CreateProjectRequest) (*project.ProjectResponse, error) {
+			return &project.ProjectResponse{ID: 1, Name: request.Name, Role: auth.RoleOwner}, nil
+		},
+	}
+
+	handler := NewProjectHandler(mockService)
+	r := httptest.NewRequest(http.MethodPost, "/todo/projects", bytes.NewBufferString(`{"name":"Household"}`))
+	w := httptest.NewRecorder()
+	handler.CreateProjectHandler(w, r)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var respBody map[string]interface{}
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&respBody))
+	assert.Equal(t, "Household", respBody["name"])
+	assert.Equal(t, "owner", respBody["role"])
+}
+
+func TestGetProjectsHandler(t *testing.T) {
+	mockService := &MockProjectService{
+		GetProjectsFunc: func(ctx context.Context) ([]project.ProjectResponse, error) {
+			return []project.ProjectResponse{{ID: 1, Name: "Household", Role: auth.RoleOwner, TaskCounts: project.TaskCounts{Open: 3, Completed: 1}}}, nil
+		},
+	}
+
+	handler := NewProjectHandler(mockService)
+	w := httptest.NewRecorder()
+	handler.GetProjectsHandler(w, httptest.NewRequest(http.MethodGet, "/todo/projects", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var respBody struct {
+		Items []map[string]interface{} `json:"items"`
+	}
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&respBody))
+	assert.Len(t, respBody.Items, 1)
+	assert.Equal(t, float64(3), respBody.Items[0]["openTasks"])
+	assert.Equal(t, float64(1), respBody.Items[0]["completedTasks"])
+}
+
+func TestUpdateProjectHandler(t *testing.T) {
+	mockService := &MockProjectService{
+		UpdateProjectFunc: func(ctx context.Context, request *project.UpdateProjectRequest) (*project.ProjectResponse, error) {
+			assert.Equal(t, &project.UpdateProjectRequest{ID: 1, Name: "Chores"}, request)
+			return &project.ProjectResponse{ID: request.ID, Name: request.Name, Role: auth.RoleOwner}, nil
+		},
+	}
+
+	handler := NewProjectHandler(mockService)
+	r := httptest.NewRequest(http.MethodPatch, "/todo/projects/1", bytes.NewBufferString(`{"name":"Chores"}`))
+	r = mux.SetURLVars(r, map[string]string{"id": "1"})
+	w := httptest.NewRecorder()
+	handler.UpdateProjectHandler(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestDeleteProjectHandler(t *testing.T) {
+	mockService := &MockProjectService{
+		DeleteProjectFunc: func(ctx context.Context, id uint64) error {
+			if id != 1 {
+				return project.ErrProjectNotEmpty
+			}
+			return nil
+		},
+	}
+	handler := NewProjectHandler(mockService)
+
+	for id, status := range map[string]int{"1": http.StatusOK, "2": http.StatusConflict, "x": http.StatusBadRequest} {
+		r := httptest.NewRequest(http.MethodDelete, "/todo/projects/"+id, nil)
+		r = mux.SetURLVars(r, map[string]string{"id": id})
+		w := httptest.NewRecorder()
+		handler.DeleteProjectHandler(w, r)
+
+		assert.Equal(t, status, w.Code, id)
+	}
+}
+
+func TestAddMemberHandler(t *testing.T) {
+	mockService := &MockProjectService{
+		AddMemberFunc: func(ctx context.Context, request *project.AddMemberRequest) (*project.MemberResponse, error) {
+			assert.Equal(t, &project.AddMemberRequest{ProjectID: 1, Email: "ana@example.com", Role: auth.RoleEditor}, request)
+			return &project.MemberResponse{UserID: 9, Email: request.Email, Role: request.Role}, nil
+		},
+	}
+
+	handler := NewProjectHandler(mockService)
+	r := httptest.NewRequest(http.MethodPost, "/todo/projects/1/members", bytes.NewBufferString(`{"email":"ana@example.com","role":"editor"}`))
+	r = mux.SetURLVars(r, map[string]string{"id": "1"})
+	w := httptest.NewRecorder()
+	handler.AddMemberHandler(w, r)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+}
+
+func TestAddMemberHandlerWhenEmailCannotBeAdded(t *testing.T) {
+	mockService := &MockProjectService{
+		AddMemberFunc: func(ctx context.Context, request *project.AddMemberRequest) (*project.MemberResponse, error) {
+			return nil, &task.ValidationError{Fields: []task.FieldError{{Field: "email", Message: "cannot be added to the project"}}}
+		},
+	}
+
+	handler := NewProjectHandler(mockService)
+	r := httptest.NewRequest(http.MethodPost, "/todo/projects/1/members", bytes.NewBufferString(`{"email":"bob@example.com","role":"editor"}`))
+	r = mux.SetURLVars(r, map[string]string{"id": "1"})
+	w := httptest.NewRecorder()
+	handler.AddMemberHandler(w, r)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), "cannot be added to the project")
+}
+
+func TestUpdateMemberHandlerWhenNotOwner(t *testing.T) {
+	mockService := &MockProjectService{
+		UpdateMemberFunc: func(ctx context.Context, request *project.UpdateMemberRequest) (*project.MemberResponse, error) {
+			assert.Equal(t, uint64(9), request.UserID)
+			return nil, project.ErrNotOwner
+		},
+	}
+
+	handler := NewProjectHandler(mockService)
+	r := httptest.NewRequest(http.MethodPatch, "/todo/projects/1/members/9", bytes.NewBufferString(`{"role":"viewer"}`))
+	r = mux.SetURLVars(r, map[string]string{"id": "1", "userId": "9"})
+	w := httptest.NewRecorder()
+	handler.UpdateMemberHandler(w, r)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestRemoveMemberHandler(t *testing.T) {
+	handler := NewProjectHandler(&MockProjectService{})
+
+	r := httptest.NewRequest(http.MethodDelete, "/todo/projects/1/members/9", nil)
+	r = mux.SetURLVars(r, map[string]string{"id": "1", "userId": "9"})
+	w := httptest.NewRecorder()
+	handler.RemoveMemberHandler(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	r = mux.SetURLVars(r, map[string]string{"id": "1", "userId": "ana"})
+	w = httptest.NewRecorder()
+	handler.RemoveMemberHandler(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
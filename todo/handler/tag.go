@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"mkmgo-todo/todo/task"
+)
+
+type TagService interface {
+	GetTags(ctx context.Context, projectID uint64) ([]task.TagResponse, error)
+	CreateTag(ctx context.Context, request *task.CreateTagRequest) (*task.TagResponse, error)
+	UpdateTag(ctx context.Context, request *task.UpdateTagRequest) (*task.TagResponse, error)
+	DeleteTag(ctx context.Context, id uint64) error
+}
+
+type TagHandler struct {
+	tagSvc TagService
+}
+
+func NewTagHandler(service TagService) *TagHandler {
+	return &TagHandler{tagSvc: service}
+}
+
+// GetTagsHandler lists the caller's tags and those of their projects, or with ?projectId= the tags
+// of one project.
+func (h *TagHandler) GetTagsHandler(w http.ResponseWriter, r *http.Request) {
+	var projectID uint64
+	if value := r.URL.Query().Get("projectId"); value != "" {
+		var err error
+		if projectID, err = strconv.ParseUint(value, 10, 64); err != nil {
+			writeBadRequest(w, r, "invalid query parameters", task.FieldError{Field: "projectId", Message: "must be a positive integer"})
+			return
+		}
+	}
+	res, err := h.tagSvc.GetTags(r.Context(), projectID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeResponse(w, http.StatusOK, map[string]any{"items": res})
+}
+
+// CreateTagHandler creates a tag in the project given in the body, or one of the caller's own.
+func (h *TagHandler) CreateTagHandler(w http.ResponseWriter, r *http.Request) {
+	var req task.CreateTagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, r, "request body is not valid JSON")
+		return
+	}
+	res, err := h.tagSvc.CreateTag(r.Context(), &req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeResponse(w, http.StatusCreated, res)
+}
+
+// UpdateTagHandler renames a tag.
+func (h *TagHandler) UpdateTagHandler(w http.ResponseWriter, r *http.Request) {
+	var req task.UpdateTagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, r, "request body is not valid JSON")
+		return
+	}
+	id, err := getIDFromRequest(r)
+	if err != nil {
+		writeInvalidTagID(w, r)
+		return
+	}
+	req.ID = id
+
+	res, err := h.tagSvc.UpdateTag(r.Context(), &req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeResponse(w, http.StatusOK, res)
+}
+
+// DeleteTagHandler deletes a tag and takes it off every task.
+func (h *TagHandler) DeleteTagHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromRequest(r)
+	if err != nil {
+		writeInvalidTagID(w, r)
+		return
+	}
+	if err := h.tagSvc.DeleteTag(r.Context(), id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeResponse(w, http.StatusOK, fmt.Sprintf("tag %d deleted", id))
+}
+
+func writeInvalidTagID(w http.ResponseWriter, r *http.Request) {
+	writeBadRequest(w, r, "invalid tag ID", task.FieldError{Field: "id", Message: "must be a positive integer"})
+}
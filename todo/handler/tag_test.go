@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mkmgo-todo/todo/task"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+	Mock task/tag_service.go
+*/
+
+type MockTagService struct {
+	GetTagsFunc   func(ctx context.Context, projectID uint64) ([]task.TagResponse, error)
+	CreateTagFunc func(ctx context.Context, request *task.CreateTagRequest) (*task.TagResponse, error)
+	UpdateTagFunc func(ctx context.Context, request *task.UpdateTagRequest) (*task.TagResponse, error)
+	DeleteTagFunc func(ctx context.Context, id uint64) error
+}
+
+func (m *MockTagService) GetTags(ctx context.Context, projectID uint64) ([]task.TagResponse, error) {
+	if m.GetTagsFunc != nil {
+		return m.GetTagsFunc(ctx, projectID)
+	}
+	return nil, nil
+}
+
+func (m *MockTagService) CreateTag(ctx context.Context, request *task.CreateTagRequest) (*task.TagResponse, error) {
+	if m.CreateTagFunc != nil {
+		return m.CreateTagFunc(ctx, request)
+	}
+	return nil, nil
+}
+
+func (m *MockTagService) UpdateTag(ctx context.Context, request *task.UpdateTagRequest) (*task.TagResponse, error) {
+	if m.UpdateTagFunc != nil {
+		return m.UpdateTagFunc(ctx, request)
+	}
+	return nil, nil
+}
+
+func (m *MockTagService) DeleteTag(ctx context.Context, id uint64) error {
+	if m.DeleteTagFunc != nil {
+		return m.DeleteTagFunc(ctx, id)
+	}
+	return nil
+}
+
+/*
+	Unit test for handler/tag.go
+*/
+
+func TestGetTagsHandler(t *testing.T) {
+	var got uint64
+	mockService := &MockTagService{
+		GetTagsFunc: func(ctx context.Context, projectID uint64) ([]task.TagResponse, error) {
+			got = projectID
+			return []task.TagResponse{{ID: 3, Name: "backend", ProjectID: projectID}}, nil
+		},
+	}
+
+	handler := NewTagHandler(mockService)
+	w := httptest.NewRecorder()
+	handler.GetTagsHandler(w, httptest.NewRequest(http.MethodGet, "/todo/tags?projectId=5", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, uint64(5), got)
+	var respBody struct {
+		Items []task.TagResponse `json:"items"`
+	}
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&respBody))
+	assert.Equal(t, []task.TagResponse{{ID: 3, Name: "backend", ProjectID: 5}}, respBody.Items)
+}
+
+func TestGetTagsHandlerWhenInvalidProjectID(t *testing.T) {
+	handler := NewTagHandler(&MockTagService{
+		GetTagsFunc: func(ctx context.Context, projectID uint64) ([]task.TagResponse, error) {
+			t.Fatal("tags must not be listed for an invalid project")
+			return nil, nil
+		},
+	})
+	w := httptest.NewRecorder()
+	handler.GetTagsHandler(w, httptest.NewRequest(http.MethodGet, "/todo/tags?projectId=x", nil))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestCreateTagHandler(t *testing.T) {
+	mockService := &MockTagService{
+		CreateTagFunc: func(ctx context.Context, request *task.CreateTagRequest) (*task.TagResponse, error) {
+			assert.Equal(t, &task.CreateTagRequest{Name: "Urgent"}, request)
+			return &task.TagResponse{ID: 3, Name: "urgent"}, nil
+		},
+	}
+
+	handler := NewTagHandler(mockService)
+	r := httptest.NewRequest(http.MethodPost, "/todo/tags", bytes.NewBufferString(`{"name":"Urgent"}`))
+	w := httptest.NewRecorder()
+	handler.CreateTagHandler(w, r)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var respBody map[string]interface{}
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&respBody))
+	assert.Equal(t, "urgent", respBody["name"])
+	assert.NotContains(t, respBody, "projectId")
+}
+
+func TestUpdateTagHandlerWhenNameTaken(t *testing.T) {
+	mockService := &MockTagService{
+		UpdateTagFunc: func(ctx context.Context, request *task.UpdateTagRequest) (*task.TagResponse, error) {
+			assert.Equal(t, &task.UpdateTagRequest{ID: 3, Name: "home"}, request)
+			return nil, task.ErrTagExists
+		},
+	}
+
+	handler := NewTagHandler(mockService)
+	r := httptest.NewRequest(http.MethodPatch, "/todo/tags/3", bytes.NewBufferString(`{"name":"home"}`))
+	r = mux.SetURLVars(r, map[string]string{"id": "3"})
+	w := httptest.NewRecorder()
+	handler.UpdateTagHandler(w, r)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestDeleteTagHandler(t *testing.T) {
+	mockService := &MockTagService{
+		DeleteTagFunc: func(ctx context.Context, id uint64) error {
+			if id != 3 {
+				return task.ErrTagNotFound
+			}
+			return nil
+		},
+	}
+	handler := NewTagHandler(mockService)
+
+	for id, status := range map[string]int{"3": http.StatusOK, "4": http.StatusNotFound, "x": http.StatusBadRequest} {
+		r := httptest.NewRequest(http.MethodDelete, "/todo/tags/"+id, nil)
+		r = mux.SetURLVars(r, map[string]string{"id": id})
+		w := httptest.NewRecorder()
+		handler.DeleteTagHandler(w, r)
+
+		assert.Equal(t, status, w.Code, id)
+	}
+}
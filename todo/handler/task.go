@@ -3,19 +3,33 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"mkmgo-todo/todo/pagination"
+	"mkmgo-todo/todo/patch"
 	"mkmgo-todo/todo/task"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 )
 
 type TaskService interface {
 	SaveTask(ctx context.Context, request *task.WriteTaskRequest) (*task.GetTaskResponse, error)
-	GetAllTasks(ctx context.Context, request task.GetAllTaskRequest) ([]task.GetTaskResponse, error)
+	PatchTask(ctx context.Context, request *task.PatchTaskRequest) (*task.GetTaskResponse, error)
+	GetTask(ctx context.Context, id uint64) (*task.GetTaskResponse, error)
+	GetAllTasks(ctx context.Context, request task.GetAllTaskRequest) (*pagination.PaginationResponse[task.GetTaskResponse], error)
+	UpdateTaskStatus(ctx context.Context, request *task.UpdateTaskStatusRequest) (*task.GetTaskResponse, error)
+	CompleteTask(ctx context.Context, id uint64) (*task.GetTaskResponse, error)
+	ReopenTask(ctx context.Context, id uint64) (*task.GetTaskResponse, error)
 	DeleteTask(ctx context.Context, id uint64) error
+	GetTrash(ctx context.Context, request task.GetAllTaskRequest) (*pagination.PaginationResponse[task.GetTaskResponse], error)
+	RestoreTask(ctx context.Context, id uint64) (*task.GetTaskResponse, error)
+	PurgeTask(ctx context.Context, id uint64) error
 }
 
 type TaskHandler struct {
@@ -26,73 +40,386 @@ func NewTaskHandler(service TaskService) *TaskHandler {
 	return &TaskHandler{taskSvc: service}
 }
 
+// WriteTaskHandler creates a task. Replacing one takes PUT /tasks/{id}, so an id in the body is
+// ignored.
 func (h *TaskHandler) WriteTaskHandler(w http.ResponseWriter, r *http.Request) {
 	var req task.WriteTaskRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeResponse(w, http.StatusBadRequest, "Invalid request")
+		writeBadRequest(w, r, "request body is not valid JSON")
 		return
 	}
+	req.ID = 0
+
 	res, err := h.taskSvc.SaveTask(r.Context(), &req)
 	if err != nil {
-		writeResponse(w, http.StatusInternalServerError, err.Error())
+		writeError(w, r, err)
 		return
 	}
-	writeResponse(w, http.StatusOK, res)
+	writeTaskResponse(w, http.StatusOK, res)
 }
 
+// UpdateTaskHandler partially updates a task. The body is a JSON Merge Patch unless the request is
+// sent as application/json-patch+json.
 func (h *TaskHandler) UpdateTaskHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeBadRequest(w, r, "request body could not be read")
+		return
+	}
+
+	var p patch.Patch
+	switch mediaType(r) {
+	case "", "application/json", patch.MergePatchContentType:
+		p, err = patch.NewMergePatch(body)
+	case patch.JSONPatchContentType:
+		p, err = patch.NewJSONPatch(body)
+	default:
+		w.Header().Set("Accept-Patch", patch.MergePatchContentType+", "+patch.JSONPatchContentType)
+		writeProblem(w, newProblem(r, problemTypeBlank, http.StatusUnsupportedMediaType, "unsupported patch format "+mediaType(r)))
+		return
+	}
+	if err != nil {
+		writeBadRequest(w, r, err.Error())
+		return
+	}
+
+	id, err := getIDFromRequest(r)
+	if err != nil {
+		writeInvalidID(w, r)
+		return
+	}
+
+	request := task.PatchTaskRequest{ID: id, Patch: p, IfMatch: r.Header.Get("If-Match")}
+	res, err := h.taskSvc.PatchTask(r.Context(), &request)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeTaskResponse(w, http.StatusOK, res)
+}
+
+// ReplaceTaskHandler fully replaces a task; fields left out of the body are cleared.
+func (h *TaskHandler) ReplaceTaskHandler(w http.ResponseWriter, r *http.Request) {
 	var req task.WriteTaskRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeResponse(w, http.StatusBadRequest, "Invalid request")
+		writeBadRequest(w, r, "request body is not valid JSON")
 		return
 	}
 
 	id, err := getIDFromRequest(r)
 	if err != nil {
-		writeResponse(w, http.StatusBadRequest, "Invalid ID")
+		writeInvalidID(w, r)
 		return
 	}
 	req.ID = id
+	req.IfMatch = r.Header.Get("If-Match")
 
 	res, err := h.taskSvc.SaveTask(r.Context(), &req)
 	if err != nil {
-		writeResponse(w, http.StatusInternalServerError, err.Error())
+		writeError(w, r, err)
 		return
 	}
-	writeResponse(w, http.StatusOK, res)
+	writeTaskResponse(w, http.StatusOK, res)
+}
+
+func (h *TaskHandler) GetTaskHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromRequest(r)
+	if err != nil {
+		writeInvalidID(w, r)
+		return
+	}
+
+	res, err := h.taskSvc.GetTask(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeTaskResponse(w, http.StatusOK, res)
 }
 
 func (h *TaskHandler) GetAllTaskHandler(w http.ResponseWriter, r *http.Request) {
-	pagination := pagination.NewPaginationRequest(r)
-	request := task.GetAllTaskRequest{PaginationRequest: pagination}
+	request, invalid := newGetAllTaskRequest(r)
+	if invalid != nil {
+		writeBadRequest(w, r, "invalid query parameters", invalid.Fields...)
+		return
+	}
+
+	res, err := h.taskSvc.GetAllTasks(r.Context(), request)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Link", res.Link(r.URL))
+	writeResponse(w, http.StatusOK, res)
+}
+
+// GetProjectTasksHandler lists the tasks of one project, taking the same query parameters as
+// GetAllTaskHandler.
+func (h *TaskHandler) GetProjectTasksHandler(w http.ResponseWriter, r *http.Request) {
+	projectID, err := getIDFromRequest(r)
+	if err != nil {
+		writeInvalidProjectID(w, r)
+		return
+	}
+	request, invalid := newGetAllTaskRequest(r)
+	if invalid != nil {
+		writeBadRequest(w, r, "invalid query parameters", invalid.Fields...)
+		return
+	}
+	request.ProjectID = projectID
 
 	res, err := h.taskSvc.GetAllTasks(r.Context(), request)
 	if err != nil {
-		writeResponse(w, http.StatusInternalServerError, err.Error())
+		writeError(w, r, err)
 		return
 	}
+	w.Header().Set("Link", res.Link(r.URL))
 	writeResponse(w, http.StatusOK, res)
 }
 
+// WriteProjectTaskHandler creates a task in the project of the path. It never replaces one, even
+// when the body names an id.
+func (h *TaskHandler) WriteProjectTaskHandler(w http.ResponseWriter, r *http.Request) {
+	var req task.WriteTaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, r, "request body is not valid JSON")
+		return
+	}
+	projectID, err := getIDFromRequest(r)
+	if err != nil {
+		writeInvalidProjectID(w, r)
+		return
+	}
+	req.ID = 0
+	req.ProjectID = projectID
+
+	res, err := h.taskSvc.SaveTask(r.Context(), &req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeTaskResponse(w, http.StatusOK, res)
+}
+
+func (h *TaskHandler) UpdateTaskStatusHandler(w http.ResponseWriter, r *http.Request) {
+	var req task.UpdateTaskStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, r, "request body is not valid JSON")
+		return
+	}
+
+	id, err := getIDFromRequest(r)
+	if err != nil {
+		writeInvalidID(w, r)
+		return
+	}
+	req.ID = id
+	req.IfMatch = r.Header.Get("If-Match")
+
+	res, err := h.taskSvc.UpdateTaskStatus(r.Context(), &req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeTaskResponse(w, http.StatusOK, res)
+}
+
+func (h *TaskHandler) CompleteTaskHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromRequest(r)
+	if err != nil {
+		writeInvalidID(w, r)
+		return
+	}
+
+	res, err := h.taskSvc.CompleteTask(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeTaskResponse(w, http.StatusOK, res)
+}
+
+func (h *TaskHandler) ReopenTaskHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromRequest(r)
+	if err != nil {
+		writeInvalidID(w, r)
+		return
+	}
+
+	res, err := h.taskSvc.ReopenTask(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeTaskResponse(w, http.StatusOK, res)
+}
+
 func (h *TaskHandler) DeleteTaskHandler(w http.ResponseWriter, r *http.Request) {
 	id, err := getIDFromRequest(r)
 	if err != nil {
-		writeResponse(w, http.StatusBadRequest, "Invalid ID")
+		writeInvalidID(w, r)
+		return
+	}
+
+	permanent := false
+	if value := r.URL.Query().Get("permanent"); value != "" {
+		if permanent, err = strconv.ParseBool(value); err != nil {
+			writeBadRequest(w, r, "invalid query parameters", task.FieldError{Field: "permanent", Message: "must be true or false"})
+			return
+		}
+	}
+
+	if permanent {
+		if err := h.taskSvc.PurgeTask(r.Context(), id); err != nil {
+			writeError(w, r, err)
+			return
+		}
+		writeResponse(w, http.StatusOK, fmt.Sprintf("Task %d permanently deleted", id))
 		return
 	}
 
 	if err := h.taskSvc.DeleteTask(r.Context(), id); err != nil {
-		writeResponse(w, http.StatusInternalServerError, err.Error())
+		writeError(w, r, err)
 		return
 	}
 	writeResponse(w, http.StatusOK, fmt.Sprintf("Task %d deleted", id))
 }
 
+func (h *TaskHandler) GetTrashHandler(w http.ResponseWriter, r *http.Request) {
+	request, invalid := newTrashRequest(r)
+	if invalid != nil {
+		writeBadRequest(w, r, "invalid query parameters", invalid.Fields...)
+		return
+	}
+
+	res, err := h.taskSvc.GetTrash(r.Context(), request)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Link", res.Link(r.URL))
+	writeResponse(w, http.StatusOK, res)
+}
+
+func (h *TaskHandler) RestoreTaskHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromRequest(r)
+	if err != nil {
+		writeInvalidID(w, r)
+		return
+	}
+
+	res, err := h.taskSvc.RestoreTask(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeTaskResponse(w, http.StatusOK, res)
+}
+
+func newGetAllTaskRequest(r *http.Request) (task.GetAllTaskRequest, *task.ValidationError) {
+	sortable := task.SortableFields()
+	if r.URL.Query().Has("cursor") {
+		sortable = task.KeysetSortableFields()
+	}
+	return newListRequest(r, sortable, task.DefaultSort)
+}
+
+// newTrashRequest reads the same filters as a task listing. The trash is paged by offset only.
+func newTrashRequest(r *http.Request) (task.GetAllTaskRequest, *task.ValidationError) {
+	if r.URL.Query().Has("cursor") {
+		return task.GetAllTaskRequest{}, &task.ValidationError{Fields: []task.FieldError{{Field: "cursor", Message: "is not supported for the trash"}}}
+	}
+	return newListRequest(r, task.TrashSortableFields(), task.DefaultTrashSort)
+}
+
+func newListRequest(r *http.Request, sortable []string, defaultSort []pagination.SortField) (task.GetAllTaskRequest, *task.ValidationError) {
+	query := r.URL.Query()
+	request := task.GetAllTaskRequest{
+		Due:      task.DueFilter(query.Get("due")),
+		TagsAny:  parseListParam(query.Get("tagsAny")),
+		TagsAll:  parseListParam(query.Get("tagsAll")),
+		TagsNone: parseListParam(query.Get("tagsNone")),
+	}
+
+	var fields []task.FieldError
+	var err error
+	request.PaginationRequest, err = pagination.NewPaginationRequest(r, sortable, defaultSort...)
+	var paramErr *pagination.ParameterError
+	if errors.As(err, &paramErr) {
+		fields = append(fields, task.FieldError{Field: paramErr.Parameter, Message: paramErr.Message})
+	}
+	if request.Due != "" && !request.Due.IsValid() {
+		fields = append(fields, task.FieldError{Field: "due", Message: "must be one of overdue, today, week"})
+	}
+	if request.DueFrom, err = parseDateParam(query.Get("dueFrom"), false); err != nil {
+		fields = append(fields, task.FieldError{Field: "dueFrom", Message: "must be an RFC 3339 timestamp or a YYYY-MM-DD date"})
+	}
+	if request.DueTo, err = parseDateParam(query.Get("dueTo"), true); err != nil {
+		fields = append(fields, task.FieldError{Field: "dueTo", Message: "must be an RFC 3339 timestamp or a YYYY-MM-DD date"})
+	}
+	if request.DueFrom != nil && request.DueTo != nil && !request.DueFrom.Before(*request.DueTo) {
+		fields = append(fields, task.FieldError{Field: "dueFrom", Message: "must be before dueTo"})
+	}
+	if len(fields) > 0 {
+		return request, &task.ValidationError{Fields: fields}
+	}
+	return request, nil
+}
+
+// parseDateParam accepts an RFC 3339 timestamp or a plain date. A plain date used as an upper bound
+// covers the whole day, so dueTo=2024-01-31 still includes tasks due on the 31st.
+func parseDateParam(value string, upperBound bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.ParseInLocation(time.DateOnly, value, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	if upperBound {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
+
+// parseListParam splits a comma separated query parameter, dropping blank entries.
+func parseListParam(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func mediaType(r *http.Request) string {
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		return ""
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return contentType
+	}
+	return mediaType
+}
+
 func getIDFromRequest(r *http.Request) (uint64, error) {
 	idStr := mux.Vars(r)["id"]
 	return strconv.ParseUint(idStr, 10, 64)
 }
 
+func writeTaskResponse(w http.ResponseWriter, statusCode int, res *task.GetTaskResponse) {
+	if res != nil && res.ETag != "" {
+		w.Header().Set("ETag", res.ETag)
+	}
+	writeResponse(w, statusCode, res)
+}
+
 func writeResponse(w http.ResponseWriter, statusCode int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
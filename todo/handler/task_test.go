@@ -4,12 +4,16 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"mkmgo-todo/todo/middleware"
 	"mkmgo-todo/todo/pagination"
+	"mkmgo-todo/todo/patch"
 	"mkmgo-todo/todo/task"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
@@ -20,9 +24,17 @@ import (
 */
 
 type MockTaskService struct {
-	SaveTaskFunc    func(ctx context.Context, request *task.WriteTaskRequest) (*task.GetTaskResponse, error)
-	GetAllTasksFunc func(ctx context.Context, request task.GetAllTaskRequest) ([]task.GetTaskResponse, error)
-	DeleteTaskFunc  func(ctx context.Context, id uint64) error
+	SaveTaskFunc         func(ctx context.Context, request *task.WriteTaskRequest) (*task.GetTaskResponse, error)
+	PatchTaskFunc        func(ctx context.Context, request *task.PatchTaskRequest) (*task.GetTaskResponse, error)
+	GetTaskFunc          func(ctx context.Context, id uint64) (*task.GetTaskResponse, error)
+	GetAllTasksFunc      func(ctx context.Context, request task.GetAllTaskRequest) (*pagination.PaginationResponse[task.GetTaskResponse], error)
+	UpdateTaskStatusFunc func(ctx context.Context, request *task.UpdateTaskStatusRequest) (*task.GetTaskResponse, error)
+	CompleteTaskFunc     func(ctx context.Context, id uint64) (*task.GetTaskResponse, error)
+	ReopenTaskFunc       func(ctx context.Context, id uint64) (*task.GetTaskResponse, error)
+	DeleteTaskFunc       func(ctx context.Context, id uint64) error
+	GetTrashFunc         func(ctx context.Context, request task.GetAllTaskRequest) (*pagination.PaginationResponse[task.GetTaskResponse], error)
+	RestoreTaskFunc      func(ctx context.Context, id uint64) (*task.GetTaskResponse, error)
+	PurgeTaskFunc        func(ctx context.Context, id uint64) error
 }
 
 func (m *MockTaskService) SaveTask(ctx context.Context, request *task.WriteTaskRequest) (*task.GetTaskResponse, error) {
@@ -32,10 +44,45 @@ func (m *MockTaskService) SaveTask(ctx context.Context, request *task.WriteTaskR
 	return nil, nil
 }
 
-func (m *MockTaskService) GetAllTasks(ctx context.Context, request task.GetAllTaskRequest) ([]task.GetTaskResponse, error) {
+func (m *MockTaskService) PatchTask(ctx context.Context, request *task.PatchTaskRequest) (*task.GetTaskResponse, error) {
+	if m.PatchTaskFunc != nil {
+		return m.PatchTaskFunc(ctx, request)
+	}
+	return nil, nil
+}
+
+func (m *MockTaskService) GetTask(ctx context.Context, id uint64) (*task.GetTaskResponse, error) {
+	if m.GetTaskFunc != nil {
+		return m.GetTaskFunc(ctx, id)
+	}
+	return nil, nil
+}
+
+func (m *MockTaskService) GetAllTasks(ctx context.Context, request task.GetAllTaskRequest) (*pagination.PaginationResponse[task.GetTaskResponse], error) {
 	if m.GetAllTasksFunc != nil {
 		return m.GetAllTasksFunc(ctx, request)
 	}
+	return pagination.NewPaginationResponse(*request.PaginationRequest, []task.GetTaskResponse{}, 0), nil
+}
+
+func (m *MockTaskService) UpdateTaskStatus(ctx context.Context, request *task.UpdateTaskStatusRequest) (*task.GetTaskResponse, error) {
+	if m.UpdateTaskStatusFunc != nil {
+		return m.UpdateTaskStatusFunc(ctx, request)
+	}
+	return nil, nil
+}
+
+func (m *MockTaskService) CompleteTask(ctx context.Context, id uint64) (*task.GetTaskResponse, error) {
+	if m.CompleteTaskFunc != nil {
+		return m.CompleteTaskFunc(ctx, id)
+	}
+	return nil, nil
+}
+
+func (m *MockTaskService) ReopenTask(ctx context.Context, id uint64) (*task.GetTaskResponse, error) {
+	if m.ReopenTaskFunc != nil {
+		return m.ReopenTaskFunc(ctx, id)
+	}
 	return nil, nil
 }
 
@@ -46,20 +93,42 @@ func (m *MockTaskService) DeleteTask(ctx context.Context, id uint64) error {
 	return nil
 }
 
+func (m *MockTaskService) GetTrash(ctx context.Context, request task.GetAllTaskRequest) (*pagination.PaginationResponse[task.GetTaskResponse], error) {
+	if m.GetTrashFunc != nil {
+		return m.GetTrashFunc(ctx, request)
+	}
+	return pagination.NewPaginationResponse(*request.PaginationRequest, []task.GetTaskResponse{}, 0), nil
+}
+
+func (m *MockTaskService) RestoreTask(ctx context.Context, id uint64) (*task.GetTaskResponse, error) {
+	if m.RestoreTaskFunc != nil {
+		return m.RestoreTaskFunc(ctx, id)
+	}
+	return nil, nil
+}
+
+func (m *MockTaskService) PurgeTask(ctx context.Context, id uint64) error {
+	if m.PurgeTaskFunc != nil {
+		return m.PurgeTaskFunc(ctx, id)
+	}
+	return nil
+}
+
 /*
 	Unit test for handler/task.go
 */
 
 const (
-	testTitle               = "Makima"
-	testDescription         = "Makima super kawaii"
-	testID                  = 1
-	updatedAt               = "02 Jan 2006, 15:04 WIB"
-	invalidWriteTaskRequest = `{"title":"Makima","description"}`
-	validWriteTaskRequest   = `{"title":"Makima","description":"Makima super kawaii"}`
-	validUpdateTaskRequest  = `{"id":1, "title":"Makima","description":"Makima super kawaii"}`
-	validGetAllTaskRequest  = `{"page":1,"pageSize":10, "sortBy":"title", "orderBy":"asc"}`
-	tasksUrl                = "/tasks"
+	testTitle                = "Makima"
+	testDescription          = "Makima super kawaii"
+	testID                   = 1
+	updatedAt                = "2006-01-02T15:04:05+07:00"
+	invalidWriteTaskRequest  = `{"title":"Makima","description"}`
+	validWriteTaskRequest    = `{"title":"Makima","description":"Makima super kawaii"}`
+	validUpdateTaskRequest   = `{"id":1, "title":"Makima","description":"Makima super kawaii"}`
+	validGetAllTaskRequest   = `{"page":1,"pageSize":10, "sortBy":"title", "orderBy":"asc"}`
+	validUpdateStatusRequest = `{"status":"in_progress"}`
+	tasksUrl                 = "/tasks"
 )
 
 func TestSaveTaskHandler(t *testing.T) {
@@ -94,6 +163,22 @@ func TestSaveTaskHandler(t *testing.T) {
 	assert.Equal(t, testDescription, respBody["description"])
 }
 
+func TestSaveTaskHandlerIgnoresBodyID(t *testing.T) {
+	mockService := &MockTaskService{
+		SaveTaskFunc: func(ctx context.Context, request *task.WriteTaskRequest) (*task.GetTaskResponse, error) {
+			assert.Equal(t, uint64(0), request.ID, "replacing a task takes PUT with If-Match")
+			return &task.GetTaskResponse{ID: testID, Title: request.Title}, nil
+		},
+	}
+
+	handler := NewTaskHandler(mockService)
+	r := httptest.NewRequest(http.MethodPost, tasksUrl, bytes.NewBufferString(validUpdateTaskRequest))
+	w := httptest.NewRecorder()
+	handler.WriteTaskHandler(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+}
+
 func TestSaveTaskHandlerWhenInvalidJSONRequest(t *testing.T) {
 	mockService := &MockTaskService{}
 	handler := NewTaskHandler(mockService)
@@ -104,10 +189,13 @@ func TestSaveTaskHandlerWhenInvalidJSONRequest(t *testing.T) {
 
 	resp := w.Result()
 	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
-	var respBody map[string]string
-	err := json.NewDecoder(resp.Body).Decode(&respBody)
+	assert.Equal(t, "application/problem+json", resp.Header.Get("Content-Type"))
 
-	assert.Error(t, err)
+	var respBody Problem
+	err := json.NewDecoder(resp.Body).Decode(&respBody)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, respBody.Status)
+	assert.Equal(t, "Bad Request", respBody.Title)
 }
 
 func TestSaveTaskHandlerWhenSvcSaveTaskFail(t *testing.T) {
@@ -133,7 +221,7 @@ func TestSaveTaskHandlerWhenSvcSaveTaskFail(t *testing.T) {
 
 func TestUpdateTaskHandler(t *testing.T) {
 	mockService := &MockTaskService{
-		SaveTaskFunc: func(ctx context.Context, request *task.WriteTaskRequest) (*task.GetTaskResponse, error) {
+		PatchTaskFunc: func(ctx context.Context, request *task.PatchTaskRequest) (*task.GetTaskResponse, error) {
 			response := task.GetTaskResponse{
 				ID:          testID,
 				Title:       testTitle,
@@ -175,10 +263,13 @@ func TestUpdateTaskHandlerInvalidRequest(t *testing.T) {
 
 	resp := w.Result()
 	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
-	var respBody map[string]string
-	err := json.NewDecoder(resp.Body).Decode(&respBody)
+	assert.Equal(t, "application/problem+json", resp.Header.Get("Content-Type"))
 
-	assert.Error(t, err)
+	var respBody Problem
+	err := json.NewDecoder(resp.Body).Decode(&respBody)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, respBody.Status)
+	assert.Equal(t, "Bad Request", respBody.Title)
 }
 
 func TestUpdateTaskHandlerInvalidParamID(t *testing.T) {
@@ -191,37 +282,289 @@ func TestUpdateTaskHandlerInvalidParamID(t *testing.T) {
 
 	resp := w.Result()
 	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
-	var respBody map[string]string
-	err := json.NewDecoder(resp.Body).Decode(&respBody)
+	assert.Equal(t, "application/problem+json", resp.Header.Get("Content-Type"))
 
-	assert.Error(t, err)
+	var respBody Problem
+	err := json.NewDecoder(resp.Body).Decode(&respBody)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, respBody.Status)
+	assert.Equal(t, "Bad Request", respBody.Title)
 }
 
-func TestUpdateTaskHandlerWhenSvcSaveFail(t *testing.T) {
+func TestUpdateTaskHandlerWhenSvcPatchFail(t *testing.T) {
 	mockService := &MockTaskService{
-		SaveTaskFunc: func(ctx context.Context, request *task.WriteTaskRequest) (*task.GetTaskResponse, error) {
+		PatchTaskFunc: func(ctx context.Context, request *task.PatchTaskRequest) (*task.GetTaskResponse, error) {
 			return nil, fmt.Errorf("write task error")
 		},
 	}
 	handler := NewTaskHandler(mockService)
 
-	r := httptest.NewRequest(http.MethodPost, tasksUrl+"/1", bytes.NewBufferString(validUpdateTaskRequest))
+	r := httptest.NewRequest(http.MethodPatch, tasksUrl+"/1", bytes.NewBufferString(validUpdateTaskRequest))
 	r = mux.SetURLVars(r, map[string]string{"id": "1"})
 	w := httptest.NewRecorder()
 	handler.UpdateTaskHandler(w, r)
 
-	req := task.WriteTaskRequest{
-		ID:          testID,
-		Title:       testTitle,
-		Description: testDescription,
+	assert.Equal(t, http.StatusInternalServerError, w.Result().StatusCode)
+}
+
+func TestUpdateTaskHandlerWithJSONPatch(t *testing.T) {
+	var got *task.PatchTaskRequest
+	mockService := &MockTaskService{
+		PatchTaskFunc: func(ctx context.Context, request *task.PatchTaskRequest) (*task.GetTaskResponse, error) {
+			got = request
+			return &task.GetTaskResponse{ID: request.ID, Title: testTitle}, nil
+		},
 	}
-	_, err := handler.taskSvc.SaveTask(r.Context(), &req)
-	assert.Error(t, err)
+	handler := NewTaskHandler(mockService)
+
+	r := httptest.NewRequest(http.MethodPatch, tasksUrl+"/1", bytes.NewBufferString(`[{"op":"replace","path":"/title","value":"Makima"}]`))
+	r.Header.Set("Content-Type", "application/json-patch+json")
+	r = mux.SetURLVars(r, map[string]string{"id": "1"})
+	w := httptest.NewRecorder()
+	handler.UpdateTaskHandler(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.Equal(t, uint64(1), got.ID)
+	assert.IsType(t, patch.JSONPatch{}, got.Patch)
+}
+
+func TestUpdateTaskHandlerWithMergePatchContentType(t *testing.T) {
+	var got *task.PatchTaskRequest
+	mockService := &MockTaskService{
+		PatchTaskFunc: func(ctx context.Context, request *task.PatchTaskRequest) (*task.GetTaskResponse, error) {
+			got = request
+			return &task.GetTaskResponse{ID: request.ID, Title: testTitle}, nil
+		},
+	}
+	handler := NewTaskHandler(mockService)
+
+	r := httptest.NewRequest(http.MethodPatch, tasksUrl+"/1", bytes.NewBufferString(`{"title":"Makima"}`))
+	r.Header.Set("Content-Type", "application/merge-patch+json; charset=utf-8")
+	r = mux.SetURLVars(r, map[string]string{"id": "1"})
+	w := httptest.NewRecorder()
+	handler.UpdateTaskHandler(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.IsType(t, patch.MergePatch{}, got.Patch)
+}
+
+func TestUpdateTaskHandlerUnsupportedMediaType(t *testing.T) {
+	handler := NewTaskHandler(&MockTaskService{})
+
+	r := httptest.NewRequest(http.MethodPatch, tasksUrl+"/1", bytes.NewBufferString(`title=Makima`))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r = mux.SetURLVars(r, map[string]string{"id": "1"})
+	w := httptest.NewRecorder()
+	handler.UpdateTaskHandler(w, r)
+
+	resp := w.Result()
+	assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Accept-Patch"), "application/json-patch+json")
+}
+
+func TestUpdateTaskHandlerWhenNotFound(t *testing.T) {
+	mockService := &MockTaskService{
+		PatchTaskFunc: func(ctx context.Context, request *task.PatchTaskRequest) (*task.GetTaskResponse, error) {
+			return nil, task.ErrTaskNotFound
+		},
+	}
+	handler := NewTaskHandler(mockService)
+
+	r := httptest.NewRequest(http.MethodPatch, tasksUrl+"/9", bytes.NewBufferString(`{"title":"Makima"}`))
+	r = mux.SetURLVars(r, map[string]string{"id": "9"})
+	w := httptest.NewRecorder()
+	handler.UpdateTaskHandler(w, r)
+
+	assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+}
+
+func TestUpdateTaskHandlerWhenPatchCannotApply(t *testing.T) {
+	mockService := &MockTaskService{
+		PatchTaskFunc: func(ctx context.Context, request *task.PatchTaskRequest) (*task.GetTaskResponse, error) {
+			return nil, task.ErrPatchTestFailed
+		},
+	}
+	handler := NewTaskHandler(mockService)
+
+	r := httptest.NewRequest(http.MethodPatch, tasksUrl+"/1", bytes.NewBufferString(`[{"op":"test","path":"/title","value":"Power"}]`))
+	r.Header.Set("Content-Type", "application/json-patch+json")
+	r = mux.SetURLVars(r, map[string]string{"id": "1"})
+	w := httptest.NewRecorder()
+	handler.UpdateTaskHandler(w, r)
+
+	assert.Equal(t, http.StatusConflict, w.Result().StatusCode)
+}
+
+func TestReplaceTaskHandler(t *testing.T) {
+	var got *task.WriteTaskRequest
+	mockService := &MockTaskService{
+		SaveTaskFunc: func(ctx context.Context, request *task.WriteTaskRequest) (*task.GetTaskResponse, error) {
+			got = request
+			return &task.GetTaskResponse{ID: request.ID, Title: request.Title, Description: request.Description}, nil
+		},
+	}
+	handler := NewTaskHandler(mockService)
+
+	r := httptest.NewRequest(http.MethodPut, tasksUrl+"/1", bytes.NewBufferString(validWriteTaskRequest))
+	r = mux.SetURLVars(r, map[string]string{"id": "1"})
+	w := httptest.NewRecorder()
+	handler.ReplaceTaskHandler(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.Equal(t, uint64(testID), got.ID)
+	assert.Equal(t, testTitle, got.Title)
+}
+
+func TestReplaceTaskHandlerWhenIfMatchDiffers(t *testing.T) {
+	var got *task.WriteTaskRequest
+	mockService := &MockTaskService{
+		SaveTaskFunc: func(ctx context.Context, request *task.WriteTaskRequest) (*task.GetTaskResponse, error) {
+			got = request
+			return nil, task.ErrTaskVersionMismatch
+		},
+	}
+	handler := NewTaskHandler(mockService)
+
+	r := httptest.NewRequest(http.MethodPut, tasksUrl+"/1", bytes.NewBufferString(validWriteTaskRequest))
+	r.Header.Set("If-Match", `"1"`)
+	r = mux.SetURLVars(r, map[string]string{"id": "1"})
+	w := httptest.NewRecorder()
+	handler.ReplaceTaskHandler(w, r)
+
+	assert.Equal(t, http.StatusPreconditionFailed, w.Result().StatusCode)
+	assert.Equal(t, `"1"`, got.IfMatch)
+}
+
+func TestReplaceTaskHandlerWhenNotFound(t *testing.T) {
+	mockService := &MockTaskService{
+		SaveTaskFunc: func(ctx context.Context, request *task.WriteTaskRequest) (*task.GetTaskResponse, error) {
+			return nil, task.ErrTaskNotFound
+		},
+	}
+	handler := NewTaskHandler(mockService)
+
+	r := httptest.NewRequest(http.MethodPut, tasksUrl+"/9", bytes.NewBufferString(validWriteTaskRequest))
+	r = mux.SetURLVars(r, map[string]string{"id": "9"})
+	w := httptest.NewRecorder()
+	handler.ReplaceTaskHandler(w, r)
+
+	assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+}
+
+func TestGetTaskHandler(t *testing.T) {
+	mockService := &MockTaskService{
+		GetTaskFunc: func(ctx context.Context, id uint64) (*task.GetTaskResponse, error) {
+			return &task.GetTaskResponse{ID: id, Title: testTitle, Description: testDescription, UpdatedAt: updatedAt}, nil
+		},
+	}
+
+	handler := NewTaskHandler(mockService)
+	r := httptest.NewRequest(http.MethodGet, tasksUrl+"/1", nil)
+	r = mux.SetURLVars(r, map[string]string{"id": "1"})
+	w := httptest.NewRecorder()
+	handler.GetTaskHandler(w, r)
+
+	resp := w.Result()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var respBody map[string]interface{}
+	err := json.NewDecoder(resp.Body).Decode(&respBody)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(testID), respBody["id"])
+	assert.Equal(t, testTitle, respBody["title"])
+}
+
+func TestGetTaskHandlerSetsETag(t *testing.T) {
+	mockService := &MockTaskService{
+		GetTaskFunc: func(ctx context.Context, id uint64) (*task.GetTaskResponse, error) {
+			return &task.GetTaskResponse{ID: id, Title: testTitle, ETag: `"7"`}, nil
+		},
+	}
+
+	handler := NewTaskHandler(mockService)
+	r := httptest.NewRequest(http.MethodGet, tasksUrl+"/1", nil)
+	r = mux.SetURLVars(r, map[string]string{"id": "1"})
+	w := httptest.NewRecorder()
+	handler.GetTaskHandler(w, r)
+
+	assert.Equal(t, `"7"`, w.Result().Header.Get("ETag"))
+}
+
+func TestGetTaskHandlerHidesInternalError(t *testing.T) {
+	mockService := &MockTaskService{
+		GetTaskFunc: func(ctx context.Context, id uint64) (*task.GetTaskResponse, error) {
+			return nil, fmt.Errorf("failed to retrieve task: %w", errors.New(`pq: relation "task" does not exist`))
+		},
+	}
+
+	handler := NewTaskHandler(mockService)
+	r := httptest.NewRequest(http.MethodGet, tasksUrl+"/1", nil)
+	r = mux.SetURLVars(r, map[string]string{"id": "1"})
+	w := httptest.NewRecorder()
+	handler.GetTaskHandler(w, r)
+
+	resp := w.Result()
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+
+	var respBody Problem
+	err := json.NewDecoder(resp.Body).Decode(&respBody)
+	assert.NoError(t, err)
+	assert.Equal(t, "internal server error", respBody.Detail)
+	assert.NotContains(t, respBody.Detail, "pq:")
+}
+
+func TestGetTaskHandlerWhenNotFound(t *testing.T) {
+	mockService := &MockTaskService{
+		GetTaskFunc: func(ctx context.Context, id uint64) (*task.GetTaskResponse, error) {
+			return nil, fmt.Errorf("%w: id %d", task.ErrTaskNotFound, id)
+		},
+	}
+
+	handler := NewTaskHandler(mockService)
+	r := httptest.NewRequest(http.MethodGet, tasksUrl+"/2", nil)
+	r.Header.Set("X-Request-ID", "req-42")
+	r = mux.SetURLVars(r, map[string]string{"id": "2"})
+	w := httptest.NewRecorder()
+	middleware.RequestID(http.HandlerFunc(handler.GetTaskHandler)).ServeHTTP(w, r)
+
+	resp := w.Result()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	assert.Equal(t, "application/problem+json", resp.Header.Get("Content-Type"))
+
+	var respBody Problem
+	err := json.NewDecoder(resp.Body).Decode(&respBody)
+	assert.NoError(t, err)
+	assert.Equal(t, Problem{
+		Type:      "urn:mkmgo-todo:problem:not-found",
+		Title:     "Not Found",
+		Status:    http.StatusNotFound,
+		Detail:    "task not found: id 2",
+		Instance:  "/tasks/2",
+		RequestID: "req-42",
+	}, respBody)
+}
+
+func TestGetTaskHandlerInvalidID(t *testing.T) {
+	handler := NewTaskHandler(&MockTaskService{})
+
+	r := httptest.NewRequest(http.MethodGet, tasksUrl+"/abc", nil)
+	r = mux.SetURLVars(r, map[string]string{"id": "abc"})
+	w := httptest.NewRecorder()
+	handler.GetTaskHandler(w, r)
+
+	resp := w.Result()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	var respBody Problem
+	err := json.NewDecoder(resp.Body).Decode(&respBody)
+	assert.NoError(t, err)
+	assert.Equal(t, []task.FieldError{{Field: "id", Message: "must be a positive integer"}}, respBody.Errors)
 }
 
 func TestGetAllTaskHandler(t *testing.T) {
 	mockService := &MockTaskService{
-		GetAllTasksFunc: func(ctx context.Context, request task.GetAllTaskRequest) ([]task.GetTaskResponse, error) {
+		GetAllTasksFunc: func(ctx context.Context, request task.GetAllTaskRequest) (*pagination.PaginationResponse[task.GetTaskResponse], error) {
 			var responses []task.GetTaskResponse
 			response := task.GetTaskResponse{
 				ID:          testID,
@@ -230,7 +573,7 @@ func TestGetAllTaskHandler(t *testing.T) {
 				UpdatedAt:   updatedAt,
 			}
 			responses = append(responses, response)
-			return responses, nil
+			return pagination.NewPaginationResponse(*request.PaginationRequest, responses, 1), nil
 		},
 	}
 
@@ -247,8 +590,7 @@ func TestGetAllTaskHandler(t *testing.T) {
 	pagination := pagination.PaginationRequest{
 		Page:     1,
 		PageSize: 10,
-		Order:    "title",
-		SortBy:   "asc",
+		Sort:     []pagination.SortField{{Field: "title"}},
 	}
 	request := task.GetAllTaskRequest{
 		PaginationRequest: &pagination,
@@ -256,7 +598,7 @@ func TestGetAllTaskHandler(t *testing.T) {
 	responses, err := handler.taskSvc.GetAllTasks(context.Background(), request)
 	assert.NoError(t, err)
 
-	for i, response := range responses {
+	for i, response := range responses.Items {
 		expectedResponse := expectedResponses[i]
 		assert.Equal(t, expectedResponse.ID, response.ID)
 		assert.Equal(t, expectedResponse.Title, response.Title)
@@ -265,9 +607,211 @@ func TestGetAllTaskHandler(t *testing.T) {
 
 }
 
+func TestGetAllTaskHandlerReturnsEnvelopeAndLinks(t *testing.T) {
+	mockService := &MockTaskService{
+		GetAllTasksFunc: func(ctx context.Context, request task.GetAllTaskRequest) (*pagination.PaginationResponse[task.GetTaskResponse], error) {
+			return pagination.NewPaginationResponse(*request.PaginationRequest, []task.GetTaskResponse{{ID: testID, Title: testTitle}}, 5), nil
+		},
+	}
+
+	handler := NewTaskHandler(mockService)
+	r := httptest.NewRequest(http.MethodGet, tasksUrl+"?page=2&pageSize=2&due=week", nil)
+	w := httptest.NewRecorder()
+	handler.GetAllTaskHandler(w, r)
+
+	resp := w.Result()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `</tasks?due=week&page=1&pageSize=2>; rel="first", `+
+		`</tasks?due=week&page=1&pageSize=2>; rel="prev", `+
+		`</tasks?due=week&page=3&pageSize=2>; rel="next", `+
+		`</tasks?due=week&page=3&pageSize=2>; rel="last"`, resp.Header.Get("Link"))
+
+	var respBody pagination.PaginationResponse[task.GetTaskResponse]
+	err := json.NewDecoder(resp.Body).Decode(&respBody)
+	assert.NoError(t, err)
+	assert.Len(t, respBody.Items, 1)
+	assert.Equal(t, 2, respBody.Page)
+	assert.Equal(t, 2, respBody.PageSize)
+	assert.Equal(t, int64(5), respBody.TotalItems)
+	assert.Equal(t, 3, respBody.TotalPages)
+	assert.True(t, respBody.HasNext)
+}
+
+func TestGetAllTaskHandlerWithDueFilter(t *testing.T) {
+	var got task.GetAllTaskRequest
+	mockService := &MockTaskService{
+		GetAllTasksFunc: func(ctx context.Context, request task.GetAllTaskRequest) (*pagination.PaginationResponse[task.GetTaskResponse], error) {
+			got = request
+			return pagination.NewPaginationResponse(*request.PaginationRequest, []task.GetTaskResponse{}, 0), nil
+		},
+	}
+
+	handler := NewTaskHandler(mockService)
+	r := httptest.NewRequest(http.MethodGet, tasksUrl+"?due=week&dueFrom=2024-01-01&dueTo=2024-01-31", nil)
+	w := httptest.NewRecorder()
+	handler.GetAllTaskHandler(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.Equal(t, task.DueThisWeek, got.Due)
+	assert.Equal(t, "2024-01-01", got.DueFrom.Format(time.DateOnly))
+	assert.Equal(t, "2024-02-01", got.DueTo.Format(time.DateOnly))
+}
+
+func TestGetAllTaskHandlerWithTagFilters(t *testing.T) {
+	var got task.GetAllTaskRequest
+	mockService := &MockTaskService{
+		GetAllTasksFunc: func(ctx context.Context, request task.GetAllTaskRequest) (*pagination.PaginationResponse[task.GetTaskResponse], error) {
+			got = request
+			return pagination.NewPaginationResponse(*request.PaginationRequest, []task.GetTaskResponse{}, 0), nil
+		},
+	}
+
+	handler := NewTaskHandler(mockService)
+	r := httptest.NewRequest(http.MethodGet, tasksUrl+"?tagsAny=home,%20work&tagsAll=urgent&tagsNone=,someday,", nil)
+	w := httptest.NewRecorder()
+	handler.GetAllTaskHandler(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.Equal(t, []string{"home", "work"}, got.TagsAny)
+	assert.Equal(t, []string{"urgent"}, got.TagsAll)
+	assert.Equal(t, []string{"someday"}, got.TagsNone)
+}
+
+func TestGetProjectTasksHandler(t *testing.T) {
+	var got task.GetAllTaskRequest
+	mockService := &MockTaskService{
+		GetAllTasksFunc: func(ctx context.Context, request task.GetAllTaskRequest) (*pagination.PaginationResponse[task.GetTaskResponse], error) {
+			got = request
+			return pagination.NewPaginationResponse(*request.PaginationRequest, []task.GetTaskResponse{}, 0), nil
+		},
+	}
+
+	handler := NewTaskHandler(mockService)
+	r := httptest.NewRequest(http.MethodGet, "/todo/projects/5/tasks?due=week", nil)
+	r = mux.SetURLVars(r, map[string]string{"id": "5"})
+	w := httptest.NewRecorder()
+	handler.GetProjectTasksHandler(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.Equal(t, uint64(5), got.ProjectID)
+	assert.Equal(t, task.DueThisWeek, got.Due)
+}
+
+func TestWriteProjectTaskHandler(t *testing.T) {
+	mockService := &MockTaskService{
+		SaveTaskFunc: func(ctx context.Context, request *task.WriteTaskRequest) (*task.GetTaskResponse, error) {
+			assert.Equal(t, uint64(5), request.ProjectID)
+			return &task.GetTaskResponse{ID: testID, Title: request.Title, ProjectID: request.ProjectID}, nil
+		},
+	}
+
+	handler := NewTaskHandler(mockService)
+	r := httptest.NewRequest(http.MethodPost, "/todo/projects/5/tasks", bytes.NewBufferString(`{"title":"Shared","projectId":6}`))
+	r = mux.SetURLVars(r, map[string]string{"id": "5"})
+	w := httptest.NewRecorder()
+	handler.WriteProjectTaskHandler(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	var respBody map[string]interface{}
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&respBody))
+	assert.Equal(t, float64(5), respBody["projectId"])
+}
+
+func TestWriteProjectTaskHandlerOnlyCreates(t *testing.T) {
+	mockService := &MockTaskService{
+		SaveTaskFunc: func(ctx context.Context, request *task.WriteTaskRequest) (*task.GetTaskResponse, error) {
+			assert.Equal(t, uint64(0), request.ID, "the body must not pick a task to replace")
+			return &task.GetTaskResponse{ID: testID, Title: request.Title, ProjectID: request.ProjectID}, nil
+		},
+	}
+
+	handler := NewTaskHandler(mockService)
+	r := httptest.NewRequest(http.MethodPost, "/todo/projects/5/tasks", bytes.NewBufferString(`{"id":42,"title":"Shared"}`))
+	r = mux.SetURLVars(r, map[string]string{"id": "5"})
+	w := httptest.NewRecorder()
+	handler.WriteProjectTaskHandler(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+}
+
+func TestGetAllTaskHandlerWhenInvalidDueFilter(t *testing.T) {
+	handler := NewTaskHandler(&MockTaskService{})
+
+	for query, field := range map[string]string{"?due=someday": "due", "?dueFrom=yesterday": "dueFrom", "?dueFrom=2024-02-01&dueTo=2024-01-01": "dueFrom"} {
+		r := httptest.NewRequest(http.MethodGet, tasksUrl+query, nil)
+		w := httptest.NewRecorder()
+		handler.GetAllTaskHandler(w, r)
+
+		resp := w.Result()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, query)
+
+		var respBody Problem
+		err := json.NewDecoder(resp.Body).Decode(&respBody)
+		assert.NoError(t, err)
+		assert.Len(t, respBody.Errors, 1, query)
+		assert.Equal(t, field, respBody.Errors[0].Field, query)
+	}
+}
+
+func TestGetAllTaskHandlerWhenCursorTampered(t *testing.T) {
+	cursor := pagination.Cursor{Sort: pagination.SortField{Field: "updatedAt"}, Key: "yesterday", ID: 7}
+	handler := NewTaskHandler(&MockTaskService{
+		GetAllTasksFunc: func(ctx context.Context, request task.GetAllTaskRequest) (*pagination.PaginationResponse[task.GetTaskResponse], error) {
+			assert.Equal(t, &cursor, request.PaginationRequest.After)
+			return nil, &task.ValidationError{Fields: []task.FieldError{{Field: "cursor", Message: "is not a valid cursor"}}}
+		},
+	})
+	r := httptest.NewRequest(http.MethodGet, tasksUrl+"?cursor="+cursor.Encode(), nil)
+	w := httptest.NewRecorder()
+	handler.GetAllTaskHandler(w, r)
+
+	resp := w.Result()
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	var respBody Problem
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&respBody))
+	assert.Equal(t, []task.FieldError{{Field: "cursor", Message: "is not a valid cursor"}}, respBody.Errors)
+}
+
+func TestGetAllTaskHandlerWithSort(t *testing.T) {
+	var got task.GetAllTaskRequest
+	mockService := &MockTaskService{
+		GetAllTasksFunc: func(ctx context.Context, request task.GetAllTaskRequest) (*pagination.PaginationResponse[task.GetTaskResponse], error) {
+			got = request
+			return pagination.NewPaginationResponse(*request.PaginationRequest, []task.GetTaskResponse{}, 0), nil
+		},
+	}
+
+	handler := NewTaskHandler(mockService)
+	r := httptest.NewRequest(http.MethodGet, tasksUrl+"?sort=-dueAt,title", nil)
+	w := httptest.NewRecorder()
+	handler.GetAllTaskHandler(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.Equal(t, []pagination.SortField{{Field: "dueAt", Desc: true}, {Field: "title"}}, got.PaginationRequest.Sort)
+}
+
+func TestGetAllTaskHandlerWhenSortFieldUnknown(t *testing.T) {
+	handler := NewTaskHandler(&MockTaskService{})
+
+	for query, field := range map[string]string{"?sort=description": "sort", "?sortBy=deleted_at": "sortBy", "?sortBy=title&order=sideways": "order", "?cursor=&sort=dueAt": "sort"} {
+		r := httptest.NewRequest(http.MethodGet, tasksUrl+query, nil)
+		w := httptest.NewRecorder()
+		handler.GetAllTaskHandler(w, r)
+
+		resp := w.Result()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, query)
+
+		var respBody Problem
+		err := json.NewDecoder(resp.Body).Decode(&respBody)
+		assert.NoError(t, err)
+		assert.Len(t, respBody.Errors, 1, query)
+		assert.Equal(t, field, respBody.Errors[0].Field, query)
+	}
+}
+
 func TestGetAllTaskHandlerWhenSvcGetAllFail(t *testing.T) {
 	mockService := &MockTaskService{
-		GetAllTasksFunc: func(ctx context.Context, request task.GetAllTaskRequest) ([]task.GetTaskResponse, error) {
+		GetAllTasksFunc: func(ctx context.Context, request task.GetAllTaskRequest) (*pagination.PaginationResponse[task.GetTaskResponse], error) {
 			return nil, fmt.Errorf("get all task error")
 		},
 	}
@@ -277,8 +821,7 @@ func TestGetAllTaskHandlerWhenSvcGetAllFail(t *testing.T) {
 	pagination := pagination.PaginationRequest{
 		Page:     1,
 		PageSize: 10,
-		Order:    "title",
-		SortBy:   "asc",
+		Sort:     []pagination.SortField{{Field: "title"}},
 	}
 	request := task.GetAllTaskRequest{
 		PaginationRequest: &pagination,
@@ -340,3 +883,248 @@ func TestDeleteTaskHandlerWhenSvcFail(t *testing.T) {
 	err := handler.taskSvc.DeleteTask(context.Background(), 1)
 	assert.Error(t, err)
 }
+
+func TestDeleteTaskHandlerPermanent(t *testing.T) {
+	var purged, deleted bool
+	mockService := &MockTaskService{
+		PurgeTaskFunc: func(ctx context.Context, id uint64) error {
+			purged = true
+			return nil
+		},
+		DeleteTaskFunc: func(ctx context.Context, id uint64) error {
+			deleted = true
+			return nil
+		},
+	}
+
+	handler := NewTaskHandler(mockService)
+	r := httptest.NewRequest(http.MethodDelete, tasksUrl+"/1?permanent=true", nil)
+	r = mux.SetURLVars(r, map[string]string{"id": "1"})
+	w := httptest.NewRecorder()
+	handler.DeleteTaskHandler(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.True(t, purged)
+	assert.False(t, deleted)
+}
+
+func TestDeleteTaskHandlerWhenPermanentInvalid(t *testing.T) {
+	handler := NewTaskHandler(&MockTaskService{})
+	r := httptest.NewRequest(http.MethodDelete, tasksUrl+"/1?permanent=forever", nil)
+	r = mux.SetURLVars(r, map[string]string{"id": "1"})
+	w := httptest.NewRecorder()
+	handler.DeleteTaskHandler(w, r)
+
+	resp := w.Result()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	var respBody Problem
+	err := json.NewDecoder(resp.Body).Decode(&respBody)
+	assert.NoError(t, err)
+	assert.Equal(t, "permanent", respBody.Errors[0].Field)
+}
+
+func TestGetTrashHandler(t *testing.T) {
+	var got task.GetAllTaskRequest
+	mockService := &MockTaskService{
+		GetTrashFunc: func(ctx context.Context, request task.GetAllTaskRequest) (*pagination.PaginationResponse[task.GetTaskResponse], error) {
+			got = request
+			items := []task.GetTaskResponse{{ID: testID, Title: testTitle, DeletedAt: updatedAt}}
+			return pagination.NewPaginationResponse(*request.PaginationRequest, items, 1), nil
+		},
+	}
+
+	handler := NewTaskHandler(mockService)
+	r := httptest.NewRequest(http.MethodGet, tasksUrl+"/trash", nil)
+	w := httptest.NewRecorder()
+	handler.GetTrashHandler(w, r)
+
+	resp := w.Result()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, task.DefaultTrashSort, got.PaginationRequest.Sort)
+
+	var respBody pagination.PaginationResponse[task.GetTaskResponse]
+	err := json.NewDecoder(resp.Body).Decode(&respBody)
+	assert.NoError(t, err)
+	assert.Equal(t, updatedAt, respBody.Items[0].DeletedAt)
+}
+
+func TestGetTrashHandlerWhenCursorGiven(t *testing.T) {
+	handler := NewTaskHandler(&MockTaskService{})
+	r := httptest.NewRequest(http.MethodGet, tasksUrl+"/trash?cursor=", nil)
+	w := httptest.NewRecorder()
+	handler.GetTrashHandler(w, r)
+
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
+
+func TestRestoreTaskHandler(t *testing.T) {
+	mockService := &MockTaskService{
+		RestoreTaskFunc: func(ctx context.Context, id uint64) (*task.GetTaskResponse, error) {
+			return &task.GetTaskResponse{ID: id, Title: testTitle, ETag: `"2"`}, nil
+		},
+	}
+
+	handler := NewTaskHandler(mockService)
+	r := httptest.NewRequest(http.MethodPost, tasksUrl+"/1/restore", nil)
+	r = mux.SetURLVars(r, map[string]string{"id": "1"})
+	w := httptest.NewRecorder()
+	handler.RestoreTaskHandler(w, r)
+
+	resp := w.Result()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `"2"`, resp.Header.Get("ETag"))
+}
+
+func TestRestoreTaskHandlerWhenNotTrashed(t *testing.T) {
+	mockService := &MockTaskService{
+		RestoreTaskFunc: func(ctx context.Context, id uint64) (*task.GetTaskResponse, error) {
+			return nil, task.ErrTaskNotTrashed
+		},
+	}
+
+	handler := NewTaskHandler(mockService)
+	r := httptest.NewRequest(http.MethodPost, tasksUrl+"/1/restore", nil)
+	r = mux.SetURLVars(r, map[string]string{"id": "1"})
+	w := httptest.NewRecorder()
+	handler.RestoreTaskHandler(w, r)
+
+	assert.Equal(t, http.StatusConflict, w.Result().StatusCode)
+}
+
+func TestUpdateTaskStatusHandler(t *testing.T) {
+	mockService := &MockTaskService{
+		UpdateTaskStatusFunc: func(ctx context.Context, request *task.UpdateTaskStatusRequest) (*task.GetTaskResponse, error) {
+			return &task.GetTaskResponse{ID: request.ID, Title: testTitle, Status: request.Status}, nil
+		},
+	}
+
+	handler := NewTaskHandler(mockService)
+	r := httptest.NewRequest(http.MethodPut, tasksUrl+"/1/status", bytes.NewBufferString(validUpdateStatusRequest))
+	r = mux.SetURLVars(r, map[string]string{"id": "1"})
+	w := httptest.NewRecorder()
+	handler.UpdateTaskStatusHandler(w, r)
+
+	resp := w.Result()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var respBody map[string]interface{}
+	err := json.NewDecoder(resp.Body).Decode(&respBody)
+	assert.NoError(t, err)
+	assert.Equal(t, "in_progress", respBody["status"])
+}
+
+func TestSaveTaskHandlerWhenValidationFails(t *testing.T) {
+	mockService := &MockTaskService{
+		SaveTaskFunc: func(ctx context.Context, request *task.WriteTaskRequest) (*task.GetTaskResponse, error) {
+			return nil, &task.ValidationError{Fields: []task.FieldError{{Field: "startAt", Message: "must not be after dueAt"}}}
+		},
+	}
+
+	handler := NewTaskHandler(mockService)
+	r := httptest.NewRequest(http.MethodPost, tasksUrl, bytes.NewBufferString(validWriteTaskRequest))
+	w := httptest.NewRecorder()
+	handler.WriteTaskHandler(w, r)
+
+	resp := w.Result()
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+	var respBody Problem
+	err := json.NewDecoder(resp.Body).Decode(&respBody)
+	assert.NoError(t, err)
+	assert.Equal(t, "urn:mkmgo-todo:problem:validation", respBody.Type)
+	assert.Equal(t, []task.FieldError{{Field: "startAt", Message: "must not be after dueAt"}}, respBody.Errors)
+}
+
+func TestUpdateTaskStatusHandlerWhenInvalidStatus(t *testing.T) {
+	mockService := &MockTaskService{
+		UpdateTaskStatusFunc: func(ctx context.Context, request *task.UpdateTaskStatusRequest) (*task.GetTaskResponse, error) {
+			return nil, &task.ValidationError{Fields: []task.FieldError{{Field: "status", Message: "must be one of todo, in_progress, done, cancelled"}}}
+		},
+	}
+
+	handler := NewTaskHandler(mockService)
+	r := httptest.NewRequest(http.MethodPut, tasksUrl+"/1/status", bytes.NewBufferString(`{"status":"archived"}`))
+	r = mux.SetURLVars(r, map[string]string{"id": "1"})
+	w := httptest.NewRecorder()
+	handler.UpdateTaskStatusHandler(w, r)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Result().StatusCode)
+}
+
+func TestCompleteTaskHandler(t *testing.T) {
+	mockService := &MockTaskService{
+		CompleteTaskFunc: func(ctx context.Context, id uint64) (*task.GetTaskResponse, error) {
+			return &task.GetTaskResponse{ID: id, Title: testTitle, Status: task.StatusDone, CompletedAt: updatedAt}, nil
+		},
+	}
+
+	handler := NewTaskHandler(mockService)
+	r := httptest.NewRequest(http.MethodPost, tasksUrl+"/1/complete", nil)
+	r = mux.SetURLVars(r, map[string]string{"id": "1"})
+	w := httptest.NewRecorder()
+	handler.CompleteTaskHandler(w, r)
+
+	resp := w.Result()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var respBody map[string]interface{}
+	err := json.NewDecoder(resp.Body).Decode(&respBody)
+	assert.NoError(t, err)
+	assert.Equal(t, "done", respBody["status"])
+	assert.Equal(t, updatedAt, respBody["completedAt"])
+}
+
+func TestCompleteTaskHandlerWhenInvalidTransition(t *testing.T) {
+	mockService := &MockTaskService{
+		CompleteTaskFunc: func(ctx context.Context, id uint64) (*task.GetTaskResponse, error) {
+			return nil, task.ErrInvalidStatusTransition
+		},
+	}
+
+	handler := NewTaskHandler(mockService)
+	r := httptest.NewRequest(http.MethodPost, tasksUrl+"/1/complete", nil)
+	r = mux.SetURLVars(r, map[string]string{"id": "1"})
+	w := httptest.NewRecorder()
+	handler.CompleteTaskHandler(w, r)
+
+	assert.Equal(t, http.StatusConflict, w.Result().StatusCode)
+}
+
+func TestReopenTaskHandler(t *testing.T) {
+	mockService := &MockTaskService{
+		ReopenTaskFunc: func(ctx context.Context, id uint64) (*task.GetTaskResponse, error) {
+			return &task.GetTaskResponse{ID: id, Title: testTitle, Status: task.StatusTodo}, nil
+		},
+	}
+
+	handler := NewTaskHandler(mockService)
+	r := httptest.NewRequest(http.MethodPost, tasksUrl+"/1/reopen", nil)
+	r = mux.SetURLVars(r, map[string]string{"id": "1"})
+	w := httptest.NewRecorder()
+	handler.ReopenTaskHandler(w, r)
+
+	resp := w.Result()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var respBody map[string]interface{}
+	err := json.NewDecoder(resp.Body).Decode(&respBody)
+	assert.NoError(t, err)
+	assert.Equal(t, "todo", respBody["status"])
+}
+
+func TestReopenTaskHandlerWhenNotFound(t *testing.T) {
+	mockService := &MockTaskService{
+		ReopenTaskFunc: func(ctx context.Context, id uint64) (*task.GetTaskResponse, error) {
+			return nil, task.ErrTaskNotFound
+		},
+	}
+
+	handler := NewTaskHandler(mockService)
+	r := httptest.NewRequest(http.MethodPost, tasksUrl+"/9/reopen", nil)
+	r = mux.SetURLVars(r, map[string]string{"id": "9"})
+	w := httptest.NewRecorder()
+	handler.ReopenTaskHandler(w, r)
+
+	assert.Equal(t, http.StatusNotFound, w.Result().StatusCode)
+}
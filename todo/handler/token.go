@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"mkmgo-todo/todo/auth"
+)
+
+type TokenIssuer interface {
+	IssueToken(userID uint64) (string, time.Time, error)
+}
+
+type TokenHandler struct {
+	verifier auth.PasswordVerifier
+	issuer   TokenIssuer
+	now      func() time.Time
+}
+
+func NewTokenHandler(verifier auth.PasswordVerifier, issuer TokenIssuer) *TokenHandler {
+	return &TokenHandler{verifier: verifier, issuer: issuer, now: time.Now}
+}
+
+// LoginHandler exchanges an email and password for an access token to send as a Bearer
+// credential. Like registration it needs no authentication.
+func (h *TokenHandler) LoginHandler(w http.ResponseWriter, r *http.Request) {
+	var req auth.LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, r, "request body is not valid JSON")
+		return
+	}
+	if req.Email == "" || req.Password == "" {
+		writeUnauthorized(w, r, nil, "", "invalid email or password")
+		return
+	}
+	userID, err := h.verifier.VerifyPassword(r.Context(), req.Email, req.Password)
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		writeUnauthorized(w, r, nil, "", "invalid email or password")
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeToken(w, r, h.issuer, h.now(), userID)
+}
+
+// writeToken responds with a new access token for the user.
+func writeToken(w http.ResponseWriter, r *http.Request, issuer TokenIssuer, now time.Time, userID uint64) {
+	token, expiresAt, err := issuer.IssueToken(userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeResponse(w, http.StatusOK, auth.TokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(expiresAt.Sub(now).Round(time.Second).Seconds()),
+	})
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mkmgo-todo/todo/auth"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+	Mock user/service.go and auth/token.go
+*/
+
+type MockPasswordVerifier struct {
+	VerifyPasswordFunc func(ctx context.Context, email, password string) (uint64, error)
+}
+
+func (m *MockPasswordVerifier) VerifyPassword(ctx context.Context, email, password string) (uint64, error) {
+	if m.VerifyPasswordFunc != nil {
+		return m.VerifyPasswordFunc(ctx, email, password)
+	}
+	return 0, auth.ErrInvalidCredentials
+}
+
+type MockTokenIssuer struct {
+	IssueTokenFunc func(userID uint64) (string, time.Time, error)
+}
+
+func (m *MockTokenIssuer) IssueToken(userID uint64) (string, time.Time, error) {
+	if m.IssueTokenFunc != nil {
+		return m.IssueTokenFunc(userID)
+	}
+	return "", time.Time{}, nil
+}
+
+/*
+	Unit test for handler/token.go
+*/
+
+func TestLoginHandler(t *testing.T) {
+	now := time.Now()
+	verifier := &MockPasswordVerifier{
+		VerifyPasswordFunc: func(ctx context.Context, email, password string) (uint64, error) {
+			if email == "ana@example.com" && password == "correct horse" {
+				return 7, nil
+			}
+			return 0, auth.ErrInvalidCredentials
+		},
+	}
+	issuer := &MockTokenIssuer{
+		IssueTokenFunc: func(userID uint64) (string, time.Time, error) {
+			assert.Equal(t, uint64(7), userID)
+			return "token-for-7", now.Add(time.Hour), nil
+		},
+	}
+
+	handler := NewTokenHandler(verifier, issuer)
+	handler.now = func() time.Time { return now }
+	body := `{"email":"ana@example.com","password":"correct horse"}`
+	r := httptest.NewRequest(http.MethodPost, "/todo/login", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	handler.LoginHandler(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	assert.JSONEq(t, `{"accessToken":"token-for-7","tokenType":"Bearer","expiresIn":3600}`, w.Body.String())
+}
+
+func TestLoginHandlerWhenInvalidCredentials(t *testing.T) {
+	handler := NewTokenHandler(&MockPasswordVerifier{}, &MockTokenIssuer{
+		IssueTokenFunc: func(userID uint64) (string, time.Time, error) {
+			t.Fatal("no token may be issued")
+			return "", time.Time{}, nil
+		},
+	})
+
+	for _, body := range []string{`{"email":"ana@example.com","password":"wrong"}`, `{"email":"ana@example.com"}`} {
+		r := httptest.NewRequest(http.MethodPost, "/todo/login", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		handler.LoginHandler(w, r)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code, body)
+		var problem Problem
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&problem))
+		assert.Equal(t, "invalid email or password", problem.Detail)
+	}
+}
+
+func TestLoginHandlerWhenInvalidJSON(t *testing.T) {
+	handler := NewTokenHandler(&MockPasswordVerifier{}, &MockTokenIssuer{})
+	r := httptest.NewRequest(http.MethodPost, "/todo/login", bytes.NewBufferString(`{`))
+	w := httptest.NewRecorder()
+	handler.LoginHandler(w, r)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"mkmgo-todo/todo/auth"
+	"mkmgo-todo/todo/user"
+)
+
+type UserService interface {
+	Register(ctx context.Context, request *user.RegisterRequest) (*user.UserResponse, error)
+	GetUser(ctx context.Context, id uint64) (*user.UserResponse, error)
+}
+
+type UserHandler struct {
+	userSvc UserService
+}
+
+func NewUserHandler(service UserService) *UserHandler {
+	return &UserHandler{userSvc: service}
+}
+
+// RegisterHandler creates an account. Unlike every other API endpoint it needs no authentication.
+func (h *UserHandler) RegisterHandler(w http.ResponseWriter, r *http.Request) {
+	var req user.RegisterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBadRequest(w, r, "request body is not valid JSON")
+		return
+	}
+	res, err := h.userSvc.Register(r.Context(), &req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeResponse(w, http.StatusCreated, res)
+}
+
+// MeHandler returns the account of the authenticated caller.
+func (h *UserHandler) MeHandler(w http.ResponseWriter, r *http.Request) {
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if !ok {
+		writeError(w, r, errors.New("no principal in context, route is missing authentication"))
+		return
+	}
+	res, err := h.userSvc.GetUser(r.Context(), principal.UserID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeResponse(w, http.StatusOK, res)
+}
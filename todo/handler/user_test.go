@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mkmgo-todo/todo/auth"
+	"mkmgo-todo/todo/user"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+	Mock user/service.go
+*/
+
+type MockUserService struct {
+	RegisterFunc func(ctx context.Context, request *user.RegisterRequest) (*user.UserResponse, error)
+	GetUserFunc  func(ctx context.Context, id uint64) (*user.UserResponse, error)
+}
+
+func (m *MockUserService) Register(ctx context.Context, request *user.RegisterRequest) (*user.UserResponse, error) {
+	if m.RegisterFunc != nil {
+		return m.RegisterFunc(ctx, request)
+	}
+	return nil, nil
+}
+
+func (m *MockUserService) GetUser(ctx context.Context, id uint64) (*user.UserResponse, error) {
+	if m.GetUserFunc != nil {
+		return m.GetUserFunc(ctx, id)
+	}
+	return nil, nil
+}
+
+/*
+	Unit test for handler/user.go
+*/
+
+func TestRegisterHandler(t *testing.T) {
+	mockService := &MockUserService{
+		RegisterFunc: func(ctx context.Context, request *user.RegisterRequest) (*user.UserResponse, error) {
+			return &user.UserResponse{ID: 1, Email: request.Email, Name: request.Name}, nil
+		},
+	}
+
+	handler := NewUserHandler(mockService)
+	body := `{"email":"ana@example.com","name":"Ana","password":"correct horse"}`
+	r := httptest.NewRequest(http.MethodPost, "/todo/users", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	handler.RegisterHandler(w, r)
+
+	resp := w.Result()
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var respBody map[string]interface{}
+	err := json.NewDecoder(resp.Body).Decode(&respBody)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"id": float64(1), "email": "ana@example.com", "name": "Ana"}, respBody)
+}
+
+func TestRegisterHandlerWhenEmailTaken(t *testing.T) {
+	mockService := &MockUserService{
+		RegisterFunc: func(ctx context.Context, request *user.RegisterRequest) (*user.UserResponse, error) {
+			return nil, user.ErrEmailTaken
+		},
+	}
+
+	handler := NewUserHandler(mockService)
+	r := httptest.NewRequest(http.MethodPost, "/todo/users", bytes.NewBufferString(`{"email":"ana@example.com"}`))
+	w := httptest.NewRecorder()
+	handler.RegisterHandler(w, r)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestMeHandler(t *testing.T) {
+	mockService := &MockUserService{
+		GetUserFunc: func(ctx context.Context, id uint64) (*user.UserResponse, error) {
+			return &user.UserResponse{ID: id, Email: "ana@example.com", Name: "Ana"}, nil
+		},
+	}
+
+	handler := NewUserHandler(mockService)
+	r := httptest.NewRequest(http.MethodGet, "/todo/users/me", nil)
+	r = r.WithContext(auth.WithPrincipal(r.Context(), auth.Principal{UserID: 7}))
+	w := httptest.NewRecorder()
+	handler.MeHandler(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"id":7,"email":"ana@example.com","name":"Ana"}`, w.Body.String())
+}
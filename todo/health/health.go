@@ -0,0 +1,142 @@
+// Package health serves the liveness and readiness probes.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// checkTimeout bounds every readiness check, so a hung database fails the probe instead of
+// stalling it.
+const checkTimeout = 2 * time.Second
+
+const (
+	StatusUp       = "up"
+	StatusDown     = "down"
+	StatusReady    = "ready"
+	StatusNotReady = "not_ready"
+	StatusDraining = "draining"
+)
+
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckFunc adapts a function to a Checker.
+type CheckFunc func(ctx context.Context) error
+
+func (f CheckFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+type component struct {
+	name    string
+	checker Checker
+}
+
+type ComponentStatus struct {
+	Status  string `json:"status"`
+	Latency string `json:"latency"`
+}
+
+type Report struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentStatus `json:"components"`
+}
+
+// Health decides whether the server is ready to take traffic: every component check must pass,
+// and the server must not be shutting down.
+type Health struct {
+	components []component
+	draining   atomic.Bool
+}
+
+func New() *Health {
+	return &Health{}
+}
+
+// Add registers a component the server cannot serve requests without.
+func (h *Health) Add(name string, checker Checker) {
+	h.components = append(h.components, component{name: name, checker: checker})
+}
+
+// Drain marks the server as shutting down. Readiness fails from then on, so load balancers stop
+// sending new requests while the ones in flight finish.
+func (h *Health) Drain() {
+	h.draining.Store(true)
+}
+
+// Check runs every component check concurrently and reports the outcome.
+func (h *Health) Check(ctx context.Context) Report {
+	statuses := make([]ComponentStatus, len(h.components))
+	var wg sync.WaitGroup
+	for i, c := range h.components {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses[i] = runCheck(ctx, c)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusReady, Components: make(map[string]ComponentStatus, len(h.components))}
+	for i, c := range h.components {
+		report.Components[c.name] = statuses[i]
+		if statuses[i].Status != StatusUp {
+			report.Status = StatusNotReady
+		}
+	}
+	if h.draining.Load() {
+		report.Status = StatusDraining
+	}
+	return report
+}
+
+// runCheck keeps the reason a check failed in the server log: the probe is unauthenticated, and
+// driver and OS errors say too much about the host.
+func runCheck(ctx context.Context, c component) ComponentStatus {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+	start := time.Now()
+	err := c.checker.Check(ctx)
+	status := ComponentStatus{Status: StatusUp, Latency: time.Since(start).String()}
+	if err != nil {
+		status.Status = StatusDown
+		zerolog.Ctx(ctx).Warn().Err(err).Str("component", c.name).Msg("check failed")
+	}
+	return status
+}
+
+// LivenessHandler answers as long as the process can serve HTTP at all. It checks no
+// dependencies: restarting the server would not bring a lost database back.
+func (h *Health) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": StatusUp})
+	})
+}
+
+// ReadinessHandler reports every component, answering 503 unless the server is ready.
+func (h *Health) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := h.Check(r.Context())
+		status := http.StatusOK
+		if report.Status != StatusReady {
+			status = http.StatusServiceUnavailable
+			zerolog.Ctx(r.Context()).Warn().Interface("report", report).Msg("not ready")
+		}
+		writeJSON(w, status, report)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
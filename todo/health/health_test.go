@@ -0,0 +1,113 @@
+package health
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func up(ctx context.Context) error {
+	return nil
+}
+
+func readiness(t *testing.T, h *Health) (int, Report) {
+	w := httptest.NewRecorder()
+	h.ReadinessHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	var report Report
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	return w.Code, report
+}
+
+/* Unit test for LivenessHandler */
+
+func TestLiveness(t *testing.T) {
+	h := New()
+	h.Add("database", CheckFunc(func(ctx context.Context) error { return errors.New("gone") }))
+
+	w := httptest.NewRecorder()
+	h.LivenessHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/livez", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"up"}`, w.Body.String())
+}
+
+/* Unit test for ReadinessHandler */
+
+func TestReadinessWhenAllUp(t *testing.T) {
+	h := New()
+	h.Add("database", CheckFunc(up))
+	h.Add("migrations", CheckFunc(up))
+
+	status, report := readiness(t, h)
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, StatusReady, report.Status)
+	assert.Equal(t, StatusUp, report.Components["database"].Status)
+	assert.Equal(t, StatusUp, report.Components["migrations"].Status)
+}
+
+func TestReadinessWhenComponentDown(t *testing.T) {
+	h := New()
+	h.Add("database", CheckFunc(up))
+	h.Add("migrations", CheckFunc(func(ctx context.Context) error {
+		return errors.New("schema is behind: 1 pending migration(s)")
+	}))
+
+	var logs bytes.Buffer
+	ctx := zerolog.New(&logs).WithContext(context.Background())
+	w := httptest.NewRecorder()
+	h.ReadinessHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil).WithContext(ctx))
+
+	var report Report
+	require.NoError(t, json.NewDecoder(bytes.NewReader(w.Body.Bytes())).Decode(&report))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, StatusNotReady, report.Status)
+	assert.Equal(t, StatusUp, report.Components["database"].Status)
+	assert.Equal(t, ComponentStatus{
+		Status:  StatusDown,
+		Latency: report.Components["migrations"].Latency,
+	}, report.Components["migrations"])
+	assert.NotContains(t, w.Body.String(), "schema is behind")
+	assert.Contains(t, logs.String(), "schema is behind: 1 pending migration(s)")
+}
+
+func TestReadinessWhenCheckHangs(t *testing.T) {
+	h := New()
+	h.Add("database", CheckFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	report := h.Check(ctx)
+
+	assert.Equal(t, StatusNotReady, report.Status)
+	assert.Equal(t, StatusDown, report.Components["database"].Status)
+}
+
+func TestReadinessWhenDraining(t *testing.T) {
+	h := New()
+	h.Add("database", CheckFunc(up))
+
+	status, _ := readiness(t, h)
+	assert.Equal(t, http.StatusOK, status)
+
+	h.Drain()
+	status, report := readiness(t, h)
+
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, StatusDraining, report.Status)
+	assert.Equal(t, StatusUp, report.Components["database"].Status)
+}
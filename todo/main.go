@@ -2,50 +2,163 @@ package main
 
 import (
 	"context"
-	"fmt"
+	"errors"
+	"flag"
+	"mkmgo-todo/todo/apikey"
+	"mkmgo-todo/todo/auth"
+	"mkmgo-todo/todo/config"
+	"mkmgo-todo/todo/database"
 	"mkmgo-todo/todo/handler"
+	"mkmgo-todo/todo/health"
+	"mkmgo-todo/todo/metrics"
+	"mkmgo-todo/todo/middleware"
+	"mkmgo-todo/todo/migration"
+	"mkmgo-todo/todo/pagination"
+	"mkmgo-todo/todo/project"
 	"mkmgo-todo/todo/task"
+	"mkmgo-todo/todo/tracing"
+	"mkmgo-todo/todo/user"
 	"net/http"
 	"os"
 	"os/signal"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
-	"gorm.io/driver/sqlite"
-	"gorm.io/gorm"
 )
 
 func main() {
+	cfg, err := config.Load(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load configuration")
+	}
+	zerolog.SetGlobalLevel(cfg.LogLevel())
+	pagination.DefaultPageSize = cfg.Pagination.DefaultPageSize
+	pagination.MaxPageSize = cfg.Pagination.MaxPageSize
+
 	// Setup database
-	db, err := gorm.Open(sqlite.Open("todo/gorm.db"), &gorm.Config{})
+	db, err := database.Open(cfg.Database)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Database connection failed")
+	}
+	migrator := migration.NewMigrator(db, migration.All)
+	ctx := log.Logger.WithContext(context.Background())
+	if len(cfg.Args) > 0 {
+		if err := runCommand(ctx, db, migrator, cfg.Args); err != nil {
+			log.Fatal().Err(err).Msg("Command failed")
+		}
+		return
+	}
+	if err := migrator.Check(ctx); err != nil {
+		log.Fatal().Err(err).Msg("Database schema is not up to date, run the migrate up command first")
+	}
+
+	// Setup health checks
+	sqlDB, err := db.DB()
 	if err != nil {
 		log.Fatal().Err(err).Msg("Database connection failed")
 	}
-	db.AutoMigrate(&task.Task{})
+	probes := health.New()
+	probes.Add("database", health.CheckFunc(sqlDB.PingContext))
+	probes.Add("migrations", migrator)
+
+	// Setup tracing
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to set up tracing")
+	}
+	if err := db.Use(tracing.NewGORMPlugin()); err != nil {
+		log.Fatal().Err(err).Msg("Failed to trace database queries")
+	}
+
+	// Setup metrics
+	appMetrics := metrics.New()
+	if err := appMetrics.RegisterDB(db); err != nil {
+		log.Fatal().Err(err).Msg("Failed to register database metrics")
+	}
 
 	// Setup repository, service, and handlers
-	taskRepo := task.NewTaskRepositoryImpl(db)
-	taskSvc := task.NewTaskServiceImpl(taskRepo)
+	taskRepoImpl := task.NewTaskRepositoryImpl(db)
+	if err := appMetrics.RegisterTaskCounts(taskRepoImpl); err != nil {
+		log.Fatal().Err(err).Msg("Failed to register task metrics")
+	}
+	taskRepo := metrics.NewTaskRepository(tracing.NewTaskRepository(taskRepoImpl), appMetrics)
+	userRepo := user.NewUserRepositoryImpl(db)
+	projectSvc := project.NewProjectServiceImpl(project.NewProjectRepositoryImpl(db), userRepo)
+	projectHandler := handler.NewProjectHandler(projectSvc)
+	taskSvc := tracing.NewTaskService(task.NewTaskServiceImpl(taskRepo, projectSvc))
 	taskHandler := handler.NewTaskHandler(taskSvc)
+	userSvc := user.NewUserServiceImpl(userRepo)
+	userHandler := handler.NewUserHandler(userSvc)
+	tokens, err := auth.NewTokenAuthenticator(cfg.Auth)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to set up access tokens")
+	}
+	if cfg.Auth.Secret == "" && cfg.Auth.SigningKeyFile == "" {
+		log.Warn().Msg("No auth.secret or auth.signingKeyFile set, access tokens stop working when the server restarts")
+	}
+	tokenHandler := handler.NewTokenHandler(userSvc, tokens)
+	var oidcHandler *handler.OIDCHandler
+	if cfg.Auth.OIDC.Issuer != "" {
+		provider, err := auth.NewOIDCProvider(ctx, cfg.Auth.OIDC)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to set up single sign-on")
+		}
+		sealer, err := tokens.NewSealer("oidc login")
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to set up single sign-on")
+		}
+		oidcHandler = handler.NewOIDCHandler(provider, userSvc, tokens, sealer)
+	}
+	apiKeySvc := apikey.NewAPIKeyServiceImpl(apikey.NewAPIKeyRepositoryImpl(db))
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeySvc)
+	tagHandler := handler.NewTagHandler(task.NewTagServiceImpl(task.NewTagRepositoryImpl(db), projectSvc))
+
+	handler := Handler{
+		taskHandler:      taskHandler,
+		userHandler:      userHandler,
+		tokenHandler:     tokenHandler,
+		apiKeyHandler:    apiKeyHandler,
+		projectHandler:   projectHandler,
+		tagHandler:       tagHandler,
+		oidcHandler:      oidcHandler,
+		authenticate:     handler.Authenticate(tokens, apiKeySvc, auth.NewBasicAuthenticator(userSvc)),
+		metricsHandler:   appMetrics.Handler(),
+		livenessHandler:  probes.LivenessHandler(),
+		readinessHandler: probes.ReadinessHandler(),
+	}
 
-	handler := Handler{taskHandler: taskHandler}
+	// Purge the trash in the background until shutdown
+	purgeCtx, stopPurge := context.WithCancel(ctx)
+	defer stopPurge()
+	if cfg.Trash.Retention > 0 {
+		go task.NewTrashPurger(taskRepo, cfg.Trash.Retention, cfg.Trash.PurgeInterval).Run(purgeCtx)
+	}
 
 	// Setup router and server
 	router := mux.NewRouter()
 	setupRoutes(router, handler)
 
 	server := &http.Server{
-		Addr:    "localhost:8080",
-		Handler: router,
+		Addr: cfg.Server.Addr,
+		Handler: middleware.RequestID(
+			middleware.Tracing(router, "/metrics", "/livez", "/readyz")(
+				middleware.Logging(log.Logger, router)(
+					middleware.Metrics(appMetrics, router)(router)))),
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
 	}
 
-	log.Info().Msg("Start server")
+	log.Info().Str("addr", cfg.Server.Addr).Msg("Start server")
 	go func() {
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			log.Fatal().Err(err).Msg("Server failed")
 		}
-		fmt.Printf("Starting server at port 8080")
 	}()
 
 	// Graceful shutdown
@@ -53,28 +166,94 @@ func main() {
 	signal.Notify(stop, os.Interrupt)
 	<-stop
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	// Report not-ready first so load balancers stop sending requests before the listener closes
+	probes.Drain()
+	log.Info().Dur("drainDelay", cfg.Server.DrainDelay).Msg("Draining server")
+	time.Sleep(cfg.Server.DrainDelay)
+
+	stopPurge()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer cancel()
-	if err := server.Shutdown(ctx); err != nil {
+	if err := server.Shutdown(shutdownCtx); err != nil {
 		log.Fatal().Err(err).Msg("Server shutdown failed")
 	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		log.Error().Err(err).Msg("Failed to flush traces")
+	}
 
 	log.Info().Msg("Server stopped successfully")
 }
 
 type Handler struct {
-	taskHandler *handler.TaskHandler
+	taskHandler      *handler.TaskHandler
+	userHandler      *handler.UserHandler
+	tokenHandler     *handler.TokenHandler
+	apiKeyHandler    *handler.APIKeyHandler
+	projectHandler   *handler.ProjectHandler
+	tagHandler       *handler.TagHandler
+	oidcHandler      *handler.OIDCHandler // nil without single sign-on
+	authenticate     func(http.Handler) http.Handler
+	metricsHandler   http.Handler
+	livenessHandler  http.Handler
+	readinessHandler http.Handler
 }
 
 func setupRoutes(router *mux.Router, h Handler) {
-	router.HandleFunc("/todo/tasks/health", healthCheck).Methods("GET")
-	router.HandleFunc("/todo/tasks", h.taskHandler.WriteTaskHandler).Methods("POST")
-	router.HandleFunc("/todo/tasks/{id}", h.taskHandler.UpdateTaskHandler).Methods("PATCH")
-	router.HandleFunc("/todo/tasks", h.taskHandler.GetAllTaskHandler).Methods("GET")
-	router.HandleFunc("/todo/tasks/{id}", h.taskHandler.DeleteTaskHandler).Methods("DELETE")
-}
+	router.NotFoundHandler = handler.NotFoundHandler()
+	router.MethodNotAllowedHandler = handler.MethodNotAllowedHandler()
+
+	router.Handle("/livez", h.livenessHandler).Methods("GET")
+	router.Handle("/readyz", h.readinessHandler).Methods("GET")
+	router.Handle("/metrics", h.metricsHandler).Methods("GET")
+	router.HandleFunc("/todo/users", h.userHandler.RegisterHandler).Methods("POST")
+	router.HandleFunc("/todo/login", h.tokenHandler.LoginHandler).Methods("POST")
+	if h.oidcHandler != nil {
+		router.HandleFunc("/todo/oidc/login", h.oidcHandler.LoginHandler).Methods("GET")
+		router.HandleFunc("/todo/oidc/callback", h.oidcHandler.CallbackHandler).Methods("GET")
+	}
+
+	// Everything else under /todo acts for the authenticated user
+	api := router.PathPrefix("/todo").Subrouter()
+	api.Use(mux.MiddlewareFunc(h.authenticate), handler.Authorize)
+	api.HandleFunc("/users/me", h.userHandler.MeHandler).Methods("GET")
+	api.HandleFunc("/tasks", h.taskHandler.WriteTaskHandler).Methods("POST")
+	api.HandleFunc("/tasks/{id}", h.taskHandler.UpdateTaskHandler).Methods("PATCH")
+	api.HandleFunc("/tasks/{id}", h.taskHandler.ReplaceTaskHandler).Methods("PUT")
+	api.HandleFunc("/tasks", h.taskHandler.GetAllTaskHandler).Methods("GET")
+	api.HandleFunc("/tasks/trash", h.taskHandler.GetTrashHandler).Methods("GET")
+	api.HandleFunc("/tasks/{id}", h.taskHandler.GetTaskHandler).Methods("GET")
+	api.HandleFunc("/tasks/{id}", h.taskHandler.DeleteTaskHandler).Methods("DELETE")
+	api.HandleFunc("/tasks/{id}/status", h.taskHandler.UpdateTaskStatusHandler).Methods("PUT")
+	api.HandleFunc("/tasks/{id}/complete", h.taskHandler.CompleteTaskHandler).Methods("POST")
+	api.HandleFunc("/tasks/{id}/reopen", h.taskHandler.ReopenTaskHandler).Methods("POST")
+	api.HandleFunc("/tasks/{id}/restore", h.taskHandler.RestoreTaskHandler).Methods("POST")
+	api.HandleFunc("/projects", h.projectHandler.CreateProjectHandler).Methods("POST")
+	api.HandleFunc("/projects", h.projectHandler.GetProjectsHandler).Methods("GET")
+	api.HandleFunc("/projects/{id}", h.projectHandler.GetProjectHandler).Methods("GET")
+	api.HandleFunc("/projects/{id}", h.projectHandler.UpdateProjectHandler).Methods("PATCH")
+	api.HandleFunc("/projects/{id}", h.projectHandler.DeleteProjectHandler).Methods("DELETE")
+	api.HandleFunc("/projects/{id}/tasks", h.taskHandler.GetProjectTasksHandler).Methods("GET")
+	api.HandleFunc("/projects/{id}/tasks", h.taskHandler.WriteProjectTaskHandler).Methods("POST")
+	api.HandleFunc("/projects/{id}/members", h.projectHandler.GetMembersHandler).Methods("GET")
+	api.HandleFunc("/projects/{id}/members", h.projectHandler.AddMemberHandler).Methods("POST")
+	api.HandleFunc("/projects/{id}/members/{userId}", h.projectHandler.UpdateMemberHandler).Methods("PATCH")
+	api.HandleFunc("/projects/{id}/members/{userId}", h.projectHandler.RemoveMemberHandler).Methods("DELETE")
+	api.HandleFunc("/tags", h.tagHandler.GetTagsHandler).Methods("GET")
+	api.HandleFunc("/tags", h.tagHandler.CreateTagHandler).Methods("POST")
+	api.HandleFunc("/tags/{id}", h.tagHandler.UpdateTagHandler).Methods("PATCH")
+	api.HandleFunc("/tags/{id}", h.tagHandler.DeleteTagHandler).Methods("DELETE")
 
-func healthCheck(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("OK"))
+	// Only credentials with the admin scope may manage API keys
+	keys := api.PathPrefix("/api-keys").Subrouter()
+	keys.Use(handler.RequireScope(auth.ScopeAdmin))
+	keys.HandleFunc("", h.apiKeyHandler.CreateAPIKeyHandler).Methods("POST")
+	keys.HandleFunc("", h.apiKeyHandler.GetAPIKeysHandler).Methods("GET")
+	keys.HandleFunc("/{id}", h.apiKeyHandler.UpdateAPIKeyHandler).Methods("PATCH")
+	keys.HandleFunc("/{id}", h.apiKeyHandler.RevokeAPIKeyHandler).Methods("DELETE")
+
+	if h.oidcHandler != nil {
+		link := api.PathPrefix("/oidc/link").Subrouter()
+		link.Use(handler.RequireScope(auth.ScopeAdmin))
+		link.HandleFunc("", h.oidcHandler.LinkHandler).Methods("POST")
+	}
 }
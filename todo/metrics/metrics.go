@@ -0,0 +1,136 @@
+// Package metrics exposes the service's Prometheus metrics.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"mkmgo-todo/todo/task"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"gorm.io/gorm"
+)
+
+const namespace = "todo"
+
+// Metrics owns a registry holding every metric the service reports. Each instance has its own
+// registry, so tests do not trip over metrics registered elsewhere.
+type Metrics struct {
+	registry        *prometheus.Registry
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	repoDuration    *prometheus.HistogramVec
+	repoErrors      *prometheus.CounterVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests served, by method, route template and status code.",
+		}, []string{"method", "route", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Time taken to serve HTTP requests, by method, route template and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		repoDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "repository_operation_duration_seconds",
+			Help:      "Time taken by task repository operations, by operation.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"operation"}),
+		repoErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "repository_operation_errors_total",
+			Help:      "Task repository operations that failed, by operation and error kind.",
+		}, []string{"operation", "kind"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.repoDuration,
+		m.repoErrors,
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format. A collector that fails, such as the
+// task counts while the database is down, is reported without hiding the other metrics.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError})
+}
+
+// knownMethods keeps made-up request methods from each adding a label value.
+var knownMethods = map[string]bool{
+	http.MethodGet: true, http.MethodHead: true, http.MethodPost: true, http.MethodPut: true,
+	http.MethodPatch: true, http.MethodDelete: true, http.MethodOptions: true,
+}
+
+// ObserveRequest records one served HTTP request.
+func (m *Metrics) ObserveRequest(method, route string, status int, elapsed time.Duration) {
+	if !knownMethods[method] {
+		method = "OTHER"
+	}
+	code := strconv.Itoa(status)
+	m.requests.WithLabelValues(method, route, code).Inc()
+	m.requestDuration.WithLabelValues(method, route, code).Observe(elapsed.Seconds())
+}
+
+// startOperation starts timing a task repository call. The returned function ends it, counting
+// the call as failed if err is set.
+func (m *Metrics) startOperation(operation string) func(err error) {
+	start := time.Now()
+	return func(err error) {
+		m.repoDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+		if err != nil {
+			m.repoErrors.WithLabelValues(operation, errorKind(err)).Inc()
+		}
+	}
+}
+
+// RegisterDB reports the connection pool statistics of db.
+func (m *Metrics) RegisterDB(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get database handle: %w", err)
+	}
+	return m.registry.Register(collectors.NewDBStatsCollector(sqlDB, namespace))
+}
+
+// RegisterTaskCounts reports how many tasks there are per status, and how many of them are open,
+// counted afresh on every scrape.
+func (m *Metrics) RegisterTaskCounts(counter TaskCounter) error {
+	return m.registry.Register(newTaskCollector(counter))
+}
+
+// errorKind names the error kind err belongs to, keeping the label values to a fixed set.
+func errorKind(err error) string {
+	switch {
+	case errors.Is(err, task.ErrNotFound):
+		return "not_found"
+	case errors.Is(err, task.ErrValidation):
+		return "validation"
+	case errors.Is(err, task.ErrConflict):
+		return "conflict"
+	case errors.Is(err, task.ErrPreconditionFailed):
+		return "precondition_failed"
+	case errors.Is(err, task.ErrForbidden):
+		return "forbidden"
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return "canceled"
+	default:
+		return "internal"
+	}
+}
@@ -0,0 +1,141 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"mkmgo-todo/todo/task"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+/* Mock TaskRepository */
+
+type MockTaskRepository struct {
+	task.TaskRepository
+	GetTaskFunc func(ctx context.Context, id uint64) (*task.Task, error)
+}
+
+func (m *MockTaskRepository) GetTask(ctx context.Context, id uint64) (*task.Task, error) {
+	return m.GetTaskFunc(ctx, id)
+}
+
+/* Mock TaskCounter */
+
+type MockTaskCounter struct {
+	CountTasksByStatusFunc func(ctx context.Context) (map[task.Status]int64, error)
+}
+
+func (m *MockTaskCounter) CountTasksByStatus(ctx context.Context) (map[task.Status]int64, error) {
+	return m.CountTasksByStatusFunc(ctx)
+}
+
+/* Unit test for Metrics */
+
+func TestObserveRequest(t *testing.T) {
+	m := New()
+
+	m.ObserveRequest(http.MethodGet, "/todo/tasks/{id}", http.StatusOK, 20*time.Millisecond)
+	m.ObserveRequest(http.MethodGet, "/todo/tasks/{id}", http.StatusOK, 30*time.Millisecond)
+	m.ObserveRequest(http.MethodGet, "/todo/tasks/{id}", http.StatusNotFound, time.Millisecond)
+	m.ObserveRequest("BREW", "unmatched", http.StatusMethodNotAllowed, time.Millisecond)
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.requests.WithLabelValues("GET", "/todo/tasks/{id}", "200")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.requests.WithLabelValues("GET", "/todo/tasks/{id}", "404")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.requests.WithLabelValues("OTHER", "unmatched", "405")))
+	assert.Equal(t, 3, testutil.CollectAndCount(m.requestDuration))
+}
+
+func TestTaskRepositoryRecordsOperations(t *testing.T) {
+	m := New()
+	calls := 0
+	repo := NewTaskRepository(&MockTaskRepository{
+		GetTaskFunc: func(ctx context.Context, id uint64) (*task.Task, error) {
+			calls++
+			switch id {
+			case 1:
+				return &task.Task{ID: id}, nil
+			case 2:
+				return nil, fmt.Errorf("%w: id %d", task.ErrTaskNotFound, id)
+			default:
+				return nil, errors.New("connection refused")
+			}
+		},
+	}, m)
+
+	found, err := repo.GetTask(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), found.ID)
+	_, err = repo.GetTask(context.Background(), 2)
+	assert.ErrorIs(t, err, task.ErrTaskNotFound)
+	_, err = repo.GetTask(context.Background(), 3)
+	assert.EqualError(t, err, "connection refused")
+
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, 1, testutil.CollectAndCount(m.repoDuration))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.repoErrors.WithLabelValues("GetTask", "not_found")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.repoErrors.WithLabelValues("GetTask", "internal")))
+}
+
+func TestErrorKind(t *testing.T) {
+	for err, kind := range map[error]string{
+		fmt.Errorf("%w: id 2", task.ErrTaskNotFound): "not_found",
+		task.ErrTaskExists:                           "conflict",
+		task.ErrTaskVersionMismatch:                  "precondition_failed",
+		task.ErrTaskForbidden:                        "forbidden",
+		context.DeadlineExceeded:                     "canceled",
+		errors.New("connection refused"):             "internal",
+	} {
+		assert.Equal(t, kind, errorKind(err), err.Error())
+	}
+}
+
+func TestTaskCounts(t *testing.T) {
+	m := New()
+	err := m.RegisterTaskCounts(&MockTaskCounter{
+		CountTasksByStatusFunc: func(ctx context.Context) (map[task.Status]int64, error) {
+			return map[task.Status]int64{task.StatusTodo: 3, task.StatusInProgress: 2, task.StatusDone: 7}, nil
+		},
+	})
+	assert.NoError(t, err)
+
+	err = testutil.GatherAndCompare(m.registry, strings.NewReader(`
+# HELP todo_open_tasks Tasks outside the trash that are neither done nor cancelled.
+# TYPE todo_open_tasks gauge
+todo_open_tasks 5
+# HELP todo_tasks Tasks outside the trash, by status.
+# TYPE todo_tasks gauge
+todo_tasks{status="cancelled"} 0
+todo_tasks{status="done"} 7
+todo_tasks{status="in_progress"} 2
+todo_tasks{status="todo"} 3
+`), "todo_open_tasks", "todo_tasks")
+	assert.NoError(t, err)
+}
+
+func TestHandlerWhenTaskCountsFail(t *testing.T) {
+	m := New()
+	m.ObserveRequest(http.MethodGet, "/todo/tasks", http.StatusOK, time.Millisecond)
+	err := m.RegisterTaskCounts(&MockTaskCounter{
+		CountTasksByStatusFunc: func(ctx context.Context) (map[task.Status]int64, error) {
+			return nil, errors.New("database is down")
+		},
+	})
+	assert.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	body, _ := io.ReadAll(w.Body)
+	assert.Contains(t, string(body), `todo_http_requests_total{method="GET",route="/todo/tasks",status="200"} 1`)
+	assert.NotContains(t, string(body), "todo_open_tasks")
+}
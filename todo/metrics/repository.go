@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"mkmgo-todo/todo/task"
+)
+
+// TaskRepository wraps a task.TaskRepository and times every call to it.
+type TaskRepository struct {
+	next    task.TaskRepository
+	metrics *Metrics
+}
+
+func NewTaskRepository(next task.TaskRepository, metrics *Metrics) *TaskRepository {
+	return &TaskRepository{next: next, metrics: metrics}
+}
+
+func (r *TaskRepository) SaveTask(ctx context.Context, t *task.Task) error {
+	done := r.metrics.startOperation("SaveTask")
+	err := r.next.SaveTask(ctx, t)
+	done(err)
+	return err
+}
+
+func (r *TaskRepository) UpdateTask(ctx context.Context, t *task.Task) error {
+	done := r.metrics.startOperation("UpdateTask")
+	err := r.next.UpdateTask(ctx, t)
+	done(err)
+	return err
+}
+
+func (r *TaskRepository) GetTask(ctx context.Context, id uint64) (*task.Task, error) {
+	done := r.metrics.startOperation("GetTask")
+	t, err := r.next.GetTask(ctx, id)
+	done(err)
+	return t, err
+}
+
+func (r *TaskRepository) GetTaskIncludingTrash(ctx context.Context, id uint64) (*task.Task, error) {
+	done := r.metrics.startOperation("GetTaskIncludingTrash")
+	t, err := r.next.GetTaskIncludingTrash(ctx, id)
+	done(err)
+	return t, err
+}
+
+func (r *TaskRepository) GetAllTasks(ctx context.Context, request task.GetAllTaskRequest) ([]task.Task, int64, error) {
+	done := r.metrics.startOperation("GetAllTasks")
+	tasks, total, err := r.next.GetAllTasks(ctx, request)
+	done(err)
+	return tasks, total, err
+}
+
+func (r *TaskRepository) DeleteTask(ctx context.Context, id uint64) error {
+	done := r.metrics.startOperation("DeleteTask")
+	err := r.next.DeleteTask(ctx, id)
+	done(err)
+	return err
+}
+
+func (r *TaskRepository) GetTrashedTasks(ctx context.Context, request task.GetAllTaskRequest) ([]task.Task, int64, error) {
+	done := r.metrics.startOperation("GetTrashedTasks")
+	tasks, total, err := r.next.GetTrashedTasks(ctx, request)
+	done(err)
+	return tasks, total, err
+}
+
+func (r *TaskRepository) RestoreTask(ctx context.Context, id uint64) error {
+	done := r.metrics.startOperation("RestoreTask")
+	err := r.next.RestoreTask(ctx, id)
+	done(err)
+	return err
+}
+
+func (r *TaskRepository) PurgeTask(ctx context.Context, id uint64) error {
+	done := r.metrics.startOperation("PurgeTask")
+	err := r.next.PurgeTask(ctx, id)
+	done(err)
+	return err
+}
+
+func (r *TaskRepository) PurgeTrash(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	done := r.metrics.startOperation("PurgeTrash")
+	purged, err := r.next.PurgeTrash(ctx, deletedBefore)
+	done(err)
+	return purged, err
+}
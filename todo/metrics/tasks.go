@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"mkmgo-todo/todo/task"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// countTimeout bounds the count query so a slow database cannot stall a scrape.
+const countTimeout = 5 * time.Second
+
+type TaskCounter interface {
+	CountTasksByStatus(ctx context.Context) (map[task.Status]int64, error)
+}
+
+var statuses = []task.Status{task.StatusTodo, task.StatusInProgress, task.StatusDone, task.StatusCancelled}
+
+// taskCollector turns the task counts into gauges when the registry is scraped.
+type taskCollector struct {
+	counter TaskCounter
+	tasks   *prometheus.Desc
+	open    *prometheus.Desc
+}
+
+func newTaskCollector(counter TaskCounter) *taskCollector {
+	return &taskCollector{
+		counter: counter,
+		tasks: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "tasks"),
+			"Tasks outside the trash, by status.", []string{"status"}, nil),
+		open: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "open_tasks"),
+			"Tasks outside the trash that are neither done nor cancelled.", nil, nil),
+	}
+}
+
+func (c *taskCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.tasks
+	ch <- c.open
+}
+
+func (c *taskCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), countTimeout)
+	defer cancel()
+	counts, err := c.counter.CountTasksByStatus(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.tasks, err)
+		ch <- prometheus.NewInvalidMetric(c.open, err)
+		return
+	}
+
+	var open int64
+	for _, status := range statuses {
+		ch <- prometheus.MustNewConstMetric(c.tasks, prometheus.GaugeValue, float64(counts[status]), string(status))
+		if (task.Task{Status: status}).IsOpen() {
+			open += counts[status]
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(c.open, prometheus.GaugeValue, float64(open))
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Logging attaches a logger describing the request to its context, which is what every
+// zerolog.Ctx call further down picks up, and writes one access-log line per request once it is
+// served. It expects to run inside RequestID, and inside Tracing for log lines to carry the
+// trace ID.
+func Logging(base zerolog.Logger, routes *mux.Router) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			logCtx := base.With().
+				Str("requestId", RequestIDFromContext(r.Context())).
+				Str("httpMethod", r.Method).
+				Str("route", RouteTemplate(routes, r)).
+				Str("remoteAddr", r.RemoteAddr)
+			if span := trace.SpanContextFromContext(r.Context()); span.IsValid() {
+				logCtx = logCtx.Str("traceId", span.TraceID().String())
+			}
+			log := logCtx.Logger()
+
+			recorder := newStatusRecorder(w)
+			next.ServeHTTP(recorder, r.WithContext(log.WithContext(r.Context())))
+
+			log.Info().
+				Str("path", r.URL.Path).
+				Int("status", recorder.status).
+				Int64("bytes", recorder.written).
+				Dur("latency", time.Since(start)).
+				Msg("request served")
+		})
+	}
+}
+
+// RouteTemplate names the route a request matches, such as /todo/tasks/{id}, rather than its path,
+// keeping IDs out of log and metric labels. Requests that match no route are "unmatched".
+func RouteTemplate(routes *mux.Router, r *http.Request) string {
+	var match mux.RouteMatch
+	if routes.Match(r, &match) && match.Route != nil {
+		if template, err := match.Route.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return "unmatched"
+}
+
+// statusRecorder remembers the status code and body size of a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status  int
+	written int64
+}
+
+func newStatusRecorder(w http.ResponseWriter) *statusRecorder {
+	return &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(b)
+	r.written += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
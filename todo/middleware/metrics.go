@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type RequestObserver interface {
+	ObserveRequest(method, route string, status int, elapsed time.Duration)
+}
+
+// Metrics reports every request to observer, labelled by route template rather than path.
+func Metrics(observer RequestObserver, routes *mux.Router) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			recorder := newStatusRecorder(w)
+			next.ServeHTTP(recorder, r)
+			observer.ObserveRequest(r.Method, RouteTemplate(routes, r), recorder.status, time.Since(start))
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+/* Unit test for RequestID */
+
+func TestRequestIDGeneratesID(t *testing.T) {
+	var seen string
+	h := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestIDFromContext(r.Context())
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/todo/tasks", nil))
+
+	assert.Len(t, seen, 32)
+	assert.Equal(t, seen, w.Header().Get(RequestIDHeader))
+}
+
+func TestRequestIDPropagatesIncomingID(t *testing.T) {
+	var seen string
+	h := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestIDFromContext(r.Context())
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/todo/tasks", nil)
+	r.Header.Set(RequestIDHeader, "3f2b9c1e-upstream")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	assert.Equal(t, "3f2b9c1e-upstream", seen)
+	assert.Equal(t, "3f2b9c1e-upstream", w.Header().Get(RequestIDHeader))
+}
+
+func TestRequestIDReplacesUnsafeID(t *testing.T) {
+	for _, id := range []string{"has space", "line\nbreak", strings.Repeat("a", 129)} {
+		var seen string
+		h := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			seen = RequestIDFromContext(r.Context())
+		}))
+
+		r := httptest.NewRequest(http.MethodGet, "/todo/tasks", nil)
+		r.Header.Set(RequestIDHeader, id)
+		h.ServeHTTP(httptest.NewRecorder(), r)
+
+		assert.NotEqual(t, id, seen)
+		assert.Len(t, seen, 32)
+	}
+}
+
+/* Unit test for Logging */
+
+func newLoggedRouter(buf *bytes.Buffer) http.Handler {
+	router := mux.NewRouter()
+	router.HandleFunc("/todo/tasks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		zerolog.Ctx(r.Context()).Info().Msg("inside handler")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("created"))
+	}).Methods("GET")
+	return RequestID(Logging(zerolog.New(buf), router)(router))
+}
+
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		lines = append(lines, entry)
+	}
+	return lines
+}
+
+func TestLoggingAttachesRequestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	r := httptest.NewRequest(http.MethodGet, "/todo/tasks/7", nil)
+	r.Header.Set(RequestIDHeader, "req-1")
+	r.RemoteAddr = "192.0.2.1:1234"
+	newLoggedRouter(&buf).ServeHTTP(httptest.NewRecorder(), r)
+
+	lines := decodeLines(t, &buf)
+	require.Len(t, lines, 2)
+	for _, line := range lines {
+		assert.Equal(t, "req-1", line["requestId"])
+		assert.Equal(t, "GET", line["httpMethod"])
+		assert.Equal(t, "/todo/tasks/{id}", line["route"])
+		assert.Equal(t, "192.0.2.1:1234", line["remoteAddr"])
+	}
+	assert.Equal(t, "inside handler", lines[0]["message"])
+}
+
+func TestLoggingWritesAccessLine(t *testing.T) {
+	var buf bytes.Buffer
+	newLoggedRouter(&buf).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/todo/tasks/7", nil))
+
+	lines := decodeLines(t, &buf)
+	access := lines[len(lines)-1]
+	assert.Equal(t, "request served", access["message"])
+	assert.Equal(t, "/todo/tasks/7", access["path"])
+	assert.Equal(t, float64(http.StatusCreated), access["status"])
+	assert.Equal(t, float64(len("created")), access["bytes"])
+	assert.Contains(t, access, "latency")
+}
+
+func TestLoggingUnmatchedRoute(t *testing.T) {
+	var buf bytes.Buffer
+	w := httptest.NewRecorder()
+	newLoggedRouter(&buf).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/nowhere/7", nil))
+
+	lines := decodeLines(t, &buf)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "unmatched", lines[0]["route"])
+	assert.Equal(t, float64(http.StatusNotFound), lines[0]["status"])
+}
+
+/* Mock RequestObserver */
+
+type MockRequestObserver struct {
+	ObserveRequestFunc func(method, route string, status int, elapsed time.Duration)
+}
+
+func (m *MockRequestObserver) ObserveRequest(method, route string, status int, elapsed time.Duration) {
+	m.ObserveRequestFunc(method, route, status, elapsed)
+}
+
+/* Unit test for Metrics */
+
+func TestMetricsObservesRouteAndStatus(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/todo/tasks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}).Methods("DELETE")
+
+	var method, route string
+	var status int
+	observer := &MockRequestObserver{
+		ObserveRequestFunc: func(m, r string, s int, elapsed time.Duration) {
+			method, route, status = m, r, s
+		},
+	}
+	Metrics(observer, router)(router).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "/todo/tasks/12", nil))
+
+	assert.Equal(t, http.MethodDelete, method)
+	assert.Equal(t, "/todo/tasks/{id}", route)
+	assert.Equal(t, http.StatusNoContent, status)
+}
+
+/* Unit test for Tracing */
+
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	return recorder
+}
+
+func TestTracingNamesSpanAfterRoute(t *testing.T) {
+	recorder := recordSpans(t)
+	var buf bytes.Buffer
+	router := mux.NewRouter()
+	router.HandleFunc("/todo/tasks/{id}", func(w http.ResponseWriter, r *http.Request) {}).Methods("GET")
+	h := RequestID(Tracing(router)(Logging(zerolog.New(&buf), router)(router)))
+
+	r := httptest.NewRequest(http.MethodGet, "/todo/tasks/7", nil)
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "GET /todo/tasks/{id}", spans[0].Name())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", spans[0].Parent().SpanID().String())
+	assert.Contains(t, spans[0].Attributes(), semconv.HTTPRoute("/todo/tasks/{id}"))
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", decodeLines(t, &buf)[0]["traceId"])
+}
+
+func TestTracingSkipsUntracedPaths(t *testing.T) {
+	recorder := recordSpans(t)
+	router := mux.NewRouter()
+	router.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {}).Methods("GET")
+
+	Tracing(router, "/metrics")(router).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Empty(t, recorder.Ended())
+}
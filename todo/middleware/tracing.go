@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing starts a server span per request, named after its route template and continuing the
+// trace of the caller when the request carries W3C trace context. Requests to the untraced paths,
+// such as metric scrapes, get no span.
+func Tracing(routes *mux.Router, untraced ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			trace.SpanFromContext(r.Context()).SetAttributes(semconv.HTTPRoute(RouteTemplate(routes, r)))
+			next.ServeHTTP(w, r)
+		})
+		return otelhttp.NewHandler(inner, "http.server",
+			otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+				return r.Method + " " + RouteTemplate(routes, r)
+			}),
+			otelhttp.WithFilter(func(r *http.Request) bool {
+				return !slices.Contains(untraced, r.URL.Path)
+			}),
+		)
+	}
+}
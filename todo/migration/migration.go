@@ -0,0 +1,219 @@
+// Package migration evolves the database schema through ordered, versioned migrations and records
+// the applied ones in the schema_migrations table.
+package migration
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrSchemaBehind   = errors.New("database schema is behind")
+	ErrUnknownVersion = errors.New("unknown migration version")
+)
+
+// Migration is one step of the schema history. Up and Down run in a transaction together with
+// the bookkeeping in schema_migrations, and must not depend on the current Go models, which move
+// on while a migration stays as it was released.
+type Migration struct {
+	Version uint64
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// appliedMigration is a row of schema_migrations.
+type appliedMigration struct {
+	Version   uint64    `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (appliedMigration) TableName() string {
+	return "schema_migrations"
+}
+
+type Status struct {
+	Version   uint64
+	Name      string
+	AppliedAt *time.Time // nil while pending
+}
+
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// NewMigrator orders the migrations by version. Versions must be unique and non-zero, zero being
+// the empty schema.
+func NewMigrator(db *gorm.DB, migrations []Migration) *Migrator {
+	sorted := slices.Clone(migrations)
+	slices.SortFunc(sorted, func(a, b Migration) int { return cmp.Compare(a.Version, b.Version) })
+	for i, m := range sorted {
+		if m.Version == 0 || (i > 0 && sorted[i-1].Version == m.Version) {
+			panic(fmt.Sprintf("migration: invalid or duplicate version %d", m.Version))
+		}
+	}
+	return &Migrator{db: db, migrations: sorted}
+}
+
+// Latest is the version the schema has once every migration is applied.
+func (m *Migrator) Latest() uint64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up applies every pending migration.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.To(ctx, m.Latest())
+}
+
+// Down rolls back the most recently applied migration.
+func (m *Migrator) Down(ctx context.Context) error {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return err
+	}
+	if len(applied) == 0 {
+		return nil
+	}
+	current := slices.Max(applied)
+	previous := uint64(0)
+	for _, version := range applied {
+		if version < current && version > previous {
+			previous = version
+		}
+	}
+	return m.To(ctx, previous)
+}
+
+// To brings the schema to the given version: pending migrations up to and including it are applied
+// in order, applied ones above it are rolled back newest first.
+func (m *Migrator) To(ctx context.Context, version uint64) error {
+	if version != 0 && !slices.ContainsFunc(m.migrations, func(mg Migration) bool { return mg.Version == version }) {
+		return fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return err
+	}
+	for _, v := range applied {
+		if v > version && !slices.ContainsFunc(m.migrations, func(mg Migration) bool { return mg.Version == v }) {
+			return fmt.Errorf("%w: %d is applied but this build cannot roll it back", ErrUnknownVersion, v)
+		}
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		mg := m.migrations[i]
+		if mg.Version > version && slices.Contains(applied, mg.Version) {
+			if err := m.run(ctx, mg, false); err != nil {
+				return err
+			}
+		}
+	}
+	for _, mg := range m.migrations {
+		if mg.Version <= version && !slices.Contains(applied, mg.Version) {
+			if err := m.run(ctx, mg, true); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Status lists every known migration with the time it was applied.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+	var rows []appliedMigration
+	if err := m.db.WithContext(ctx).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	statuses := make([]Status, len(m.migrations))
+	for i, mg := range m.migrations {
+		statuses[i] = Status{Version: mg.Version, Name: mg.Name}
+		for _, row := range rows {
+			if row.Version == mg.Version {
+				appliedAt := row.AppliedAt
+				statuses[i].AppliedAt = &appliedAt
+			}
+		}
+	}
+	return statuses, nil
+}
+
+// Check fails with ErrSchemaBehind while any migration is pending.
+func (m *Migrator) Check(ctx context.Context) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	pending := 0
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending++
+		}
+	}
+	if pending > 0 {
+		return fmt.Errorf("%w: %d pending migration(s), latest is %d", ErrSchemaBehind, pending, m.Latest())
+	}
+	return nil
+}
+
+func (m *Migrator) applied(ctx context.Context) ([]uint64, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+	var versions []uint64
+	if err := m.db.WithContext(ctx).Model(&appliedMigration{}).Pluck("version", &versions).Error; err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	return versions, nil
+}
+
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	db := m.db.WithContext(ctx)
+	if db.Migrator().HasTable(&appliedMigration{}) {
+		return nil
+	}
+	if err := db.Migrator().CreateTable(&appliedMigration{}); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	return nil
+}
+
+func (m *Migrator) run(ctx context.Context, mg Migration, up bool) error {
+	log := zerolog.Ctx(ctx).With().Str("method", "migrator.run").Uint64("version", mg.Version).Str("name", mg.Name).Logger()
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if up {
+			if err := mg.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&appliedMigration{Version: mg.Version, Name: mg.Name, AppliedAt: tx.NowFunc()}).Error
+		}
+		if err := mg.Down(tx); err != nil {
+			return err
+		}
+		return tx.Delete(&appliedMigration{}, mg.Version).Error
+	})
+	direction := "apply"
+	if !up {
+		direction = "roll back"
+	}
+	if err != nil {
+		log.Error().Err(err).Msgf("failed to %s migration", direction)
+		return fmt.Errorf("failed to %s migration %d %s: %w", direction, mg.Version, mg.Name, err)
+	}
+	log.Info().Msgf("success to %s migration", direction)
+	return nil
+}
@@ -0,0 +1,160 @@
+package migration
+
+import (
+	"context"
+	"mkmgo-todo/todo/apikey"
+	"mkmgo-todo/todo/config"
+	"mkmgo-todo/todo/database"
+	"mkmgo-todo/todo/project"
+	"mkmgo-todo/todo/task"
+	"mkmgo-todo/todo/user"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+func openDB(t *testing.T) *gorm.DB {
+	db, err := database.Open(config.DatabaseConfig{Driver: config.DriverSQLite, DSN: filepath.Join(t.TempDir(), "todo.db")})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func appliedVersions(t *testing.T, m *Migrator) []uint64 {
+	versions, err := m.applied(context.Background())
+	require.NoError(t, err)
+	return versions
+}
+
+/* Unit test for Migrator */
+
+func TestUpMatchesTaskModel(t *testing.T) {
+	db := openDB(t)
+	m := NewMigrator(db, All)
+
+	require.NoError(t, m.Up(context.Background()))
+
+	for _, model := range []any{&task.Task{}, &user.User{}, &apikey.APIKey{}, &project.Project{}, &project.Member{}, &task.Tag{}, &task.TaskTag{}} {
+		s, err := schema.Parse(model, &sync.Map{}, db.NamingStrategy)
+		require.NoError(t, err)
+		for _, field := range s.Fields {
+			if field.DBName != "" {
+				assert.True(t, db.Migrator().HasColumn(model, field.DBName), s.Table+"."+field.DBName)
+			}
+		}
+	}
+	for _, index := range []string{"Status", "DueAt", "DeletedAt", "OwnerID", "ProjectID"} {
+		assert.True(t, db.Migrator().HasIndex(&task.Task{}, index), index)
+	}
+	assert.True(t, db.Migrator().HasIndex(&user.User{}, "Email"))
+	assert.True(t, db.Migrator().HasIndex(&user.User{}, "idx_user_account_external"))
+	assert.True(t, db.Migrator().HasIndex(&apikey.APIKey{}, "Prefix"))
+	assert.True(t, db.Migrator().HasIndex(&project.Member{}, "UserID"))
+	assert.True(t, db.Migrator().HasIndex(&task.Tag{}, "idx_tag_scope"))
+	assert.True(t, db.Migrator().HasIndex(&task.TaskTag{}, "TagID"))
+	assert.NoError(t, m.Check(context.Background()))
+
+	// the migrated schema accepts tasks written by the current model
+	require.NoError(t, db.Create(&task.Task{Title: "Makima"}).Error)
+	var created task.Task
+	require.NoError(t, db.First(&created).Error)
+	assert.Equal(t, task.StatusTodo, created.Status)
+	assert.Equal(t, uint64(1), created.Version)
+}
+
+func TestDownAndTo(t *testing.T) {
+	db := openDB(t)
+	m := NewMigrator(db, All)
+	ctx := context.Background()
+	require.NoError(t, m.Up(ctx))
+
+	require.NoError(t, m.Down(ctx))
+	assert.Len(t, appliedVersions(t, m), len(All)-1)
+	assert.ErrorIs(t, m.Check(ctx), ErrSchemaBehind)
+
+	require.NoError(t, m.To(ctx, 3))
+	assert.ElementsMatch(t, []uint64{1, 2, 3}, appliedVersions(t, m))
+	assert.False(t, db.Migrator().HasColumn(&taskV4{}, "version"))
+	assert.False(t, db.Migrator().HasTable("user_account"))
+	assert.False(t, db.Migrator().HasTable("api_key"))
+	assert.False(t, db.Migrator().HasTable("project_member"))
+	assert.False(t, db.Migrator().HasTable("task_tag"))
+
+	require.NoError(t, m.To(ctx, 1))
+	assert.Equal(t, []uint64{1}, appliedVersions(t, m))
+	assert.False(t, db.Migrator().HasColumn(&taskV2{}, "status"))
+
+	require.NoError(t, m.To(ctx, 3))
+	assert.ElementsMatch(t, []uint64{1, 2, 3}, appliedVersions(t, m))
+	assert.True(t, db.Migrator().HasColumn(&taskV3{}, "due_at"))
+
+	require.NoError(t, m.To(ctx, 0))
+	assert.Empty(t, appliedVersions(t, m))
+	assert.False(t, db.Migrator().HasTable("task"))
+
+	assert.ErrorIs(t, m.To(ctx, 42), ErrUnknownVersion)
+}
+
+func TestUpAdoptsAutoMigratedDatabase(t *testing.T) {
+	db := openDB(t)
+	// the table as AutoMigrate left it before status and due dates existed
+	require.NoError(t, db.AutoMigrate(&taskV1{}))
+	require.NoError(t, db.Create(&taskV1{Title: "legacy"}).Error)
+	m := NewMigrator(db, All)
+
+	require.NoError(t, m.Up(context.Background()))
+
+	var legacy task.Task
+	require.NoError(t, db.First(&legacy).Error)
+	assert.Equal(t, "legacy", legacy.Title)
+	assert.Equal(t, task.StatusTodo, legacy.Status)
+	assert.Equal(t, uint64(1), legacy.Version)
+}
+
+func TestStatus(t *testing.T) {
+	db := openDB(t)
+	m := NewMigrator(db, All)
+	ctx := context.Background()
+	require.NoError(t, m.To(ctx, 2))
+
+	statuses, err := m.Status(ctx)
+
+	require.NoError(t, err)
+	require.Len(t, statuses, len(All))
+	assert.NotNil(t, statuses[0].AppliedAt)
+	assert.NotNil(t, statuses[1].AppliedAt)
+	assert.Nil(t, statuses[2].AppliedAt)
+	assert.Equal(t, "add_task_schedule", statuses[2].Name)
+}
+
+func TestFailedMigrationIsRolledBack(t *testing.T) {
+	db := openDB(t)
+	broken := Migration{Version: 5, Name: "broken", Up: func(tx *gorm.DB) error {
+		if err := tx.Exec("CREATE TABLE half_done (id integer)").Error; err != nil {
+			return err
+		}
+		return tx.Exec("THIS IS NOT SQL").Error
+	}}
+	m := NewMigrator(db, append(All[:4:4], broken))
+
+	err := m.Up(context.Background())
+
+	assert.ErrorContains(t, err, "broken")
+	assert.ElementsMatch(t, []uint64{1, 2, 3, 4}, appliedVersions(t, m))
+	assert.False(t, db.Migrator().HasTable("half_done"))
+}
+
+func TestNewMigratorRejectsDuplicateVersions(t *testing.T) {
+	assert.Panics(t, func() {
+		NewMigrator(nil, []Migration{{Version: 1}, {Version: 1}})
+	})
+}
@@ -0,0 +1,278 @@
+package migration
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// All is the schema history of the task store, oldest first. Append to it; never edit a released
+// migration.
+var All = []Migration{
+	{Version: 1, Name: "create_task", Up: createTask, Down: dropTask},
+	{Version: 2, Name: "add_task_status", Up: addTaskStatus, Down: dropTaskStatus},
+	{Version: 3, Name: "add_task_schedule", Up: addTaskSchedule, Down: dropTaskSchedule},
+	{Version: 4, Name: "add_task_version", Up: addTaskVersion, Down: dropTaskVersion},
+	{Version: 5, Name: "create_user_account", Up: createUserAccount, Down: dropUserAccount},
+	{Version: 6, Name: "add_task_owner", Up: addTaskOwner, Down: dropTaskOwner},
+	{Version: 7, Name: "create_api_key", Up: createAPIKey, Down: dropAPIKey},
+	{Version: 8, Name: "add_user_external_identity", Up: addUserExternalIdentity, Down: dropUserExternalIdentity},
+	{Version: 9, Name: "create_project", Up: createProject, Down: dropProject},
+	{Version: 10, Name: "add_task_project", Up: addTaskProject, Down: dropTaskProject},
+	{Version: 11, Name: "create_tag", Up: createTag, Down: dropTag},
+}
+
+// The task table as each migration leaves it. Databases created by AutoMigrate before migrations
+// existed already have some of these columns, so every step only adds what is missing.
+
+type taskV1 struct {
+	ID          uint64         `gorm:"primaryKey"`
+	Title       string         `gorm:"not null"`
+	Description string         `gorm:"not null"`
+	CreatedAt   time.Time      `gorm:"not null"`
+	UpdatedAt   time.Time      `gorm:"not null"`
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+}
+
+func (taskV1) TableName() string { return "task" }
+
+type taskV2 struct {
+	taskV1
+	Status      string `gorm:"not null;default:todo;index"`
+	CompletedAt *time.Time
+}
+
+func (taskV2) TableName() string { return "task" }
+
+type taskV3 struct {
+	taskV2
+	StartAt *time.Time
+	DueAt   *time.Time `gorm:"index"`
+}
+
+func (taskV3) TableName() string { return "task" }
+
+type taskV4 struct {
+	taskV3
+	Version uint64 `gorm:"not null;default:1"`
+}
+
+func (taskV4) TableName() string { return "task" }
+
+// taskV5 gives every task an owner. Tasks created before accounts existed get owner 0, which no
+// account has, until the assign-tasks command hands them to someone.
+type taskV5 struct {
+	taskV4
+	OwnerID uint64 `gorm:"not null;default:0;index"`
+}
+
+func (taskV5) TableName() string { return "task" }
+
+// taskV6 lets members of a project share a task. Project 0 means the task is its owner's alone.
+type taskV6 struct {
+	taskV5
+	ProjectID uint64 `gorm:"not null;default:0;index"`
+}
+
+func (taskV6) TableName() string { return "task" }
+
+type userAccountV1 struct {
+	ID           uint64    `gorm:"primaryKey"`
+	Email        string    `gorm:"not null;uniqueIndex"`
+	Name         string    `gorm:"not null"`
+	PasswordHash string    `gorm:"not null;default:''"`
+	CreatedAt    time.Time `gorm:"not null"`
+	UpdatedAt    time.Time `gorm:"not null"`
+}
+
+func (userAccountV1) TableName() string { return "user_account" }
+
+// userAccountV2 links accounts to an OpenID Connect provider. Both columns are null for accounts
+// that only sign in with a password, so the unique index does not hold them against each other.
+type userAccountV2 struct {
+	userAccountV1
+	ExternalIssuer  *string `gorm:"uniqueIndex:idx_user_account_external"`
+	ExternalSubject *string `gorm:"uniqueIndex:idx_user_account_external"`
+}
+
+func (userAccountV2) TableName() string { return "user_account" }
+
+type apiKeyV1 struct {
+	ID         uint64 `gorm:"primaryKey"`
+	UserID     uint64 `gorm:"not null;index"`
+	Label      string `gorm:"not null"`
+	Prefix     string `gorm:"not null;uniqueIndex"`
+	Hash       string `gorm:"not null"`
+	Scope      string `gorm:"not null"`
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time `gorm:"not null"`
+}
+
+func (apiKeyV1) TableName() string { return "api_key" }
+
+type projectV1 struct {
+	ID        uint64    `gorm:"primaryKey"`
+	Name      string    `gorm:"not null"`
+	CreatedAt time.Time `gorm:"not null"`
+	UpdatedAt time.Time `gorm:"not null"`
+}
+
+func (projectV1) TableName() string { return "project" }
+
+type projectMemberV1 struct {
+	ProjectID uint64    `gorm:"primaryKey"`
+	UserID    uint64    `gorm:"primaryKey;index"`
+	Role      string    `gorm:"not null"`
+	CreatedAt time.Time `gorm:"not null"`
+}
+
+func (projectMemberV1) TableName() string { return "project_member" }
+
+// tagV1 names are unique per scope: a project, or the owner of tasks outside any project.
+type tagV1 struct {
+	ID        uint64    `gorm:"primaryKey"`
+	Name      string    `gorm:"not null;uniqueIndex:idx_tag_scope"`
+	ProjectID uint64    `gorm:"not null;default:0;uniqueIndex:idx_tag_scope"`
+	OwnerID   uint64    `gorm:"not null;default:0;uniqueIndex:idx_tag_scope"`
+	CreatedAt time.Time `gorm:"not null"`
+}
+
+func (tagV1) TableName() string { return "tag" }
+
+type taskTagV1 struct {
+	TaskID uint64 `gorm:"primaryKey"`
+	TagID  uint64 `gorm:"primaryKey;index"`
+}
+
+func (taskTagV1) TableName() string { return "task_tag" }
+
+func createTask(tx *gorm.DB) error {
+	if tx.Migrator().HasTable(&taskV1{}) {
+		return nil
+	}
+	return tx.Migrator().CreateTable(&taskV1{})
+}
+
+func dropTask(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&taskV1{})
+}
+
+func addTaskStatus(tx *gorm.DB) error {
+	return addColumns(tx, &taskV2{}, []string{"Status", "CompletedAt"}, []string{"Status"})
+}
+
+func dropTaskStatus(tx *gorm.DB) error {
+	return dropColumns(tx, &taskV2{}, []string{"Status", "CompletedAt"}, []string{"Status"})
+}
+
+func addTaskSchedule(tx *gorm.DB) error {
+	return addColumns(tx, &taskV3{}, []string{"StartAt", "DueAt"}, []string{"DueAt"})
+}
+
+func dropTaskSchedule(tx *gorm.DB) error {
+	return dropColumns(tx, &taskV3{}, []string{"StartAt", "DueAt"}, []string{"DueAt"})
+}
+
+func addTaskVersion(tx *gorm.DB) error {
+	return addColumns(tx, &taskV4{}, []string{"Version"}, nil)
+}
+
+func dropTaskVersion(tx *gorm.DB) error {
+	return dropColumns(tx, &taskV4{}, []string{"Version"}, nil)
+}
+
+func createUserAccount(tx *gorm.DB) error {
+	return tx.Migrator().CreateTable(&userAccountV1{})
+}
+
+func dropUserAccount(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&userAccountV1{})
+}
+
+func addTaskOwner(tx *gorm.DB) error {
+	return addColumns(tx, &taskV5{}, []string{"OwnerID"}, []string{"OwnerID"})
+}
+
+func dropTaskOwner(tx *gorm.DB) error {
+	return dropColumns(tx, &taskV5{}, []string{"OwnerID"}, []string{"OwnerID"})
+}
+
+func createAPIKey(tx *gorm.DB) error {
+	return tx.Migrator().CreateTable(&apiKeyV1{})
+}
+
+func dropAPIKey(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&apiKeyV1{})
+}
+
+func addUserExternalIdentity(tx *gorm.DB) error {
+	return addColumns(tx, &userAccountV2{}, []string{"ExternalIssuer", "ExternalSubject"}, []string{"idx_user_account_external"})
+}
+
+func dropUserExternalIdentity(tx *gorm.DB) error {
+	return dropColumns(tx, &userAccountV2{}, []string{"ExternalIssuer", "ExternalSubject"}, []string{"idx_user_account_external"})
+}
+
+func createProject(tx *gorm.DB) error {
+	return tx.Migrator().CreateTable(&projectV1{}, &projectMemberV1{})
+}
+
+func dropProject(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&projectMemberV1{}, &projectV1{})
+}
+
+func addTaskProject(tx *gorm.DB) error {
+	return addColumns(tx, &taskV6{}, []string{"ProjectID"}, []string{"ProjectID"})
+}
+
+func dropTaskProject(tx *gorm.DB) error {
+	return dropColumns(tx, &taskV6{}, []string{"ProjectID"}, []string{"ProjectID"})
+}
+
+func createTag(tx *gorm.DB) error {
+	return tx.Migrator().CreateTable(&tagV1{}, &taskTagV1{})
+}
+
+func dropTag(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&taskTagV1{}, &tagV1{})
+}
+
+// addColumns adds the named fields of model and their indexes, skipping those that already exist.
+func addColumns(tx *gorm.DB, model any, fields, indexed []string) error {
+	m := tx.Migrator()
+	for _, field := range fields {
+		if !m.HasColumn(model, field) {
+			if err := m.AddColumn(model, field); err != nil {
+				return err
+			}
+		}
+	}
+	for _, field := range indexed {
+		if !m.HasIndex(model, field) {
+			if err := m.CreateIndex(model, field); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func dropColumns(tx *gorm.DB, model any, fields, indexed []string) error {
+	m := tx.Migrator()
+	for _, field := range indexed {
+		if m.HasIndex(model, field) {
+			if err := m.DropIndex(model, field); err != nil {
+				return err
+			}
+		}
+	}
+	for _, field := range fields {
+		if m.HasColumn(model, field) {
+			if err := m.DropColumn(model, field); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
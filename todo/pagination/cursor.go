@@ -0,0 +1,32 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+)
+
+// Cursor marks the last row of a page in cursor mode. Key is that row's value of the sort field,
+// in whatever string form the query builder can turn back into a column value, and ID breaks ties
+// between rows sharing the key. Clients only ever see it encoded.
+type Cursor struct {
+	Sort SortField `json:"s"`
+	Key  string    `json:"k"`
+	ID   uint64    `json:"i"`
+}
+
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor(value string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, &ParameterError{Parameter: "cursor", Message: "is not a valid cursor"}
+	}
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Sort.Field == "" {
+		return nil, &ParameterError{Parameter: "cursor", Message: "is not a valid cursor"}
+	}
+	return &cursor, nil
+}
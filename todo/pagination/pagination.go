@@ -1,46 +1,190 @@
 package pagination
 
 import (
+	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 )
 
+const (
+	OrderAsc  = "asc"
+	OrderDesc = "desc"
+)
+
+// Page size limits. Set from the configuration at startup, before serving requests.
+var (
+	DefaultPageSize = 10
+	MaxPageSize     = 100
+)
+
+// SortField is one key of a possibly multi-key sort. Field is the name clients see in responses,
+// not a column; translating it is up to whoever builds the query.
+type SortField struct {
+	Field string `json:"field"`
+	Desc  bool   `json:"desc"`
+}
+
 type PaginationRequest struct {
-	PageSize int    `json:"pageSize"`
-	Page     int    `json:"page"`
-	SortBy   string `json:"sortBy"`
-	Order    string `json:"order"`
+	PageSize int         `json:"pageSize"`
+	Page     int         `json:"page"`
+	Sort     []SortField `json:"sort"`
+
+	// CursorMode pages by seeking past After instead of by offset. After is nil on the first page.
+	CursorMode bool    `json:"cursorMode"`
+	After      *Cursor `json:"after,omitempty"`
+}
+
+// ParameterError reports a query parameter that could not be understood.
+type ParameterError struct {
+	Parameter string
+	Message   string
+}
+
+func (e *ParameterError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Parameter, e.Message)
 }
 
 func (r PaginationRequest) GetOffset() int {
 	return (r.Page - 1) * r.PageSize
 }
 
-func NewPaginationRequest(r *http.Request) *PaginationRequest {
-	pageSize, err := strconv.Atoi(r.URL.Query().Get("pageSize"))
-	if pageSize == 0 || err != nil {
-		pageSize = 10
+// NewPaginationRequest reads page, pageSize and the sort order from the query string. The order is
+// given either as sort=-dueAt,title (a leading "-" means descending) or as the single-key
+// sortBy=dueAt&order=desc; every field must be one of sortable. Without either it falls back to
+// defaultSort.
+//
+// A cursor parameter, empty for the first page, switches to cursor mode. A cursor remembers the
+// sort it was issued for, so later pages need not repeat it, and cursor mode sorts by one field.
+func NewPaginationRequest(r *http.Request, sortable []string, defaultSort ...SortField) (*PaginationRequest, error) {
+	query := r.URL.Query()
+	if query.Has("cursor") {
+		return newCursorRequest(r, sortable, defaultSort)
 	}
 
-	page, err := strconv.Atoi(r.URL.Query().Get("page"))
-	if page == 0 || err != nil {
+	pageSize := parsePageSize(query.Get("pageSize"))
+
+	page, err := strconv.Atoi(query.Get("page"))
+	if page <= 0 || err != nil {
 		page = 1
 	}
 
-	sortBy := r.URL.Query().Get("page")
-	if sortBy == "" {
-		sortBy = "updated_at"
+	sort, err := parseSort(query.Get("sort"), query.Get("sortBy"), query.Get("order"), sortable)
+	if err != nil {
+		return nil, err
 	}
-
-	order := r.URL.Query().Get("order")
-	if order == "" {
-		order = "desc"
+	if len(sort) == 0 {
+		sort = defaultSort
 	}
 
 	return &PaginationRequest{
 		PageSize: pageSize,
 		Page:     page,
-		SortBy:   sortBy,
-		Order:    order,
+		Sort:     sort,
+	}, nil
+}
+
+func newCursorRequest(r *http.Request, sortable []string, defaultSort []SortField) (*PaginationRequest, error) {
+	query := r.URL.Query()
+	if query.Has("page") {
+		return nil, &ParameterError{Parameter: "page", Message: "cannot be combined with cursor"}
+	}
+	pageSize := parsePageSize(query.Get("pageSize"))
+
+	sort, err := parseSort(query.Get("sort"), query.Get("sortBy"), query.Get("order"), sortable)
+	if err != nil {
+		return nil, err
+	}
+	var after *Cursor
+	if value := query.Get("cursor"); value != "" {
+		if after, err = DecodeCursor(value); err != nil {
+			return nil, err
+		}
+		if !slices.Contains(sortable, after.Sort.Field) {
+			return nil, &ParameterError{Parameter: "cursor", Message: "is not a valid cursor"}
+		}
+		if len(sort) > 0 && !slices.Equal(sort, []SortField{after.Sort}) {
+			return nil, &ParameterError{Parameter: "cursor", Message: "was issued for a different sort"}
+		}
+		sort = []SortField{after.Sort}
+	}
+	if len(sort) == 0 {
+		sort = defaultSort
+	}
+	if len(sort) != 1 {
+		return nil, &ParameterError{Parameter: "sort", Message: "must name a single field in cursor mode"}
+	}
+
+	return &PaginationRequest{
+		PageSize:   pageSize,
+		Sort:       sort,
+		CursorMode: true,
+		After:      after,
+	}, nil
+}
+
+// parsePageSize falls back to the default for a missing or malformed size and caps it at the maximum.
+func parsePageSize(value string) int {
+	pageSize, err := strconv.Atoi(value)
+	if pageSize <= 0 || err != nil {
+		return DefaultPageSize
+	}
+	return min(pageSize, MaxPageSize)
+}
+
+func parseSort(sort, sortBy, order string, sortable []string) ([]SortField, error) {
+	if sort != "" && (sortBy != "" || order != "") {
+		return nil, &ParameterError{Parameter: "sort", Message: "cannot be combined with sortBy or order"}
+	}
+	if sort == "" && sortBy == "" {
+		if order != "" {
+			return nil, &ParameterError{Parameter: "order", Message: "requires sortBy"}
+		}
+		return nil, nil
+	}
+
+	if sort == "" {
+		field := SortField{Field: sortBy}
+		switch strings.ToLower(order) {
+		case "", OrderAsc:
+		case OrderDesc:
+			field.Desc = true
+		default:
+			return nil, &ParameterError{Parameter: "order", Message: "must be one of asc, desc"}
+		}
+		if !slices.Contains(sortable, field.Field) {
+			return nil, unknownSortField("sortBy", field.Field, sortable)
+		}
+		return []SortField{field}, nil
+	}
+
+	var fields []SortField
+	seen := make(map[string]bool)
+	for _, key := range strings.Split(sort, ",") {
+		key = strings.TrimSpace(key)
+		field := SortField{Field: strings.TrimPrefix(key, "+")}
+		if strings.HasPrefix(key, "-") {
+			field = SortField{Field: key[1:], Desc: true}
+		}
+		if field.Field == "" {
+			return nil, &ParameterError{Parameter: "sort", Message: "must not contain empty fields"}
+		}
+		if !slices.Contains(sortable, field.Field) {
+			return nil, unknownSortField("sort", field.Field, sortable)
+		}
+		if seen[field.Field] {
+			return nil, &ParameterError{Parameter: "sort", Message: fmt.Sprintf("field %q is listed more than once", field.Field)}
+		}
+		seen[field.Field] = true
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+func unknownSortField(parameter, field string, sortable []string) error {
+	return &ParameterError{
+		Parameter: parameter,
+		Message:   fmt.Sprintf("unknown field %q, must be one of %s", field, strings.Join(sortable, ", ")),
 	}
 }
@@ -0,0 +1,167 @@
+package pagination
+
+import (
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var sortable = []string{"dueAt", "id", "title", "updatedAt"}
+
+/* Unit test for NewPaginationRequest */
+
+func TestNewPaginationRequestDefaults(t *testing.T) {
+	r := httptest.NewRequest("GET", "/tasks", nil)
+	request, err := NewPaginationRequest(r, sortable, SortField{Field: "updatedAt", Desc: true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, request.Page)
+	assert.Equal(t, 10, request.PageSize)
+	assert.Equal(t, []SortField{{Field: "updatedAt", Desc: true}}, request.Sort)
+}
+
+func TestNewPaginationRequestSortByAndOrder(t *testing.T) {
+	r := httptest.NewRequest("GET", "/tasks?page=2&pageSize=5&sortBy=title&order=desc", nil)
+	request, err := NewPaginationRequest(r, sortable)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, request.Page)
+	assert.Equal(t, 5, request.PageSize)
+	assert.Equal(t, []SortField{{Field: "title", Desc: true}}, request.Sort)
+}
+
+func TestNewPaginationRequestMultiKeySort(t *testing.T) {
+	r := httptest.NewRequest("GET", "/tasks?sort=-dueAt,title", nil)
+	request, err := NewPaginationRequest(r, sortable)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []SortField{{Field: "dueAt", Desc: true}, {Field: "title"}}, request.Sort)
+}
+
+func TestNewPaginationRequestWhenSortInvalid(t *testing.T) {
+	for query, parameter := range map[string]string{
+		"?sort=password":             "sort",
+		"?sort=title,,id":            "sort",
+		"?sort=title,-title":         "sort",
+		"?sort=title&order=asc":      "sort",
+		"?sortBy=title%3Bdrop+table": "sortBy",
+		"?sortBy=title&order=up":     "order",
+		"?order=asc":                 "order",
+	} {
+		r := httptest.NewRequest("GET", "/tasks"+query, nil)
+		_, err := NewPaginationRequest(r, sortable)
+
+		var paramErr *ParameterError
+		assert.True(t, errors.As(err, &paramErr), query)
+		assert.Equal(t, parameter, paramErr.Parameter, query)
+	}
+}
+
+/* Unit test for NewPaginationResponse */
+
+func TestNewPaginationResponse(t *testing.T) {
+	response := NewPaginationResponse(PaginationRequest{Page: 3, PageSize: 10}, []int{1, 2}, 22)
+
+	assert.Equal(t, 3, response.TotalPages)
+	assert.False(t, response.HasNext)
+	assert.Equal(t, int64(22), response.TotalItems)
+}
+
+func TestNewPaginationResponseWhenEmpty(t *testing.T) {
+	response := NewPaginationResponse[int](PaginationRequest{Page: 1, PageSize: 10}, nil, 0)
+
+	assert.Equal(t, []int{}, response.Items)
+	assert.Equal(t, 0, response.TotalPages)
+	assert.False(t, response.HasNext)
+
+	u, _ := url.Parse("/tasks")
+	assert.Equal(t, `</tasks?page=1&pageSize=10>; rel="first", </tasks?page=1&pageSize=10>; rel="last"`, response.Link(u))
+}
+
+func TestPaginationResponseLinkPastLastPage(t *testing.T) {
+	response := NewPaginationResponse[int](PaginationRequest{Page: 9, PageSize: 10}, nil, 15)
+
+	u, _ := url.Parse("/tasks?sort=-dueAt&page=9")
+	assert.Equal(t, `</tasks?page=1&pageSize=10&sort=-dueAt>; rel="first", `+
+		`</tasks?page=2&pageSize=10&sort=-dueAt>; rel="prev", `+
+		`</tasks?page=2&pageSize=10&sort=-dueAt>; rel="last"`, response.Link(u))
+}
+
+/* Unit test for cursor mode */
+
+func TestCursorRoundTrip(t *testing.T) {
+	cursor := Cursor{Sort: SortField{Field: "updatedAt", Desc: true}, Key: "2024-05-15T13:30:00Z", ID: 42}
+
+	decoded, err := DecodeCursor(cursor.Encode())
+
+	assert.NoError(t, err)
+	assert.Equal(t, cursor, *decoded)
+}
+
+func TestNewPaginationRequestCursorMode(t *testing.T) {
+	r := httptest.NewRequest("GET", "/tasks?cursor=&pageSize=5", nil)
+	request, err := NewPaginationRequest(r, sortable, SortField{Field: "updatedAt", Desc: true})
+
+	assert.NoError(t, err)
+	assert.True(t, request.CursorMode)
+	assert.Nil(t, request.After)
+	assert.Equal(t, 5, request.PageSize)
+	assert.Equal(t, []SortField{{Field: "updatedAt", Desc: true}}, request.Sort)
+
+	// later pages take the sort from the cursor
+	cursor := Cursor{Sort: SortField{Field: "title"}, Key: "Makima", ID: 7}
+	r = httptest.NewRequest("GET", "/tasks?cursor="+cursor.Encode(), nil)
+	request, err = NewPaginationRequest(r, sortable, SortField{Field: "updatedAt", Desc: true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &cursor, request.After)
+	assert.Equal(t, []SortField{{Field: "title"}}, request.Sort)
+}
+
+func TestNewPaginationRequestWhenCursorInvalid(t *testing.T) {
+	titleCursor := Cursor{Sort: SortField{Field: "title"}, Key: "Makima", ID: 7}.Encode()
+	foreignCursor := Cursor{Sort: SortField{Field: "password"}, Key: "x", ID: 7}.Encode()
+	for query, parameter := range map[string]string{
+		"?cursor=not-a-cursor":                  "cursor",
+		"?cursor=" + foreignCursor:              "cursor",
+		"?cursor=" + titleCursor + "&sort=-id":  "cursor",
+		"?cursor=&page=2":                       "page",
+		"?cursor=&sort=title,id":                "sort",
+		"?cursor=" + titleCursor + "&sort=titl": "sort",
+	} {
+		r := httptest.NewRequest("GET", "/tasks"+query, nil)
+		_, err := NewPaginationRequest(r, sortable)
+
+		var paramErr *ParameterError
+		assert.True(t, errors.As(err, &paramErr), query)
+		assert.Equal(t, parameter, paramErr.Parameter, query)
+	}
+}
+
+func TestCursorResponseLink(t *testing.T) {
+	request := PaginationRequest{PageSize: 2, Sort: []SortField{{Field: "title"}}, CursorMode: true}
+	next := Cursor{Sort: SortField{Field: "title"}, Key: "b", ID: 2}
+	response := NewCursorResponse(request, []string{"a", "b"}, 5, true, next)
+
+	assert.Equal(t, next.Encode(), response.NextCursor)
+	u, _ := url.Parse("/tasks?cursor=&sortBy=title&due=week")
+	assert.Equal(t, `</tasks?cursor=&due=week&pageSize=2&sort=title>; rel="first", `+
+		`</tasks?cursor=`+next.Encode()+`&due=week&pageSize=2>; rel="next"`, response.Link(u))
+
+	response = NewCursorResponse(request, []string{"e"}, 5, false, Cursor{})
+	assert.Empty(t, response.NextCursor)
+	assert.False(t, response.HasNext)
+}
+
+func TestNewPaginationRequestPageSizeLimits(t *testing.T) {
+	for query, pageSize := range map[string]int{"": DefaultPageSize, "?pageSize=-3": DefaultPageSize, "?pageSize=5000": MaxPageSize, "?pageSize=42": 42} {
+		r := httptest.NewRequest("GET", "/tasks"+query, nil)
+		request, err := NewPaginationRequest(r, sortable)
+
+		assert.NoError(t, err)
+		assert.Equal(t, pageSize, request.PageSize, query)
+	}
+}
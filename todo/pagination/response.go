@@ -0,0 +1,110 @@
+package pagination
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+type PaginationResponse[T any] struct {
+	Items      []T    `json:"items"`
+	Page       int    `json:"page"`
+	PageSize   int    `json:"pageSize"`
+	TotalItems int64  `json:"totalItems"`
+	TotalPages int    `json:"totalPages"`
+	HasNext    bool   `json:"hasNext"`
+	NextCursor string `json:"nextCursor,omitempty"` // cursor mode only
+
+	cursorMode bool
+	sort       []SortField
+}
+
+func NewPaginationResponse[T any](request PaginationRequest, items []T, totalItems int64) *PaginationResponse[T] {
+	if items == nil {
+		items = []T{}
+	}
+	totalPages := 0
+	if request.PageSize > 0 {
+		totalPages = int((totalItems + int64(request.PageSize) - 1) / int64(request.PageSize))
+	}
+	return &PaginationResponse[T]{
+		Items:      items,
+		Page:       request.Page,
+		PageSize:   request.PageSize,
+		TotalItems: totalItems,
+		TotalPages: totalPages,
+		HasNext:    request.Page < totalPages,
+		cursorMode: request.CursorMode,
+		sort:       request.Sort,
+	}
+}
+
+// NewCursorResponse builds the envelope for a page read in cursor mode. next is the cursor of the
+// page's last item and is dropped when there is nothing after it.
+func NewCursorResponse[T any](request PaginationRequest, items []T, totalItems int64, hasNext bool, next Cursor) *PaginationResponse[T] {
+	response := NewPaginationResponse(request, items, totalItems)
+	response.HasNext = hasNext
+	if hasNext {
+		response.NextCursor = next.Encode()
+	}
+	return response
+}
+
+// Link renders the RFC 8288 Link header for the page, keeping every other query parameter of the
+// request URL. prev and next are left out at either end; an empty result still links to page 1 as
+// both first and last.
+func (p *PaginationResponse[T]) Link(requestURL *url.URL) string {
+	if p.cursorMode {
+		return p.cursorLink(requestURL)
+	}
+	last := max(p.TotalPages, 1)
+	links := []string{p.link(requestURL, 1, "first")}
+	if p.Page > 1 {
+		links = append(links, p.link(requestURL, min(p.Page-1, last), "prev"))
+	}
+	if p.HasNext {
+		links = append(links, p.link(requestURL, p.Page+1, "next"))
+	}
+	links = append(links, p.link(requestURL, last, "last"))
+	return strings.Join(links, ", ")
+}
+
+func (p *PaginationResponse[T]) link(requestURL *url.URL, page int, rel string) string {
+	query := requestURL.Query()
+	query.Set("page", strconv.Itoa(page))
+	query.Set("pageSize", strconv.Itoa(p.PageSize))
+	target := url.URL{Path: requestURL.Path, RawQuery: query.Encode()}
+	return fmt.Sprintf("<%s>; rel=\"%s\"", target.String(), rel)
+}
+
+// cursorLink can only point forwards: cursor mode knows the first page and the next one.
+func (p *PaginationResponse[T]) cursorLink(requestURL *url.URL) string {
+	query := requestURL.Query()
+	query.Del("sortBy")
+	query.Del("order")
+	query.Set("pageSize", strconv.Itoa(p.PageSize))
+	query.Set("sort", formatSort(p.sort))
+	query.Set("cursor", "")
+	first := url.URL{Path: requestURL.Path, RawQuery: query.Encode()}
+	links := []string{fmt.Sprintf("<%s>; rel=\"first\"", first.String())}
+	if p.HasNext {
+		// the cursor carries the sort, so the next link doesn't repeat it
+		query.Del("sort")
+		query.Set("cursor", p.NextCursor)
+		next := url.URL{Path: requestURL.Path, RawQuery: query.Encode()}
+		links = append(links, fmt.Sprintf("<%s>; rel=\"next\"", next.String()))
+	}
+	return strings.Join(links, ", ")
+}
+
+func formatSort(sort []SortField) string {
+	keys := make([]string, len(sort))
+	for i, field := range sort {
+		keys[i] = field.Field
+		if field.Desc {
+			keys[i] = "-" + field.Field
+		}
+	}
+	return strings.Join(keys, ",")
+}
@@ -0,0 +1,92 @@
+package patch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const document = `{"id":1,"title":"Makima","description":"Makima super kawaii","dueAt":"2024-01-02T15:04:05Z","tags":["work","home"]}`
+
+func TestMergePatch(t *testing.T) {
+	p, err := NewMergePatch([]byte(`{"title":"Power","dueAt":null,"extra":{"nested":true}}`))
+	assert.NoError(t, err)
+
+	patched, err := p.Apply([]byte(document))
+
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"id":1,"title":"Power","description":"Makima super kawaii","tags":["work","home"],"extra":{"nested":true}}`, string(patched))
+}
+
+func TestMergePatchReplacesArrays(t *testing.T) {
+	p, err := NewMergePatch([]byte(`{"tags":["urgent"]}`))
+	assert.NoError(t, err)
+
+	patched, err := p.Apply([]byte(document))
+
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"id":1,"title":"Makima","description":"Makima super kawaii","dueAt":"2024-01-02T15:04:05Z","tags":["urgent"]}`, string(patched))
+}
+
+func TestNewMergePatchWhenNotObject(t *testing.T) {
+	for _, body := range []string{`["title"]`, `"title"`, `null`, `{"title":`} {
+		_, err := NewMergePatch([]byte(body))
+		assert.ErrorIs(t, err, ErrInvalidPatch, body)
+	}
+}
+
+func TestJSONPatch(t *testing.T) {
+	p, err := NewJSONPatch([]byte(`[
+		{"op":"test","path":"/title","value":"Makima"},
+		{"op":"replace","path":"/title","value":"Power"},
+		{"op":"remove","path":"/dueAt"},
+		{"op":"add","path":"/tags/-","value":"urgent"},
+		{"op":"add","path":"/tags/0","value":"first"},
+		{"op":"remove","path":"/tags/1"},
+		{"op":"copy","from":"/title","path":"/description"},
+		{"op":"move","from":"/description","path":"/a~1b"}
+	]`))
+	assert.NoError(t, err)
+
+	patched, err := p.Apply([]byte(document))
+
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"id":1,"title":"Power","a/b":"Power","tags":["first","home","urgent"]}`, string(patched))
+}
+
+func TestJSONPatchWhenTestFails(t *testing.T) {
+	p, err := NewJSONPatch([]byte(`[{"op":"test","path":"/title","value":"Power"},{"op":"replace","path":"/title","value":"Denji"}]`))
+	assert.NoError(t, err)
+
+	_, err = p.Apply([]byte(document))
+
+	assert.ErrorIs(t, err, ErrTestFailed)
+}
+
+func TestJSONPatchWhenPathMissing(t *testing.T) {
+	for _, body := range []string{
+		`[{"op":"replace","path":"/startAt","value":"2024-01-01T00:00:00Z"}]`,
+		`[{"op":"remove","path":"/tags/5"}]`,
+		`[{"op":"add","path":"/missing/child","value":1}]`,
+		`[{"op":"move","from":"/missing","path":"/title"}]`,
+	} {
+		p, err := NewJSONPatch([]byte(body))
+		assert.NoError(t, err, body)
+
+		_, err = p.Apply([]byte(document))
+		assert.ErrorIs(t, err, ErrInvalidPatch, body)
+	}
+}
+
+func TestNewJSONPatchWhenInvalid(t *testing.T) {
+	for _, body := range []string{
+		`{"op":"add"}`,
+		`[{"op":"upsert","path":"/title","value":1}]`,
+		`[{"op":"add","path":"/title"}]`,
+		`[{"op":"remove","path":"title"}]`,
+		`[{"op":"copy","from":"title","path":"/description"}]`,
+	} {
+		_, err := NewJSONPatch([]byte(body))
+		assert.ErrorIs(t, err, ErrInvalidPatch, body)
+	}
+}
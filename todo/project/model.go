@@ -0,0 +1,107 @@
+// Package project manages the shared projects tasks can belong to and who may use them.
+package project
+
+import (
+	"time"
+
+	"mkmgo-todo/todo/auth"
+)
+
+type Project struct {
+	ID        uint64 `gorm:"primaryKey"`
+	Name      string `gorm:"not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (Project) TableName() string {
+	return "project"
+}
+
+// Member gives a user a role in a project. A project always has at least one owner.
+type Member struct {
+	ProjectID uint64    `gorm:"primaryKey"`
+	UserID    uint64    `gorm:"primaryKey;index"`
+	Role      auth.Role `gorm:"not null"`
+	CreatedAt time.Time
+}
+
+func (Member) TableName() string {
+	return "project_member"
+}
+
+// MemberProject is a project together with the role of the member looking at it.
+type MemberProject struct {
+	Project
+	Role auth.Role
+}
+
+// TaskCounts counts the tasks of a project outside the trash. Cancelled tasks count as neither.
+type TaskCounts struct {
+	Open      int64 `json:"openTasks"`
+	Completed int64 `json:"completedTasks"`
+}
+
+// MemberAccount is a member together with the account it belongs to.
+type MemberAccount struct {
+	Member
+	Email string
+	Name  string
+}
+
+type CreateProjectRequest struct {
+	Name string `json:"name" validate:"required,trimmed,max=100,printable"`
+}
+
+type UpdateProjectRequest struct {
+	ID   uint64 `json:"-"`
+	Name string `json:"name" validate:"required,trimmed,max=100,printable"`
+}
+
+type AddMemberRequest struct {
+	ProjectID uint64    `json:"-"`
+	Email     string    `json:"email" validate:"required,trimmed,max=254,email"`
+	Role      auth.Role `json:"role" validate:"required,oneof=viewer editor owner"`
+}
+
+type UpdateMemberRequest struct {
+	ProjectID uint64    `json:"-"`
+	UserID    uint64    `json:"-"`
+	Role      auth.Role `json:"role" validate:"required,oneof=viewer editor owner"`
+}
+
+type ProjectResponse struct {
+	ID        uint64    `json:"id"`
+	Name      string    `json:"name"`
+	Role      auth.Role `json:"role"` // of the user asking
+	CreatedAt time.Time `json:"createdAt"`
+	TaskCounts
+}
+
+type MemberResponse struct {
+	UserID    uint64    `json:"userId"`
+	Email     string    `json:"email"`
+	Name      string    `json:"name"`
+	Role      auth.Role `json:"role"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (p Project) ToResponse(role auth.Role, counts TaskCounts) ProjectResponse {
+	return ProjectResponse{
+		ID:         p.ID,
+		Name:       p.Name,
+		Role:       role,
+		CreatedAt:  p.CreatedAt,
+		TaskCounts: counts,
+	}
+}
+
+func (m MemberAccount) ToResponse() MemberResponse {
+	return MemberResponse{
+		UserID:    m.UserID,
+		Email:     m.Email,
+		Name:      m.Name,
+		Role:      m.Role,
+		CreatedAt: m.CreatedAt,
+	}
+}
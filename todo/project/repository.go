@@ -0,0 +1,284 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"mkmgo-todo/todo/auth"
+	"mkmgo-todo/todo/task"
+
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ProjectRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+func NewProjectRepositoryImpl(db *gorm.DB) *ProjectRepositoryImpl {
+	return &ProjectRepositoryImpl{DB: db}
+}
+
+// SaveProject creates a project with ownerID as its first owner.
+func (r *ProjectRepositoryImpl) SaveProject(ctx context.Context, project *Project, ownerID uint64) error {
+	log := zerolog.Ctx(ctx).With().Str("method", "projectRepository.SaveProject").Logger()
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(project).Error; err != nil {
+			return err
+		}
+		return tx.Create(&Member{ProjectID: project.ID, UserID: ownerID, Role: auth.RoleOwner}).Error
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("failed to save project")
+		return fmt.Errorf("failed to save project: %w", err)
+	}
+	log.Info().Uint64("id", project.ID).Msg("success to save project")
+	return nil
+}
+
+// GetProjects returns every project the user is a member of, by name.
+func (r *ProjectRepositoryImpl) GetProjects(ctx context.Context, userID uint64) ([]MemberProject, error) {
+	log := zerolog.Ctx(ctx).With().Str("method", "projectRepository.GetProjects").Logger()
+	var projects []MemberProject
+	err := r.DB.WithContext(ctx).Table("project").
+		Select("project.*, project_member.role").
+		Joins("JOIN project_member ON project_member.project_id = project.id").
+		Where("project_member.user_id = ?", userID).
+		Order("project.name, project.id").
+		Find(&projects).Error
+	if err != nil {
+		log.Error().Err(err).Msg("failed to retrieve projects")
+		return nil, fmt.Errorf("failed to retrieve projects: %w", err)
+	}
+	log.Info().Int("count", len(projects)).Msg("success to retrieve projects")
+	return projects, nil
+}
+
+func (r *ProjectRepositoryImpl) GetProject(ctx context.Context, id uint64) (*Project, error) {
+	log := zerolog.Ctx(ctx).With().Str("method", "projectRepository.GetProject").Logger()
+	var project Project
+	if err := r.DB.WithContext(ctx).First(&project, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Info().Uint64("id", id).Msg("project not found")
+			return nil, fmt.Errorf("%w: id %d", ErrProjectNotFound, id)
+		}
+		log.Error().Err(err).Msg("failed to retrieve project")
+		return nil, fmt.Errorf("failed to retrieve project: %w", err)
+	}
+	log.Info().Msg("success to retrieve project")
+	return &project, nil
+}
+
+// UpdateProject renames a project.
+func (r *ProjectRepositoryImpl) UpdateProject(ctx context.Context, project *Project) error {
+	log := zerolog.Ctx(ctx).With().Str("method", "projectRepository.UpdateProject").Logger()
+	result := r.DB.WithContext(ctx).Model(project).Update("name", project.Name)
+	if result.Error != nil {
+		log.Error().Err(result.Error).Msg("failed to update project")
+		return fmt.Errorf("failed to update project: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		log.Info().Uint64("id", project.ID).Msg("project not found")
+		return fmt.Errorf("%w: id %d", ErrProjectNotFound, project.ID)
+	}
+	log.Info().Uint64("id", project.ID).Msg("success to update project")
+	return nil
+}
+
+// DeleteProject removes a project with its members and tags. Only an empty project can go: tasks
+// outside the trash must be moved or deleted first, while those in the trash are purged with it,
+// since nobody could reach them any more.
+func (r *ProjectRepositoryImpl) DeleteProject(ctx context.Context, id uint64) error {
+	log := zerolog.Ctx(ctx).With().Str("method", "projectRepository.DeleteProject").Logger()
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var tasks int64
+		if err := tx.Model(&task.Task{}).Where("project_id = ?", id).Count(&tasks).Error; err != nil {
+			return err
+		}
+		if tasks > 0 {
+			return fmt.Errorf("%w: %d task(s) left", ErrProjectNotEmpty, tasks)
+		}
+		if err := tx.Unscoped().Where("project_id = ?", id).Delete(&task.Task{}).Error; err != nil {
+			return err
+		}
+		projectTags := tx.Model(&task.Tag{}).Select("id").Where("project_id = ?", id)
+		if err := tx.Where("tag_id IN (?)", projectTags).Delete(&task.TaskTag{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("project_id = ?", id).Delete(&task.Tag{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("project_id = ?", id).Delete(&Member{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&Project{}, id)
+		if result.Error == nil && result.RowsAffected == 0 {
+			return fmt.Errorf("%w: id %d", ErrProjectNotFound, id)
+		}
+		return result.Error
+	})
+	if errors.Is(err, ErrProjectNotEmpty) || errors.Is(err, ErrProjectNotFound) {
+		log.Info().Err(err).Uint64("id", id).Msg("project not deleted")
+		return err
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("failed to delete project")
+		return fmt.Errorf("failed to delete project: %w", err)
+	}
+	log.Info().Uint64("id", id).Msg("success to delete project")
+	return nil
+}
+
+// CountTasks counts the open and completed tasks of each project. Projects without tasks are
+// left out.
+func (r *ProjectRepositoryImpl) CountTasks(ctx context.Context, projectIDs []uint64) (map[uint64]TaskCounts, error) {
+	log := zerolog.Ctx(ctx).With().Str("method", "projectRepository.CountTasks").Logger()
+	var rows []struct {
+		ProjectID uint64
+		TaskCounts
+	}
+	err := r.DB.WithContext(ctx).Model(&task.Task{}).
+		Select("project_id, SUM(CASE WHEN status IN ? THEN 0 ELSE 1 END) AS open, SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS completed",
+			[]task.Status{task.StatusDone, task.StatusCancelled}, task.StatusDone).
+		Where("project_id IN ?", projectIDs).
+		Group("project_id").
+		Scan(&rows).Error
+	if err != nil {
+		log.Error().Err(err).Msg("failed to count tasks")
+		return nil, fmt.Errorf("failed to count tasks: %w", err)
+	}
+	counts := make(map[uint64]TaskCounts, len(rows))
+	for _, row := range rows {
+		counts[row.ProjectID] = row.TaskCounts
+	}
+	log.Debug().Msg("success to count tasks")
+	return counts, nil
+}
+
+func (r *ProjectRepositoryImpl) GetMember(ctx context.Context, projectID, userID uint64) (*MemberAccount, error) {
+	log := zerolog.Ctx(ctx).With().Str("method", "projectRepository.GetMember").Logger()
+	var member MemberAccount
+	err := r.memberAccounts(ctx).
+		Where("project_member.project_id = ? AND project_member.user_id = ?", projectID, userID).
+		Take(&member).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Info().Uint64("projectId", projectID).Uint64("userId", userID).Msg("member not found")
+			return nil, fmt.Errorf("%w: user %d", ErrMemberNotFound, userID)
+		}
+		log.Error().Err(err).Msg("failed to retrieve member")
+		return nil, fmt.Errorf("failed to retrieve member: %w", err)
+	}
+	log.Debug().Msg("success to retrieve member")
+	return &member, nil
+}
+
+// GetMembers returns every member of the project, longest-standing first.
+func (r *ProjectRepositoryImpl) GetMembers(ctx context.Context, projectID uint64) ([]MemberAccount, error) {
+	log := zerolog.Ctx(ctx).With().Str("method", "projectRepository.GetMembers").Logger()
+	var members []MemberAccount
+	err := r.memberAccounts(ctx).
+		Where("project_member.project_id = ?", projectID).
+		Order("project_member.created_at, project_member.user_id").
+		Find(&members).Error
+	if err != nil {
+		log.Error().Err(err).Msg("failed to retrieve members")
+		return nil, fmt.Errorf("failed to retrieve members: %w", err)
+	}
+	log.Info().Int("count", len(members)).Msg("success to retrieve members")
+	return members, nil
+}
+
+func (r *ProjectRepositoryImpl) SaveMember(ctx context.Context, member *Member) error {
+	log := zerolog.Ctx(ctx).With().Str("method", "projectRepository.SaveMember").Logger()
+	if err := r.DB.WithContext(ctx).Create(member).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			log.Info().Err(err).Msg("user is already a member")
+			return ErrMemberExists
+		}
+		log.Error().Err(err).Msg("failed to save member")
+		return fmt.Errorf("failed to save member: %w", err)
+	}
+	log.Info().Uint64("projectId", member.ProjectID).Uint64("userId", member.UserID).Msg("success to save member")
+	return nil
+}
+
+// UpdateMember changes the role of a member. The project's last owner cannot step down.
+func (r *ProjectRepositoryImpl) UpdateMember(ctx context.Context, member *Member) error {
+	log := zerolog.Ctx(ctx).With().Str("method", "projectRepository.UpdateMember").Logger()
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if member.Role != auth.RoleOwner {
+			if err := keepOwner(tx, member.ProjectID, member.UserID); err != nil {
+				return err
+			}
+		}
+		result := tx.Model(&Member{}).
+			Where("project_id = ? AND user_id = ?", member.ProjectID, member.UserID).
+			UpdateColumn("role", member.Role)
+		if result.Error == nil && result.RowsAffected == 0 {
+			return fmt.Errorf("%w: user %d", ErrMemberNotFound, member.UserID)
+		}
+		return result.Error
+	})
+	if errors.Is(err, ErrLastOwner) || errors.Is(err, ErrMemberNotFound) {
+		log.Info().Err(err).Uint64("userId", member.UserID).Msg("member not updated")
+		return err
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("failed to update member")
+		return fmt.Errorf("failed to update member: %w", err)
+	}
+	log.Info().Uint64("projectId", member.ProjectID).Uint64("userId", member.UserID).Msg("success to update member")
+	return nil
+}
+
+// DeleteMember takes a user out of a project. The project's last owner cannot leave.
+func (r *ProjectRepositoryImpl) DeleteMember(ctx context.Context, projectID, userID uint64) error {
+	log := zerolog.Ctx(ctx).With().Str("method", "projectRepository.DeleteMember").Logger()
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := keepOwner(tx, projectID, userID); err != nil {
+			return err
+		}
+		result := tx.Where("project_id = ? AND user_id = ?", projectID, userID).Delete(&Member{})
+		if result.Error == nil && result.RowsAffected == 0 {
+			return fmt.Errorf("%w: user %d", ErrMemberNotFound, userID)
+		}
+		return result.Error
+	})
+	if errors.Is(err, ErrLastOwner) || errors.Is(err, ErrMemberNotFound) {
+		log.Info().Err(err).Uint64("userId", userID).Msg("member not deleted")
+		return err
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("failed to delete member")
+		return fmt.Errorf("failed to delete member: %w", err)
+	}
+	log.Info().Uint64("projectId", projectID).Uint64("userId", userID).Msg("success to delete member")
+	return nil
+}
+
+// keepOwner fails with ErrLastOwner when the user is the only owner of the project. It locks the
+// owners' rows until tx ends, so two owners stepping down at once cannot both count the other as
+// staying; on SQLite, which has no row locks, the database-wide write lock does the same.
+func keepOwner(tx *gorm.DB, projectID, userID uint64) error {
+	var owners []uint64
+	err := tx.Model(&Member{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("project_id = ? AND role = ?", projectID, auth.RoleOwner).
+		Pluck("user_id", &owners).Error
+	if err != nil {
+		return err
+	}
+	if len(owners) == 1 && owners[0] == userID {
+		return ErrLastOwner
+	}
+	return nil
+}
+
+// memberAccounts selects members along with the email and name of their accounts.
+func (r *ProjectRepositoryImpl) memberAccounts(ctx context.Context) *gorm.DB {
+	return r.DB.WithContext(ctx).Table("project_member").
+		Select("project_member.*, user_account.email, user_account.name").
+		Joins("JOIN user_account ON user_account.id = project_member.user_id")
+}
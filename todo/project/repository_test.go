@@ -0,0 +1,217 @@
+package project
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"mkmgo-todo/todo/auth"
+	"mkmgo-todo/todo/task"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func newMockRepository(t *testing.T) (*ProjectRepositoryImpl, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{TranslateError: true})
+	assert.NoError(t, err)
+	return NewProjectRepositoryImpl(gormDB), mock
+}
+
+func TestSaveProjectMockAddsOwner(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "project" ("name","created_at","updated_at") VALUES ($1,$2,$3) RETURNING "id"`)).
+		WithArgs("Household", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "project_member" ("project_id","user_id","role","created_at") VALUES ($1,$2,$3,$4)`)).
+		WithArgs(1, 7, auth.RoleOwner, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	project := &Project{Name: "Household"}
+	err := repo.SaveProject(context.Background(), project, 7)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), project.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetMemberMock(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT project_member.*, user_account.email, user_account.name FROM "project_member" JOIN user_account ON user_account.id = project_member.user_id WHERE project_member.project_id = $1 AND project_member.user_id = $2 LIMIT $3`)).
+		WithArgs(1, 7, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"project_id", "user_id", "role", "email", "name"}).AddRow(1, 7, "editor", "ana@example.com", "Ana"))
+
+	member, err := repo.GetMember(context.Background(), 1, 7)
+
+	assert.NoError(t, err)
+	assert.Equal(t, auth.RoleEditor, member.Role)
+	assert.Equal(t, "ana@example.com", member.Email)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetMemberMockWhenNotFound(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM "project_member"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"project_id"}))
+
+	_, err := repo.GetMember(context.Background(), 1, 7)
+
+	assert.ErrorIs(t, err, ErrMemberNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSaveMemberMockWhenAlreadyMember(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "project_member"`)).
+		WillReturnError(&pgconn.PgError{Code: "23505"})
+	mock.ExpectRollback()
+
+	err := repo.SaveMember(context.Background(), &Member{ProjectID: 1, UserID: 7, Role: auth.RoleViewer})
+
+	assert.ErrorIs(t, err, ErrMemberExists)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteMemberMockWhenNotFound(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectBegin()
+	expectOwners(mock, 8)
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "project_member" WHERE project_id = $1 AND user_id = $2`)).
+		WithArgs(1, 7).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err := repo.DeleteMember(context.Background(), 1, 7)
+
+	assert.ErrorIs(t, err, ErrMemberNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// expectOwners expects the owners of project 1 to be read and locked, finding those given.
+func expectOwners(mock sqlmock.Sqlmock, userIDs ...uint64) {
+	rows := sqlmock.NewRows([]string{"user_id"})
+	for _, id := range userIDs {
+		rows.AddRow(id)
+	}
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "user_id" FROM "project_member" WHERE project_id = $1 AND role = $2 FOR UPDATE`)).
+		WithArgs(1, auth.RoleOwner).
+		WillReturnRows(rows)
+}
+
+func TestDeleteMemberMockWhenLastOwner(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectBegin()
+	expectOwners(mock, 7)
+	mock.ExpectRollback()
+
+	err := repo.DeleteMember(context.Background(), 1, 7)
+
+	assert.ErrorIs(t, err, ErrLastOwner)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateMemberMockKeepsAnOwner(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectBegin()
+	expectOwners(mock, 7)
+	mock.ExpectRollback()
+
+	err := repo.UpdateMember(context.Background(), &Member{ProjectID: 1, UserID: 7, Role: auth.RoleEditor})
+	assert.ErrorIs(t, err, ErrLastOwner)
+
+	mock.ExpectBegin()
+	expectOwners(mock, 7, 8)
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "project_member" SET "role"=$1 WHERE project_id = $2 AND user_id = $3`)).
+		WithArgs(auth.RoleEditor, 1, 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = repo.UpdateMember(context.Background(), &Member{ProjectID: 1, UserID: 7, Role: auth.RoleEditor})
+	assert.NoError(t, err)
+
+	// promoting needs no check
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "project_member" SET "role"=$1 WHERE project_id = $2 AND user_id = $3`)).
+		WithArgs(auth.RoleOwner, 1, 8).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = repo.UpdateMember(context.Background(), &Member{ProjectID: 1, UserID: 8, Role: auth.RoleOwner})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCountTasksMock(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT project_id, SUM(CASE WHEN status IN ($1,$2) THEN 0 ELSE 1 END) AS open, SUM(CASE WHEN status = $3 THEN 1 ELSE 0 END) AS completed FROM "task" WHERE project_id IN ($4,$5) AND "task"."deleted_at" IS NULL GROUP BY "project_id"`)).
+		WithArgs(task.StatusDone, task.StatusCancelled, task.StatusDone, 1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"project_id", "open", "completed"}).AddRow(2, 3, 1))
+
+	counts, err := repo.CountTasks(context.Background(), []uint64{1, 2})
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[uint64]TaskCounts{2: {Open: 3, Completed: 1}}, counts)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteProjectMockWhenNotEmpty(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "task" WHERE project_id = $1 AND "task"."deleted_at" IS NULL`)).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectRollback()
+
+	err := repo.DeleteProject(context.Background(), 1)
+
+	assert.ErrorIs(t, err, ErrProjectNotEmpty)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteProjectMock(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "task"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "task" WHERE project_id = $1`)).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "task_tag" WHERE tag_id IN (SELECT "id" FROM "tag" WHERE project_id = $1)`)).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "tag" WHERE project_id = $1`)).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "project_member" WHERE project_id = $1`)).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "project" WHERE "project"."id" = $1`)).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repo.DeleteProject(context.Background(), 1))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,257 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"mkmgo-todo/todo/auth"
+	"mkmgo-todo/todo/task"
+	"mkmgo-todo/todo/user"
+)
+
+var (
+	ErrProjectNotFound = task.NewError(task.ErrNotFound, "project not found")
+	ErrProjectNotEmpty = task.NewError(task.ErrConflict, "project still has tasks; move or delete them first")
+	ErrMemberNotFound  = task.NewError(task.ErrNotFound, "member not found")
+	ErrMemberExists    = task.NewError(task.ErrConflict, "user is already a member of the project")
+	ErrLastOwner       = task.NewError(task.ErrConflict, "a project needs at least one owner")
+	ErrNotOwner        = task.NewError(task.ErrForbidden, "only project owners may manage the project and its members")
+)
+
+var errNoUser = errors.New("no authenticated user to manage projects for")
+
+type ProjectRepository interface {
+	SaveProject(ctx context.Context, project *Project, ownerID uint64) error
+	GetProjects(ctx context.Context, userID uint64) ([]MemberProject, error)
+	GetProject(ctx context.Context, id uint64) (*Project, error)
+	UpdateProject(ctx context.Context, project *Project) error
+	DeleteProject(ctx context.Context, id uint64) error
+	CountTasks(ctx context.Context, projectIDs []uint64) (map[uint64]TaskCounts, error)
+	GetMember(ctx context.Context, projectID, userID uint64) (*MemberAccount, error)
+	GetMembers(ctx context.Context, projectID uint64) ([]MemberAccount, error)
+	SaveMember(ctx context.Context, member *Member) error
+	UpdateMember(ctx context.Context, member *Member) error
+	DeleteMember(ctx context.Context, projectID, userID uint64) error
+}
+
+// UserRepository finds the accounts that are added as members.
+type UserRepository interface {
+	GetUserByEmail(ctx context.Context, email string) (*user.User, error)
+}
+
+type ProjectServiceImpl struct {
+	repo  ProjectRepository
+	users UserRepository
+}
+
+func NewProjectServiceImpl(repo ProjectRepository, users UserRepository) *ProjectServiceImpl {
+	return &ProjectServiceImpl{repo: repo, users: users}
+}
+
+// CreateProject creates a project owned by the authenticated user.
+func (svc *ProjectServiceImpl) CreateProject(ctx context.Context, request *CreateProjectRequest) (*ProjectResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := task.Validate(request); err != nil {
+		return nil, err
+	}
+	project := Project{Name: request.Name}
+	if err := svc.repo.SaveProject(ctx, &project, userID); err != nil {
+		return nil, err
+	}
+	response := project.ToResponse(auth.RoleOwner, TaskCounts{})
+	return &response, nil
+}
+
+// GetProjects lists the projects the authenticated user is a member of.
+func (svc *ProjectServiceImpl) GetProjects(ctx context.Context) ([]ProjectResponse, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	projects, err := svc.repo.GetProjects(ctx, userID)
+	if err != nil || len(projects) == 0 {
+		return []ProjectResponse{}, err
+	}
+	ids := make([]uint64, len(projects))
+	for i, project := range projects {
+		ids[i] = project.ID
+	}
+	counts, err := svc.repo.CountTasks(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]ProjectResponse, len(projects))
+	for i, project := range projects {
+		responses[i] = project.ToResponse(project.Role, counts[project.ID])
+	}
+	return responses, nil
+}
+
+func (svc *ProjectServiceImpl) GetProject(ctx context.Context, id uint64) (*ProjectResponse, error) {
+	role, err := svc.GetRole(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	project, err := svc.repo.GetProject(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return svc.toResponse(ctx, project, role)
+}
+
+// UpdateProject renames a project.
+func (svc *ProjectServiceImpl) UpdateProject(ctx context.Context, request *UpdateProjectRequest) (*ProjectResponse, error) {
+	if err := svc.requireOwner(ctx, request.ID); err != nil {
+		return nil, err
+	}
+	if err := task.Validate(request); err != nil {
+		return nil, err
+	}
+	project, err := svc.repo.GetProject(ctx, request.ID)
+	if err != nil {
+		return nil, err
+	}
+	project.Name = request.Name
+	if err := svc.repo.UpdateProject(ctx, project); err != nil {
+		return nil, err
+	}
+	return svc.toResponse(ctx, project, auth.RoleOwner)
+}
+
+// DeleteProject removes a project that has no tasks left.
+func (svc *ProjectServiceImpl) DeleteProject(ctx context.Context, id uint64) error {
+	if err := svc.requireOwner(ctx, id); err != nil {
+		return err
+	}
+	return svc.repo.DeleteProject(ctx, id)
+}
+
+func (svc *ProjectServiceImpl) toResponse(ctx context.Context, project *Project, role auth.Role) (*ProjectResponse, error) {
+	counts, err := svc.repo.CountTasks(ctx, []uint64{project.ID})
+	if err != nil {
+		return nil, err
+	}
+	response := project.ToResponse(role, counts[project.ID])
+	return &response, nil
+}
+
+// GetRole returns the role of the authenticated user in the project. Users who are no member get
+// ErrProjectNotFound, so they cannot tell which projects exist.
+func (svc *ProjectServiceImpl) GetRole(ctx context.Context, projectID uint64) (auth.Role, error) {
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return "", err
+	}
+	member, err := svc.repo.GetMember(ctx, projectID, userID)
+	if errors.Is(err, ErrMemberNotFound) {
+		return "", fmt.Errorf("%w: id %d", ErrProjectNotFound, projectID)
+	}
+	if err != nil {
+		return "", err
+	}
+	return member.Role, nil
+}
+
+// GetMembers lists the members of a project to any of them.
+func (svc *ProjectServiceImpl) GetMembers(ctx context.Context, projectID uint64) ([]MemberResponse, error) {
+	if _, err := svc.GetRole(ctx, projectID); err != nil {
+		return nil, err
+	}
+	members, err := svc.repo.GetMembers(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]MemberResponse, len(members))
+	for i, member := range members {
+		responses[i] = member.ToResponse()
+	}
+	return responses, nil
+}
+
+// AddMember gives the account registered with the email a role in the project.
+func (svc *ProjectServiceImpl) AddMember(ctx context.Context, request *AddMemberRequest) (*MemberResponse, error) {
+	if err := svc.requireOwner(ctx, request.ProjectID); err != nil {
+		return nil, err
+	}
+	if err := task.Validate(request); err != nil {
+		return nil, err
+	}
+	account, err := svc.users.GetUserByEmail(ctx, strings.ToLower(request.Email))
+	if errors.Is(err, user.ErrUserNotFound) {
+		// the same answer for any email that cannot be added, so the endpoint does not tell
+		// which addresses have an account
+		return nil, &task.ValidationError{Fields: []task.FieldError{{Field: "email", Message: "cannot be added to the project"}}}
+	}
+	if err != nil {
+		return nil, err
+	}
+	member := Member{ProjectID: request.ProjectID, UserID: account.ID, Role: request.Role}
+	if err := svc.repo.SaveMember(ctx, &member); err != nil {
+		return nil, err
+	}
+	response := MemberAccount{Member: member, Email: account.Email, Name: account.Name}.ToResponse()
+	return &response, nil
+}
+
+// UpdateMember changes the role of a member. The last owner cannot step down.
+func (svc *ProjectServiceImpl) UpdateMember(ctx context.Context, request *UpdateMemberRequest) (*MemberResponse, error) {
+	if err := svc.requireOwner(ctx, request.ProjectID); err != nil {
+		return nil, err
+	}
+	if err := task.Validate(request); err != nil {
+		return nil, err
+	}
+	member, err := svc.repo.GetMember(ctx, request.ProjectID, request.UserID)
+	if err != nil {
+		return nil, err
+	}
+	member.Role = request.Role
+	if err := svc.repo.UpdateMember(ctx, &member.Member); err != nil {
+		return nil, err
+	}
+	response := member.ToResponse()
+	return &response, nil
+}
+
+// RemoveMember takes a user out of the project. Owners may remove anyone and every member may
+// leave, but the last owner cannot.
+func (svc *ProjectServiceImpl) RemoveMember(ctx context.Context, projectID, userID uint64) error {
+	currentID, err := currentUser(ctx)
+	if err != nil {
+		return err
+	}
+	if userID != currentID {
+		if err := svc.requireOwner(ctx, projectID); err != nil {
+			return err
+		}
+	}
+	err = svc.repo.DeleteMember(ctx, projectID, userID)
+	if errors.Is(err, ErrMemberNotFound) && userID == currentID {
+		return fmt.Errorf("%w: id %d", ErrProjectNotFound, projectID)
+	}
+	return err
+}
+
+func (svc *ProjectServiceImpl) requireOwner(ctx context.Context, projectID uint64) error {
+	role, err := svc.GetRole(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	if !role.Allows(auth.RoleOwner) {
+		return ErrNotOwner
+	}
+	return nil
+}
+
+func currentUser(ctx context.Context) (uint64, error) {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return 0, errNoUser
+	}
+	return principal.UserID, nil
+}
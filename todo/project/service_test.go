@@ -0,0 +1,332 @@
+package project
+
+import (
+	"context"
+	"testing"
+
+	"mkmgo-todo/todo/auth"
+	"mkmgo-todo/todo/task"
+	"mkmgo-todo/todo/user"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+/*
+	Mock project/repository.go and user/repository.go
+*/
+
+type MockProjectRepository struct {
+	SaveProjectFunc   func(ctx context.Context, project *Project, ownerID uint64) error
+	GetProjectsFunc   func(ctx context.Context, userID uint64) ([]MemberProject, error)
+	GetProjectFunc    func(ctx context.Context, id uint64) (*Project, error)
+	UpdateProjectFunc func(ctx context.Context, project *Project) error
+	DeleteProjectFunc func(ctx context.Context, id uint64) error
+	CountTasksFunc    func(ctx context.Context, projectIDs []uint64) (map[uint64]TaskCounts, error)
+	GetMemberFunc     func(ctx context.Context, projectID, userID uint64) (*MemberAccount, error)
+	GetMembersFunc    func(ctx context.Context, projectID uint64) ([]MemberAccount, error)
+	SaveMemberFunc    func(ctx context.Context, member *Member) error
+	UpdateMemberFunc  func(ctx context.Context, member *Member) error
+	DeleteMemberFunc  func(ctx context.Context, projectID, userID uint64) error
+}
+
+func (m *MockProjectRepository) SaveProject(ctx context.Context, project *Project, ownerID uint64) error {
+	if m.SaveProjectFunc != nil {
+		return m.SaveProjectFunc(ctx, project, ownerID)
+	}
+	return nil
+}
+
+func (m *MockProjectRepository) GetProjects(ctx context.Context, userID uint64) ([]MemberProject, error) {
+	if m.GetProjectsFunc != nil {
+		return m.GetProjectsFunc(ctx, userID)
+	}
+	return nil, nil
+}
+
+func (m *MockProjectRepository) GetProject(ctx context.Context, id uint64) (*Project, error) {
+	if m.GetProjectFunc != nil {
+		return m.GetProjectFunc(ctx, id)
+	}
+	return &Project{ID: id}, nil
+}
+
+func (m *MockProjectRepository) UpdateProject(ctx context.Context, project *Project) error {
+	if m.UpdateProjectFunc != nil {
+		return m.UpdateProjectFunc(ctx, project)
+	}
+	return nil
+}
+
+func (m *MockProjectRepository) DeleteProject(ctx context.Context, id uint64) error {
+	if m.DeleteProjectFunc != nil {
+		return m.DeleteProjectFunc(ctx, id)
+	}
+	return nil
+}
+
+func (m *MockProjectRepository) CountTasks(ctx context.Context, projectIDs []uint64) (map[uint64]TaskCounts, error) {
+	if m.CountTasksFunc != nil {
+		return m.CountTasksFunc(ctx, projectIDs)
+	}
+	return nil, nil
+}
+
+func (m *MockProjectRepository) GetMember(ctx context.Context, projectID, userID uint64) (*MemberAccount, error) {
+	if m.GetMemberFunc != nil {
+		return m.GetMemberFunc(ctx, projectID, userID)
+	}
+	return nil, ErrMemberNotFound
+}
+
+func (m *MockProjectRepository) GetMembers(ctx context.Context, projectID uint64) ([]MemberAccount, error) {
+	if m.GetMembersFunc != nil {
+		return m.GetMembersFunc(ctx, projectID)
+	}
+	return nil, nil
+}
+
+func (m *MockProjectRepository) SaveMember(ctx context.Context, member *Member) error {
+	if m.SaveMemberFunc != nil {
+		return m.SaveMemberFunc(ctx, member)
+	}
+	return nil
+}
+
+func (m *MockProjectRepository) UpdateMember(ctx context.Context, member *Member) error {
+	if m.UpdateMemberFunc != nil {
+		return m.UpdateMemberFunc(ctx, member)
+	}
+	return nil
+}
+
+func (m *MockProjectRepository) DeleteMember(ctx context.Context, projectID, userID uint64) error {
+	if m.DeleteMemberFunc != nil {
+		return m.DeleteMemberFunc(ctx, projectID, userID)
+	}
+	return nil
+}
+
+type MockUserRepository struct {
+	GetUserByEmailFunc func(ctx context.Context, email string) (*user.User, error)
+}
+
+func (m *MockUserRepository) GetUserByEmail(ctx context.Context, email string) (*user.User, error) {
+	if m.GetUserByEmailFunc != nil {
+		return m.GetUserByEmailFunc(ctx, email)
+	}
+	return nil, user.ErrUserNotFound
+}
+
+// membersOf answers member lookups for project 1 from the roles of its members.
+func membersOf(roles map[uint64]auth.Role) *MockProjectRepository {
+	return &MockProjectRepository{
+		GetMemberFunc: func(ctx context.Context, projectID, userID uint64) (*MemberAccount, error) {
+			role, ok := roles[userID]
+			if projectID != 1 || !ok {
+				return nil, ErrMemberNotFound
+			}
+			return &MemberAccount{Member: Member{ProjectID: projectID, UserID: userID, Role: role}}, nil
+		},
+		UpdateMemberFunc: func(ctx context.Context, member *Member) error {
+			if member.Role != auth.RoleOwner && lastOwner(roles, member.UserID) {
+				return ErrLastOwner
+			}
+			roles[member.UserID] = member.Role
+			return nil
+		},
+		DeleteMemberFunc: func(ctx context.Context, projectID, userID uint64) error {
+			if _, ok := roles[userID]; projectID != 1 || !ok {
+				return ErrMemberNotFound
+			}
+			if lastOwner(roles, userID) {
+				return ErrLastOwner
+			}
+			delete(roles, userID)
+			return nil
+		},
+	}
+}
+
+// lastOwner follows the repository's rule: a user is the last owner when nobody else is one.
+func lastOwner(roles map[uint64]auth.Role, userID uint64) bool {
+	for id, role := range roles {
+		if id != userID && role == auth.RoleOwner {
+			return false
+		}
+	}
+	return roles[userID] == auth.RoleOwner
+}
+
+func userCtx(userID uint64) context.Context {
+	return auth.WithPrincipal(context.Background(), auth.Principal{UserID: userID})
+}
+
+/*
+	Unit test for project/service.go
+*/
+
+func TestCreateProject(t *testing.T) {
+	mockRepo := &MockProjectRepository{
+		SaveProjectFunc: func(ctx context.Context, project *Project, ownerID uint64) error {
+			assert.Equal(t, uint64(7), ownerID)
+			project.ID = 1
+			return nil
+		},
+	}
+	service := NewProjectServiceImpl(mockRepo, &MockUserRepository{})
+
+	resp, err := service.CreateProject(userCtx(7), &CreateProjectRequest{Name: "Household"})
+
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), resp.ID)
+	assert.Equal(t, auth.RoleOwner, resp.Role)
+
+	_, err = service.CreateProject(userCtx(7), &CreateProjectRequest{Name: " "})
+	assert.ErrorIs(t, err, task.ErrValidation)
+}
+
+func TestGetProjects(t *testing.T) {
+	mockRepo := &MockProjectRepository{
+		GetProjectsFunc: func(ctx context.Context, userID uint64) ([]MemberProject, error) {
+			assert.Equal(t, uint64(7), userID)
+			return []MemberProject{
+				{Project: Project{ID: 1, Name: "Garden"}, Role: auth.RoleViewer},
+				{Project: Project{ID: 2, Name: "Household"}, Role: auth.RoleOwner},
+			}, nil
+		},
+		CountTasksFunc: func(ctx context.Context, projectIDs []uint64) (map[uint64]TaskCounts, error) {
+			assert.Equal(t, []uint64{1, 2}, projectIDs)
+			return map[uint64]TaskCounts{2: {Open: 3, Completed: 1}}, nil
+		},
+	}
+	service := NewProjectServiceImpl(mockRepo, &MockUserRepository{})
+
+	resp, err := service.GetProjects(userCtx(7))
+
+	require.NoError(t, err)
+	assert.Equal(t, []ProjectResponse{
+		{ID: 1, Name: "Garden", Role: auth.RoleViewer},
+		{ID: 2, Name: "Household", Role: auth.RoleOwner, TaskCounts: TaskCounts{Open: 3, Completed: 1}},
+	}, resp)
+}
+
+func TestUpdateProject(t *testing.T) {
+	var renamed *Project
+	mockRepo := membersOf(map[uint64]auth.Role{7: auth.RoleOwner, 8: auth.RoleEditor})
+	mockRepo.UpdateProjectFunc = func(ctx context.Context, project *Project) error {
+		renamed = project
+		return nil
+	}
+	service := NewProjectServiceImpl(mockRepo, &MockUserRepository{})
+
+	_, err := service.UpdateProject(userCtx(8), &UpdateProjectRequest{ID: 1, Name: "Chores"})
+	assert.ErrorIs(t, err, ErrNotOwner)
+	assert.Nil(t, renamed)
+
+	resp, err := service.UpdateProject(userCtx(7), &UpdateProjectRequest{ID: 1, Name: "Chores"})
+	require.NoError(t, err)
+	assert.Equal(t, "Chores", resp.Name)
+	assert.Equal(t, "Chores", renamed.Name)
+}
+
+func TestDeleteProject(t *testing.T) {
+	var deleted []uint64
+	mockRepo := membersOf(map[uint64]auth.Role{7: auth.RoleOwner, 8: auth.RoleEditor})
+	mockRepo.DeleteProjectFunc = func(ctx context.Context, id uint64) error {
+		deleted = append(deleted, id)
+		return nil
+	}
+	service := NewProjectServiceImpl(mockRepo, &MockUserRepository{})
+
+	assert.ErrorIs(t, service.DeleteProject(userCtx(8), 1), ErrNotOwner)
+	assert.ErrorIs(t, service.DeleteProject(userCtx(9), 1), ErrProjectNotFound)
+	assert.NoError(t, service.DeleteProject(userCtx(7), 1))
+	assert.Equal(t, []uint64{1}, deleted)
+}
+
+func TestGetRole(t *testing.T) {
+	service := NewProjectServiceImpl(membersOf(map[uint64]auth.Role{7: auth.RoleEditor}), &MockUserRepository{})
+
+	role, err := service.GetRole(userCtx(7), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, auth.RoleEditor, role)
+
+	_, err = service.GetRole(userCtx(8), 1)
+	assert.ErrorIs(t, err, ErrProjectNotFound)
+	_, err = service.GetRole(context.Background(), 1)
+	assert.ErrorIs(t, err, errNoUser)
+}
+
+func TestAddMember(t *testing.T) {
+	var saved *Member
+	mockRepo := membersOf(map[uint64]auth.Role{7: auth.RoleOwner, 8: auth.RoleEditor})
+	mockRepo.SaveMemberFunc = func(ctx context.Context, member *Member) error {
+		saved = member
+		return nil
+	}
+	users := &MockUserRepository{
+		GetUserByEmailFunc: func(ctx context.Context, email string) (*user.User, error) {
+			if email != "ana@example.com" {
+				return nil, user.ErrUserNotFound
+			}
+			return &user.User{ID: 9, Email: email, Name: "Ana"}, nil
+		},
+	}
+	service := NewProjectServiceImpl(mockRepo, users)
+
+	resp, err := service.AddMember(userCtx(7), &AddMemberRequest{ProjectID: 1, Email: "Ana@Example.com", Role: auth.RoleViewer})
+	require.NoError(t, err)
+	assert.Equal(t, MemberResponse{UserID: 9, Email: "ana@example.com", Name: "Ana", Role: auth.RoleViewer}, *resp)
+	assert.Equal(t, &Member{ProjectID: 1, UserID: 9, Role: auth.RoleViewer}, saved)
+
+	_, err = service.AddMember(userCtx(7), &AddMemberRequest{ProjectID: 1, Email: "bob@example.com", Role: auth.RoleViewer})
+	var validationErr *task.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []task.FieldError{{Field: "email", Message: "cannot be added to the project"}}, validationErr.Fields)
+	assert.NotErrorIs(t, err, user.ErrUserNotFound)
+	_, err = service.AddMember(userCtx(7), &AddMemberRequest{ProjectID: 1, Email: "ana@example.com", Role: "admin"})
+	assert.ErrorIs(t, err, task.ErrValidation)
+	_, err = service.AddMember(userCtx(8), &AddMemberRequest{ProjectID: 1, Email: "ana@example.com", Role: auth.RoleViewer})
+	assert.ErrorIs(t, err, task.ErrForbidden)
+	_, err = service.AddMember(userCtx(9), &AddMemberRequest{ProjectID: 1, Email: "ana@example.com", Role: auth.RoleViewer})
+	assert.ErrorIs(t, err, ErrProjectNotFound)
+}
+
+func TestUpdateMemberKeepsAnOwner(t *testing.T) {
+	roles := map[uint64]auth.Role{7: auth.RoleOwner, 8: auth.RoleEditor}
+	mockRepo := membersOf(roles)
+	service := NewProjectServiceImpl(mockRepo, &MockUserRepository{})
+
+	_, err := service.UpdateMember(userCtx(7), &UpdateMemberRequest{ProjectID: 1, UserID: 7, Role: auth.RoleEditor})
+	assert.ErrorIs(t, err, ErrLastOwner)
+
+	resp, err := service.UpdateMember(userCtx(7), &UpdateMemberRequest{ProjectID: 1, UserID: 8, Role: auth.RoleOwner})
+	require.NoError(t, err)
+	assert.Equal(t, auth.RoleOwner, resp.Role)
+
+	_, err = service.UpdateMember(userCtx(7), &UpdateMemberRequest{ProjectID: 1, UserID: 7, Role: auth.RoleEditor})
+	assert.NoError(t, err)
+	assert.Equal(t, auth.RoleEditor, roles[7])
+}
+
+func TestRemoveMember(t *testing.T) {
+	var removed []uint64
+	mockRepo := membersOf(map[uint64]auth.Role{7: auth.RoleOwner, 8: auth.RoleEditor, 9: auth.RoleViewer})
+	deleteMember := mockRepo.DeleteMemberFunc
+	mockRepo.DeleteMemberFunc = func(ctx context.Context, projectID, userID uint64) error {
+		if err := deleteMember(ctx, projectID, userID); err != nil {
+			return err
+		}
+		removed = append(removed, userID)
+		return nil
+	}
+	service := NewProjectServiceImpl(mockRepo, &MockUserRepository{})
+
+	assert.ErrorIs(t, service.RemoveMember(userCtx(8), 1, 9), task.ErrForbidden)
+	assert.ErrorIs(t, service.RemoveMember(userCtx(7), 1, 7), ErrLastOwner)
+	assert.ErrorIs(t, service.RemoveMember(userCtx(10), 1, 10), ErrProjectNotFound)
+	assert.NoError(t, service.RemoveMember(userCtx(8), 1, 8), "members may leave")
+	assert.NoError(t, service.RemoveMember(userCtx(7), 1, 9))
+	assert.Equal(t, []uint64{8, 9}, removed)
+}
@@ -0,0 +1,80 @@
+package task
+
+import (
+	"errors"
+	"strings"
+)
+
+// Error kinds. Every error the service reports to callers wraps one of these, so handlers can
+// pick a response without knowing about individual errors. Anything else is an internal error
+// whose details must not leave the server.
+var (
+	ErrNotFound           = errors.New("not found")
+	ErrValidation         = errors.New("validation failed")
+	ErrConflict           = errors.New("conflict")
+	ErrPreconditionFailed = errors.New("precondition failed")
+	ErrForbidden          = errors.New("forbidden")
+)
+
+var (
+	ErrTaskNotFound            = NewError(ErrNotFound, "task not found")
+	ErrTaskExists              = NewError(ErrConflict, "task already exists")
+	ErrTaskModified            = NewError(ErrConflict, "task was modified concurrently")
+	ErrTaskVersionMismatch     = NewError(ErrPreconditionFailed, "task version does not match If-Match")
+	ErrInvalidStatusTransition = NewError(ErrConflict, "invalid task status transition")
+	ErrInvalidPatch            = NewError(ErrValidation, "patch cannot be applied to task")
+	ErrPatchTestFailed         = NewError(ErrConflict, "patch test operation failed")
+	ErrTaskNotTrashed          = NewError(ErrConflict, "task is not in the trash")
+	ErrTaskForbidden           = NewError(ErrForbidden, "not allowed to change tasks in this project")
+	ErrTagNotFound             = NewError(ErrNotFound, "tag not found")
+	ErrTagExists               = NewError(ErrConflict, "tag already exists")
+)
+
+// kindError is an error with its own message that also matches its kind in errors.Is.
+type kindError struct {
+	kind    error
+	message string
+}
+
+// NewError returns an error of the given kind, for packages beside task to report errors that
+// handlers understand.
+func NewError(kind error, message string) error {
+	return &kindError{kind: kind, message: message}
+}
+
+func (e *kindError) Error() string {
+	return e.message
+}
+
+func (e *kindError) Unwrap() error {
+	return e.kind
+}
+
+// IsDomainError reports whether err belongs to one of the error kinds, making its message safe
+// to show to clients.
+func IsDomainError(err error) bool {
+	return errors.Is(err, ErrNotFound) || errors.Is(err, ErrValidation) ||
+		errors.Is(err, ErrConflict) || errors.Is(err, ErrPreconditionFailed) || errors.Is(err, ErrForbidden)
+}
+
+type FieldError struct {
+	Field   string `json:"field"` // JSON name of the offending field
+	Message string `json:"message"`
+}
+
+// ValidationError lists every problem found with a request.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	problems := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		problems[i] = field.Field + " " + field.Message
+	}
+	return "validation failed: " + strings.Join(problems, "; ")
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrValidation
+}
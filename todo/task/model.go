@@ -1,42 +1,337 @@
 package task
 
 import (
+	"fmt"
 	"mkmgo-todo/todo/pagination"
+	"mkmgo-todo/todo/patch"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+type Status string
+
+const (
+	StatusTodo       Status = "todo"
+	StatusInProgress Status = "in_progress"
+	StatusDone       Status = "done"
+	StatusCancelled  Status = "cancelled"
+)
+
+// statusTransitions lists, for every status, the statuses a task may move to next.
+var statusTransitions = map[Status][]Status{
+	StatusTodo:       {StatusInProgress, StatusDone, StatusCancelled},
+	StatusInProgress: {StatusTodo, StatusDone, StatusCancelled},
+	StatusDone:       {StatusTodo, StatusInProgress},
+	StatusCancelled:  {StatusTodo},
+}
+
+func (s Status) CanTransitionTo(next Status) bool {
+	for _, allowed := range statusTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
 type Task struct {
 	ID          uint64         `json:"id" gorm:"primaryKey"`
 	Title       string         `json:"title" gorm:"not null"`
 	Description string         `json:"description" gorm:"not null"`
+	Status      Status         `json:"status" gorm:"not null;default:todo;index"`
+	CompletedAt *time.Time     `json:"completedAt"`
+	StartAt     *time.Time     `json:"startAt"`
+	DueAt       *time.Time     `json:"dueAt" gorm:"index"`
+	Version     uint64         `json:"version" gorm:"not null;default:1"` // bumped on every update
 	CreatedAt   time.Time      `json:"createdAt" gorm:"not null"`
 	UpdatedAt   time.Time      `json:"updatedAt" gorm:"not null"`
 	DeletedAt   gorm.DeletedAt `json:"deletedAt" gorm:"index"`
+	OwnerID     uint64         `json:"ownerId" gorm:"not null;default:0;index"` // user.User the task belongs to
+	// ProjectID is the project.Project whose members share the task, 0 for a task only its owner sees.
+	ProjectID uint64 `json:"projectId" gorm:"not null;default:0;index"`
+	Tags      []Tag  `json:"tags" gorm:"many2many:task_tag"`
 }
 
 func (Task) TableName() string {
 	return "task"
 }
 
+// BeforeSave stores the client-supplied times like the ones GORM sets itself, in UTC at microsecond
+// precision, so they compare the same way on every database backend.
+func (t *Task) BeforeSave(tx *gorm.DB) error {
+	for _, at := range []*time.Time{t.CompletedAt, t.StartAt, t.DueAt} {
+		if at != nil {
+			*at = storedTime(*at)
+		}
+	}
+	return nil
+}
+
+func storedTime(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Microsecond)
+}
+
 type WriteTaskRequest struct {
-	ID          uint64 `json:"id"` // set only when update
-	Title       string `json:"title"`
-	Description string `json:"description"`
+	ID          uint64     `json:"id"`
+	Title       string     `json:"title" validate:"required,trimmed,max=200,printable"`
+	Description string     `json:"description" validate:"max=10000,text"`
+	StartAt     *time.Time `json:"startAt"`
+	DueAt       *time.Time `json:"dueAt" validate:"notbefore=StartAt"`
+	ProjectID   uint64     `json:"projectId"` // changing it moves the task to another project
+	Tags        []string   `json:"tags" validate:"max=20,tagname"`
+	IfMatch     string     `json:"-"` // optional ETag the stored task must still have
+}
+
+type PatchTaskRequest struct {
+	ID      uint64 // taken from the path
+	Patch   patch.Patch
+	IfMatch string
+}
+
+type UpdateTaskStatusRequest struct {
+	ID      uint64 `json:"id"` // taken from the path
+	Status  Status `json:"status" validate:"required,oneof=todo in_progress done cancelled"`
+	IfMatch string `json:"-"`
 }
 
 type GetTaskResponse struct {
-	ID          uint64 `json:"id"`
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	UpdatedAt   string `json:"updatedAt"`
+	ID          uint64   `json:"id"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Status      Status   `json:"status"`
+	CompletedAt string   `json:"completedAt,omitempty"`
+	StartAt     string   `json:"startAt,omitempty"`
+	DueAt       string   `json:"dueAt,omitempty"`
+	Overdue     bool     `json:"overdue"`
+	UpdatedAt   string   `json:"updatedAt"`
+	DeletedAt   string   `json:"deletedAt,omitempty"` // only for tasks in the trash
+	ProjectID   uint64   `json:"projectId,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	ETag        string   `json:"-"`
+}
+
+func (t Task) ETag() string {
+	return `"` + strconv.FormatUint(t.Version, 10) + `"`
+}
+
+// MatchesETag evaluates an If-Match header value against the task. An empty value matches
+// anything, as does "*" since the task exists.
+func (t Task) MatchesETag(ifMatch string) bool {
+	if ifMatch == "" || ifMatch == "*" {
+		return true
+	}
+	etag := t.ETag()
+	for _, candidate := range strings.Split(ifMatch, ",") {
+		if strings.TrimSpace(candidate) == etag {
+			return true
+		}
+	}
+	return false
 }
 
 func (t Task) FormattedUpdatedAt() string {
-	return t.UpdatedAt.Format("02 Jan 2006, 15:04") + " WIB"
+	return formatTime(t.UpdatedAt)
+}
+
+func (t Task) FormattedCompletedAt() string {
+	return formatOptionalTime(t.CompletedAt)
+}
+
+func (t Task) IsOpen() bool {
+	return t.Status != StatusDone && t.Status != StatusCancelled
+}
+
+func (t Task) IsOverdue(now time.Time) bool {
+	return t.IsOpen() && t.DueAt != nil && t.DueAt.Before(now)
+}
+
+// ToWriteRequest returns the client-writable part of the task, the document PATCH requests are applied to.
+// Its times read as they do in the response, so a "test" operation can compare against either.
+func (t Task) ToWriteRequest() WriteTaskRequest {
+	return WriteTaskRequest{
+		ID:          t.ID,
+		Title:       t.Title,
+		Description: t.Description,
+		StartAt:     localTime(t.StartAt),
+		DueAt:       localTime(t.DueAt),
+		ProjectID:   t.ProjectID,
+		Tags:        t.TagNames(),
+	}
+}
+
+func (t Task) ToResponse() GetTaskResponse {
+	var deletedAt *time.Time
+	if t.DeletedAt.Valid {
+		deletedAt = &t.DeletedAt.Time
+	}
+	return GetTaskResponse{
+		ID:          t.ID,
+		Title:       t.Title,
+		Description: t.Description,
+		Status:      t.Status,
+		CompletedAt: t.FormattedCompletedAt(),
+		StartAt:     formatOptionalTime(t.StartAt),
+		DueAt:       formatOptionalTime(t.DueAt),
+		Overdue:     t.IsOverdue(time.Now()),
+		UpdatedAt:   t.FormattedUpdatedAt(),
+		DeletedAt:   formatOptionalTime(deletedAt),
+		ProjectID:   t.ProjectID,
+		Tags:        t.TagNames(),
+		ETag:        t.ETag(),
+	}
+}
+
+// formatTime writes t as RFC 3339, so clients can send it back in a write or a patch unchanged. It
+// is shown in the server's time zone, the one the due filters count days in.
+func formatTime(t time.Time) string {
+	return t.Local().Format(time.RFC3339Nano)
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return formatTime(*t)
+}
+
+func localTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	local := t.Local()
+	return &local
+}
+
+type DueFilter string
+
+const (
+	DueOverdue  DueFilter = "overdue"
+	DueToday    DueFilter = "today"
+	DueThisWeek DueFilter = "week"
+)
+
+func (f DueFilter) IsValid() bool {
+	switch f {
+	case DueOverdue, DueToday, DueThisWeek:
+		return true
+	}
+	return false
+}
+
+// Range returns the [from, to) window of due dates selected by the filter at the given moment.
+// Weeks start on Monday.
+func (f DueFilter) Range(now time.Time) (from, to *time.Time) {
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch f {
+	case DueOverdue:
+		return nil, &now
+	case DueToday:
+		end := startOfDay.AddDate(0, 0, 1)
+		return &startOfDay, &end
+	case DueThisWeek:
+		start := startOfDay.AddDate(0, 0, -(int(now.Weekday())+6)%7)
+		end := start.AddDate(0, 0, 7)
+		return &start, &end
+	}
+	return nil, nil
 }
 
 type GetAllTaskRequest struct {
 	PaginationRequest *pagination.PaginationRequest
+	Due               DueFilter
+	DueFrom           *time.Time // inclusive
+	DueTo             *time.Time // exclusive
+	OpenOnly          bool       // leave out done and cancelled tasks
+	ProjectID         uint64     // only the tasks of this project, when set
+	TagsAny           []string   // tasks with at least one of these tags
+	TagsAll           []string   // tasks with every one of these tags
+	TagsNone          []string   // tasks with none of these tags
+}
+
+// sortColumns maps the field names clients may sort by onto task columns. Only these names ever
+// reach ORDER BY, so it is also what keeps query input out of the SQL.
+var sortColumns = map[string]string{
+	"id":          "id",
+	"title":       "title",
+	"status":      "status",
+	"startAt":     "start_at",
+	"dueAt":       "due_at",
+	"completedAt": "completed_at",
+	"createdAt":   "created_at",
+	"updatedAt":   "updated_at",
+	"deletedAt":   "deleted_at", // trash only
+}
+
+var (
+	// DefaultSort lists recently changed tasks first.
+	DefaultSort = []pagination.SortField{{Field: "updatedAt", Desc: true}}
+	// DefaultTrashSort lists recently deleted tasks first.
+	DefaultTrashSort = []pagination.SortField{{Field: "deletedAt", Desc: true}}
+)
+
+// SortableFields returns the field names accepted by the sort query parameters.
+func SortableFields() []string {
+	return slices.DeleteFunc(TrashSortableFields(), func(field string) bool { return field == "deletedAt" })
+}
+
+// TrashSortableFields returns the field names accepted by the sort query parameters of the trash.
+func TrashSortableFields() []string {
+	fields := make([]string, 0, len(sortColumns))
+	for field := range sortColumns {
+		fields = append(fields, field)
+	}
+	slices.Sort(fields)
+	return fields
+}
+
+// keysetFields are the sortable fields whose columns can never be NULL, the only ones a cursor can
+// seek on.
+var keysetFields = []string{"createdAt", "id", "status", "title", "updatedAt"}
+
+// KeysetSortableFields returns the field names accepted by the sort query parameters in cursor mode.
+func KeysetSortableFields() []string {
+	return slices.Clone(keysetFields)
+}
+
+// Cursor points just past this task in a listing sorted by the given field.
+func (t Task) Cursor(sort pagination.SortField) pagination.Cursor {
+	cursor := pagination.Cursor{Sort: sort, ID: t.ID}
+	switch sort.Field {
+	case "id":
+		cursor.Key = strconv.FormatUint(t.ID, 10)
+	case "title":
+		cursor.Key = t.Title
+	case "status":
+		cursor.Key = string(t.Status)
+	case "createdAt":
+		cursor.Key = t.CreatedAt.Format(time.RFC3339Nano)
+	case "updatedAt":
+		cursor.Key = t.UpdatedAt.Format(time.RFC3339Nano)
+	}
+	return cursor
+}
+
+// ValidCursor reports whether a cursor seeks on a field a cursor can be issued for, with a key of
+// that field's form. Cursors are decoded from client input, so this may well be false.
+func ValidCursor(cursor pagination.Cursor) bool {
+	_, err := parseCursorKey(cursor)
+	return err == nil
+}
+
+// parseCursorKey turns the key of a cursor back into a value comparable with the sort column.
+func parseCursorKey(cursor pagination.Cursor) (any, error) {
+	switch cursor.Sort.Field {
+	case "id":
+		return strconv.ParseUint(cursor.Key, 10, 64)
+	case "title", "status":
+		return cursor.Key, nil
+	case "createdAt", "updatedAt":
+		t, err := time.Parse(time.RFC3339Nano, cursor.Key)
+		return storedTime(t), err
+	}
+	return nil, fmt.Errorf("field %q cannot be used with a cursor", cursor.Sort.Field)
 }
@@ -2,10 +2,18 @@ package task
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"mkmgo-todo/todo/auth"
+	"mkmgo-todo/todo/pagination"
 
 	"github.com/rs/zerolog"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type TaskRepositoryImpl struct {
@@ -17,8 +25,26 @@ func NewTaskRepositoryImpl(db *gorm.DB) *TaskRepositoryImpl {
 }
 
 func (r *TaskRepositoryImpl) SaveTask(ctx context.Context, task *Task) error {
-	log := zerolog.Ctx(ctx).With().Str("method", "taskService.saveTask").Logger()
-	if err := r.DB.WithContext(ctx).Save(task).Error; err != nil {
+	log := zerolog.Ctx(ctx).With().Str("method", "taskRepository.SaveTask").Logger()
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return err
+	}
+	task.OwnerID = userID
+	err = r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(task).Error; err != nil {
+			return err
+		}
+		if len(task.Tags) == 0 {
+			return nil
+		}
+		return saveTags(tx, task)
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			log.Info().Err(err).Msg("task already exists")
+			return ErrTaskExists
+		}
 		log.Error().Err(err).Msg("failed to save task")
 		return fmt.Errorf("failed to save task: %w", err)
 	}
@@ -26,27 +52,493 @@ func (r *TaskRepositoryImpl) SaveTask(ctx context.Context, task *Task) error {
 	return nil
 }
 
-func (r *TaskRepositoryImpl) GetAllTasks(ctx context.Context, request GetAllTaskRequest) ([]Task, error) {
-	log := zerolog.Ctx(ctx).With().Str("method", "taskService.GetAllTasks").Logger()
-	var tasks []Task
-	err := r.DB.WithContext(ctx).Model(&Task{}).
-		Limit(request.PaginationRequest.PageSize).
-		Offset(request.PaginationRequest.GetOffset()).
-		Find(&tasks).Error
+// UpdateTask writes every column of an existing task, and replaces its tags unless task.Tags is
+// nil. Unlike Save it never inserts, so updating a task that does not exist, or was deleted
+// meanwhile, reports ErrTaskNotFound. The update only applies to the version the task was loaded
+// at; if someone else updated it first ErrTaskModified is returned.
+func (r *TaskRepositoryImpl) UpdateTask(ctx context.Context, task *Task) error {
+	log := zerolog.Ctx(ctx).With().Str("method", "taskRepository.UpdateTask").Logger()
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return err
+	}
+	version := task.Version
+	task.Version++
+	updated := false
+	err = r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(task).Scopes(visibleTo(userID)).Where("version = ?", version).Select("*").Omit(clause.Associations).Updates(task)
+		if result.Error != nil {
+			return result.Error
+		}
+		updated = result.RowsAffected == 1
+		if !updated || task.Tags == nil {
+			return nil
+		}
+		return saveTags(tx, task)
+	})
+	if err == nil && updated {
+		log.Info().Msg("success to update task")
+		return nil
+	}
+	task.Version = version
+	if err != nil {
+		log.Error().Err(err).Msg("failed to update task")
+		return fmt.Errorf("failed to update task: %w", err)
+	}
+
+	var count int64
+	if err := r.DB.WithContext(ctx).Model(&Task{}).Scopes(visibleTo(userID)).Where("id = ?", task.ID).Count(&count).Error; err != nil {
+		log.Error().Err(err).Msg("failed to check task existence")
+		return fmt.Errorf("failed to update task: %w", err)
+	}
+	if count == 0 {
+		log.Info().Uint64("id", task.ID).Msg("task not found")
+		return fmt.Errorf("%w: id %d", ErrTaskNotFound, task.ID)
+	}
+	log.Info().Uint64("id", task.ID).Uint64("version", version).Msg("task was modified concurrently")
+	return ErrTaskModified
+}
+
+func (r *TaskRepositoryImpl) GetTask(ctx context.Context, id uint64) (*Task, error) {
+	log := zerolog.Ctx(ctx).With().Str("method", "taskRepository.GetTask").Logger()
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var task Task
+	if err := r.DB.WithContext(ctx).Scopes(visibleTo(userID)).Preload("Tags", tagsByName).First(&task, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Info().Uint64("id", id).Msg("task not found")
+			return nil, fmt.Errorf("%w: id %d", ErrTaskNotFound, id)
+		}
+		log.Error().Err(err).Msg("failed to retrieve task")
+		return nil, fmt.Errorf("failed to retrieve task: %w", err)
+	}
+	log.Info().Msg("success to retrieve task")
+	return &task, nil
+}
+
+// GetTaskIncludingTrash is GetTask that also finds tasks in the trash.
+func (r *TaskRepositoryImpl) GetTaskIncludingTrash(ctx context.Context, id uint64) (*Task, error) {
+	log := zerolog.Ctx(ctx).With().Str("method", "taskRepository.GetTaskIncludingTrash").Logger()
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var task Task
+	if err := r.DB.WithContext(ctx).Unscoped().Scopes(visibleTo(userID)).First(&task, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Info().Uint64("id", id).Msg("task not found")
+			return nil, fmt.Errorf("%w: id %d", ErrTaskNotFound, id)
+		}
+		log.Error().Err(err).Msg("failed to retrieve task")
+		return nil, fmt.Errorf("failed to retrieve task: %w", err)
+	}
+	log.Info().Msg("success to retrieve task")
+	return &task, nil
+}
+
+// GetAllTasks returns the requested page and the number of tasks matching the filters. In cursor
+// mode it reads one task beyond the page, which tells the caller whether another page follows.
+func (r *TaskRepositoryImpl) GetAllTasks(ctx context.Context, request GetAllTaskRequest) ([]Task, int64, error) {
+	log := zerolog.Ctx(ctx).With().Str("method", "taskRepository.GetAllTasks").Logger()
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	tasks, total, err := listTasks(r.DB.WithContext(ctx), request, visibleTo(userID))
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to retrieve tasks")
-		return nil, fmt.Errorf("failed to retrieve tasks: %w", err)
+		return nil, 0, err
 	}
-	log.Info().Msg("success to retrieve tasks")
-	return tasks, err
+	log.Info().Int64("total", total).Msg("success to retrieve tasks")
+	return tasks, total, nil
 }
 
 func (r *TaskRepositoryImpl) DeleteTask(ctx context.Context, id uint64) error {
-	log := zerolog.Ctx(ctx).With().Str("method", "taskService.DeleteTask").Logger()
-	if err := r.DB.WithContext(ctx).Delete(&Task{}, id).Error; err != nil {
-		log.Error().Err(err).Msg("Failed to delete task")
-		return fmt.Errorf("failed to delete task: %w", err)
+	log := zerolog.Ctx(ctx).With().Str("method", "taskRepository.DeleteTask").Logger()
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return err
+	}
+	result := r.DB.WithContext(ctx).Scopes(visibleTo(userID)).Delete(&Task{}, id)
+	if result.Error != nil {
+		log.Error().Err(result.Error).Msg("Failed to delete task")
+		return fmt.Errorf("failed to delete task: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		log.Info().Uint64("id", id).Msg("task not found")
+		return fmt.Errorf("%w: id %d", ErrTaskNotFound, id)
 	}
 	log.Info().Msg("success to delete task")
 	return nil
 }
+
+// GetTrashedTasks pages through soft-deleted tasks like GetAllTasks.
+func (r *TaskRepositoryImpl) GetTrashedTasks(ctx context.Context, request GetAllTaskRequest) ([]Task, int64, error) {
+	log := zerolog.Ctx(ctx).With().Str("method", "taskRepository.GetTrashedTasks").Logger()
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	tasks, total, err := listTasks(r.DB.WithContext(ctx), request, visibleTo(userID), trashed)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to retrieve trashed tasks")
+		return nil, 0, err
+	}
+	log.Info().Int64("total", total).Msg("success to retrieve trashed tasks")
+	return tasks, total, nil
+}
+
+// RestoreTask clears the deletion mark of a trashed task and bumps its version, so edits made
+// against the pre-deletion ETag are refused.
+func (r *TaskRepositoryImpl) RestoreTask(ctx context.Context, id uint64) error {
+	log := zerolog.Ctx(ctx).With().Str("method", "taskRepository.RestoreTask").Logger()
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return err
+	}
+	result := r.DB.WithContext(ctx).Unscoped().Model(&Task{}).Scopes(visibleTo(userID)).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Updates(map[string]any{"deleted_at": nil, "version": gorm.Expr("version + 1")})
+	if result.Error != nil {
+		log.Error().Err(result.Error).Msg("failed to restore task")
+		return fmt.Errorf("failed to restore task: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		var count int64
+		if err := r.DB.WithContext(ctx).Model(&Task{}).Scopes(visibleTo(userID)).Where("id = ?", id).Count(&count).Error; err != nil {
+			log.Error().Err(err).Msg("failed to restore task")
+			return fmt.Errorf("failed to restore task: %w", err)
+		}
+		if count > 0 {
+			log.Info().Uint64("id", id).Msg("task is not in the trash")
+			return fmt.Errorf("%w: id %d", ErrTaskNotTrashed, id)
+		}
+		log.Info().Uint64("id", id).Msg("task not found")
+		return fmt.Errorf("%w: id %d", ErrTaskNotFound, id)
+	}
+	log.Info().Msg("success to restore task")
+	return nil
+}
+
+// PurgeTask hard-deletes a task, trashed or not.
+func (r *TaskRepositoryImpl) PurgeTask(ctx context.Context, id uint64) error {
+	log := zerolog.Ctx(ctx).With().Str("method", "taskRepository.PurgeTask").Logger()
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return err
+	}
+	var result *gorm.DB
+	err = r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result = tx.Unscoped().Scopes(visibleTo(userID)).Delete(&Task{}, id)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return tx.Where("task_id = ?", id).Delete(&TaskTag{}).Error
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("failed to purge task")
+		return fmt.Errorf("failed to purge task: %w", err)
+	}
+	if result.RowsAffected == 0 {
+		log.Info().Uint64("id", id).Msg("task not found")
+		return fmt.Errorf("%w: id %d", ErrTaskNotFound, id)
+	}
+	log.Info().Msg("success to purge task")
+	return nil
+}
+
+// PurgeTrash hard-deletes every task that went into the trash before deletedBefore. It serves the
+// retention job rather than a user, so it spans all owners.
+func (r *TaskRepositoryImpl) PurgeTrash(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	log := zerolog.Ctx(ctx).With().Str("method", "taskRepository.PurgeTrash").Logger()
+	var result *gorm.DB
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		expired := func(db *gorm.DB) *gorm.DB {
+			return db.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", storedTime(deletedBefore))
+		}
+		if err := tx.Where("task_id IN (?)", tx.Model(&Task{}).Scopes(expired).Select("id")).Delete(&TaskTag{}).Error; err != nil {
+			return err
+		}
+		result = tx.Scopes(expired).Delete(&Task{})
+		return result.Error
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("failed to purge trash")
+		return 0, fmt.Errorf("failed to purge trash: %w", err)
+	}
+	log.Info().Int64("purged", result.RowsAffected).Msg("success to purge trash")
+	return result.RowsAffected, nil
+}
+
+// AssignUnownedTasks gives every task without an owner, trashed or not, to ownerID. Tasks only
+// lack an owner when they were created before user accounts existed.
+func (r *TaskRepositoryImpl) AssignUnownedTasks(ctx context.Context, ownerID uint64) (int64, error) {
+	log := zerolog.Ctx(ctx).With().Str("method", "taskRepository.AssignUnownedTasks").Logger()
+	result := r.DB.WithContext(ctx).Unscoped().Model(&Task{}).
+		Where("owner_id = 0").
+		UpdateColumn("owner_id", ownerID)
+	if result.Error != nil {
+		log.Error().Err(result.Error).Msg("failed to assign tasks")
+		return 0, fmt.Errorf("failed to assign tasks: %w", result.Error)
+	}
+	log.Info().Int64("assigned", result.RowsAffected).Uint64("ownerId", ownerID).Msg("success to assign tasks")
+	return result.RowsAffected, nil
+}
+
+// CountTasksByStatus counts the tasks of all owners outside the trash per status. Statuses
+// without tasks are left out.
+func (r *TaskRepositoryImpl) CountTasksByStatus(ctx context.Context) (map[Status]int64, error) {
+	log := zerolog.Ctx(ctx).With().Str("method", "taskRepository.CountTasksByStatus").Logger()
+	var rows []struct {
+		Status Status
+		Count  int64
+	}
+	err := r.DB.WithContext(ctx).Model(&Task{}).
+		Select("status, COUNT(*) AS count").
+		Group("status").
+		Scan(&rows).Error
+	if err != nil {
+		log.Error().Err(err).Msg("failed to count tasks")
+		return nil, fmt.Errorf("failed to count tasks: %w", err)
+	}
+	counts := make(map[Status]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	// Scraped every few seconds, so kept out of the info log.
+	log.Debug().Msg("success to count tasks")
+	return counts, nil
+}
+
+// listTasks reads one page of the tasks matching the request filters and the scopes, and counts
+// them all.
+func listTasks(db *gorm.DB, request GetAllTaskRequest, scopes ...func(*gorm.DB) *gorm.DB) ([]Task, int64, error) {
+	filters := append([]func(*gorm.DB) *gorm.DB{dueFilter(request), projectFilter(request), tagFilter(request)}, scopes...)
+
+	var tasks []Task
+	err := db.Model(&Task{}).
+		Scopes(filters...).
+		Preload("Tags", tagsByName).
+		Scopes(orderBy(request.PaginationRequest.Sort), paginate(request.PaginationRequest)).
+		Find(&tasks).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to retrieve tasks: %w", err)
+	}
+
+	var total int64
+	if err := db.Model(&Task{}).Scopes(filters...).Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count tasks: %w", err)
+	}
+	return tasks, total, nil
+}
+
+// errNoUser means a repository method was called outside an authenticated request. It is a bug,
+// not a client error, so it has no kind.
+var errNoUser = errors.New("no authenticated user to scope tasks to")
+
+// currentUser returns the user whose tasks the request may touch. Every query for a request is
+// limited to the tasks visible to them, so nobody can reach another's tasks.
+func currentUser(ctx context.Context) (uint64, error) {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return 0, errNoUser
+	}
+	return principal.UserID, nil
+}
+
+// visibleTo selects the tasks a user may see: their own tasks outside any project and every task
+// of the projects they are a member of, whatever their role. What they may do with them is up to
+// the service.
+func visibleTo(userID uint64) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("(project_id = 0 AND owner_id = ?) OR project_id IN (SELECT project_id FROM project_member WHERE user_id = ?)", userID, userID)
+	}
+}
+
+// trashed selects soft-deleted tasks only.
+func trashed(db *gorm.DB) *gorm.DB {
+	return db.Unscoped().Where("deleted_at IS NOT NULL")
+}
+
+func dueFilter(request GetAllTaskRequest) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if request.DueFrom != nil {
+			db = db.Where("due_at >= ?", storedTime(*request.DueFrom))
+		}
+		if request.DueTo != nil {
+			db = db.Where("due_at < ?", storedTime(*request.DueTo))
+		}
+		if request.OpenOnly {
+			db = db.Where("status NOT IN ?", []Status{StatusDone, StatusCancelled})
+		}
+		return db
+	}
+}
+
+func projectFilter(request GetAllTaskRequest) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if request.ProjectID != 0 {
+			db = db.Where("project_id = ?", request.ProjectID)
+		}
+		return db
+	}
+}
+
+// tagFilter matches tags by name, whatever scope they belong to. A task only carries tags of its
+// own scope anyway.
+func tagFilter(request GetAllTaskRequest) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		tagged := func(names []string) *gorm.DB {
+			return db.Session(&gorm.Session{NewDB: true}).Table("task_tag").
+				Select("task_tag.task_id").
+				Joins("JOIN tag ON tag.id = task_tag.tag_id").
+				Where("tag.name IN ?", names)
+		}
+		if len(request.TagsAny) > 0 {
+			db = db.Where("id IN (?)", tagged(request.TagsAny))
+		}
+		if len(request.TagsAll) > 0 {
+			db = db.Where("id IN (?)", tagged(request.TagsAll).Group("task_tag.task_id").Having("COUNT(*) = ?", len(request.TagsAll)))
+		}
+		if len(request.TagsNone) > 0 {
+			db = db.Where("id NOT IN (?)", tagged(request.TagsNone))
+		}
+		return db
+	}
+}
+
+// tagsByName orders preloaded tags.
+func tagsByName(db *gorm.DB) *gorm.DB {
+	return db.Order("name")
+}
+
+// saveTags makes the tags named in task.Tags the task's only ones, creating those its scope
+// lacks, and fills in the tags as stored.
+func saveTags(tx *gorm.DB, task *Task) error {
+	if err := tx.Where("task_id = ?", task.ID).Delete(&TaskTag{}).Error; err != nil {
+		return err
+	}
+	if len(task.Tags) == 0 {
+		return nil
+	}
+	projectID, ownerID := task.tagScope()
+	names := make([]string, len(task.Tags))
+	for i, tag := range task.Tags {
+		names[i] = tag.Name
+	}
+
+	var tags []Tag
+	if err := tx.Where("project_id = ? AND owner_id = ? AND name IN ?", projectID, ownerID, names).Find(&tags).Error; err != nil {
+		return err
+	}
+	var missing []Tag
+	for _, name := range names {
+		if !slices.ContainsFunc(tags, func(tag Tag) bool { return tag.Name == name }) {
+			missing = append(missing, Tag{Name: name, ProjectID: projectID, OwnerID: ownerID})
+		}
+	}
+	if len(missing) > 0 {
+		if err := tx.Create(&missing).Error; err != nil {
+			return err
+		}
+		tags = append(tags, missing...)
+	}
+
+	links := make([]TaskTag, len(tags))
+	for i, tag := range tags {
+		links[i] = TaskTag{TaskID: task.ID, TagID: tag.ID}
+	}
+	if err := tx.Create(&links).Error; err != nil {
+		return err
+	}
+	slices.SortFunc(tags, func(a, b Tag) int { return strings.Compare(a.Name, b.Name) })
+	task.Tags = tags
+	return nil
+}
+
+// orderBy applies the requested sort, then the task ID in the direction of the last key, so rows
+// that tie on every key still come back in the same order from page to page. The service has
+// already checked the fields.
+func orderBy(sort []pagination.SortField) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if len(sort) == 0 {
+			sort = DefaultSort
+		}
+		var keys []string
+		var vars []any
+		for _, field := range sort {
+			column, ok := sortColumns[field.Field]
+			if !ok {
+				db.AddError(fmt.Errorf("unknown sort field %q", field.Field))
+				return db
+			}
+			key := "?"
+			if field.Desc {
+				key += " DESC"
+			}
+			if nullableColumns[column] {
+				key += " NULLS LAST"
+			}
+			keys = append(keys, key)
+			vars = append(vars, sortKey(db, column))
+		}
+		if !slices.ContainsFunc(sort, func(f pagination.SortField) bool { return f.Field == "id" }) {
+			key := "?"
+			if sort[len(sort)-1].Desc {
+				key += " DESC"
+			}
+			keys = append(keys, key)
+			vars = append(vars, clause.Column{Name: "id"})
+		}
+		return db.Order(clause.OrderBy{Expression: clause.Expr{SQL: strings.Join(keys, ","), Vars: vars}})
+	}
+}
+
+// nullableColumns sort after every value in both directions. Postgres and SQLite disagree on where
+// NULLs go unless told.
+var nullableColumns = map[string]bool{"start_at": true, "due_at": true, "completed_at": true, "deleted_at": true}
+
+// textColumns sort bytewise. Postgres would otherwise follow the database collation, which SQLite
+// knows nothing about; its own default, BINARY, is the same byte order as Postgres' "C".
+var textColumns = map[string]bool{"title": true, "status": true}
+
+// sortKey is the expression a column is sorted and compared by.
+func sortKey(db *gorm.DB, column string) clause.Expr {
+	if textColumns[column] && db.Dialector.Name() == "postgres" {
+		return clause.Expr{SQL: `? COLLATE "C"`, Vars: []any{clause.Column{Name: column}}}
+	}
+	return clause.Expr{SQL: "?", Vars: []any{clause.Column{Name: column}}}
+}
+
+// paginate limits the query to one page, by offset or, in cursor mode, by seeking past the cursor,
+// which the service has already checked.
+func paginate(request *pagination.PaginationRequest) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if !request.CursorMode {
+			return db.Limit(request.PageSize).Offset(request.GetOffset())
+		}
+		db = db.Limit(request.PageSize + 1)
+		if request.After == nil {
+			return db
+		}
+		key, err := parseCursorKey(*request.After)
+		if err != nil {
+			db.AddError(fmt.Errorf("invalid cursor: %w", err))
+			return db
+		}
+		column := sortColumns[request.After.Sort.Field]
+		op := ">"
+		if request.After.Sort.Desc {
+			op = "<"
+		}
+		if column == "id" {
+			return db.Where("id "+op+" ?", request.After.ID)
+		}
+		return db.Where(clause.Expr{
+			SQL:  "(?, id) " + op + " (?, ?)",
+			Vars: []any{sortKey(db, column), key, request.After.ID},
+		})
+	}
+}
@@ -2,8 +2,10 @@ package task
 
 import (
 	"context"
+	"mkmgo-todo/todo/auth"
 	"mkmgo-todo/todo/pagination"
 	"regexp"
+	"sync"
 	"testing"
 	"time"
 
@@ -11,8 +13,18 @@ import (
 	"github.com/stretchr/testify/assert"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 )
 
+// ownerCtx is the context of a request authenticated as user 7.
+var ownerCtx = auth.WithPrincipal(context.Background(), auth.Principal{UserID: 7})
+
+// expectNoTags expects the query preloading the tags of the tasks just read, which finds none.
+func expectNoTags(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "task_tag" WHERE "task_tag"."task_id"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"task_id", "tag_id"}))
+}
+
 func TestSaveTaskMock(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	assert.NoError(t, err)
@@ -28,15 +40,63 @@ func TestSaveTaskMock(t *testing.T) {
 	task := &Task{Title: "Mocked Task", Description: "Mocked Desc"}
 
 	mock.ExpectBegin()
-	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "task" ("title","description","created_at","updated_at","deleted_at") VALUES ($1,$2,$3,$4,$5) RETURNING "id"`)).
-		WithArgs(task.Title, task.Description, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "task" ("title","description","status","completed_at","start_at","due_at","version","created_at","updated_at","deleted_at","owner_id","project_id") VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12) RETURNING "id"`)).
+		WithArgs(task.Title, task.Description, StatusTodo, nil, nil, nil, 1, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), 7, 0).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 	mock.ExpectCommit()
 
-	err = repo.SaveTask(context.Background(), task)
+	err = repo.SaveTask(ownerCtx, task)
 
 	assert.NoError(t, err)
 	assert.Equal(t, uint64(1), task.ID)
+	assert.Equal(t, uint64(1), task.Version)
+	assert.Equal(t, uint64(7), task.OwnerID)
+}
+
+func TestSaveTaskMockWithTags(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+	assert.NoError(t, err)
+
+	repo := NewTaskRepositoryImpl(gormDB)
+
+	task := &Task{Title: "Mocked Task", ProjectID: 5, Tags: newTags([]string{"Urgent", "home"})}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "task"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "task_tag" WHERE task_id = $1`)).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	// project tags have no owner; only the missing one is created
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "tag" WHERE project_id = $1 AND owner_id = $2 AND name IN ($3,$4)`)).
+		WithArgs(5, 0, "home", "urgent").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "project_id", "owner_id"}).AddRow(3, "urgent", 5, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "tag" ("name","project_id","owner_id","created_at") VALUES ($1,$2,$3,$4) RETURNING "id"`)).
+		WithArgs("home", 5, 0, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "task_tag" ("task_id","tag_id") VALUES ($1,$2),($3,$4)`)).
+		WithArgs(1, 3, 1, 4).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	err = repo.SaveTask(ownerCtx, task)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Tag{{ID: 4, Name: "home", ProjectID: 5}, {ID: 3, Name: "urgent", ProjectID: 5}}, clearCreatedAt(task.Tags))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func clearCreatedAt(tags []Tag) []Tag {
+	for i := range tags {
+		tags[i].CreatedAt = time.Time{}
+	}
+	return tags
 }
 
 func TestSaveTaskMockWhenError(t *testing.T) {
@@ -60,11 +120,140 @@ func TestSaveTaskMockWhenError(t *testing.T) {
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 	mock.ExpectCommit()
 
-	err = repo.SaveTask(context.Background(), task)
+	err = repo.SaveTask(ownerCtx, task)
 
 	assert.Error(t, err)
 }
 
+func TestUpdateTaskMock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+	assert.NoError(t, err)
+
+	repo := NewTaskRepositoryImpl(gormDB)
+
+	task := &Task{ID: 1, Title: "Mocked Task", Description: "Mocked Desc", Status: StatusDone}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "task" SET "title"=$1,"description"=$2,"status"=$3`)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = repo.UpdateTask(ownerCtx, task)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateTaskMockWhenNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+	assert.NoError(t, err)
+
+	repo := NewTaskRepositoryImpl(gormDB)
+
+	task := &Task{ID: 2, Title: "Mocked Task", Description: "Mocked Desc"}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "task" SET`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "task" WHERE id = $1 AND ((project_id = 0 AND owner_id = $2) OR project_id IN (SELECT project_id FROM project_member WHERE user_id = $3)) AND "task"."deleted_at" IS NULL`)).
+		WithArgs(2, 7, 7).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	err = repo.UpdateTask(ownerCtx, task)
+
+	assert.ErrorIs(t, err, ErrTaskNotFound)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestUpdateTaskMockWhenModifiedConcurrently(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+	assert.NoError(t, err)
+
+	repo := NewTaskRepositoryImpl(gormDB)
+
+	task := &Task{ID: 2, Title: "Mocked Task", Description: "Mocked Desc", Version: 3}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "task" SET`) + `.*` + regexp.QuoteMeta(`WHERE version = $`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "task"`)).
+		WithArgs(2, 7, 7).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	err = repo.UpdateTask(ownerCtx, task)
+
+	assert.ErrorIs(t, err, ErrTaskModified)
+	assert.ErrorIs(t, err, ErrConflict)
+	assert.Equal(t, uint64(3), task.Version)
+}
+
+func TestGetTaskMock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+	assert.NoError(t, err)
+
+	repo := NewTaskRepositoryImpl(gormDB)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "task" WHERE "task"."id" = $1 AND ((project_id = 0 AND owner_id = $2) OR project_id IN (SELECT project_id FROM project_member WHERE user_id = $3)) AND "task"."deleted_at" IS NULL`)).
+		WithArgs(1, 7, 7, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "description", "status", "created_at", "updated_at", "deleted_at"}).
+			AddRow(1, "Task 1", "Description 1", "done", time.Now(), time.Now(), nil))
+	expectNoTags(mock)
+
+	task, err := repo.GetTask(ownerCtx, 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), task.ID)
+	assert.Equal(t, StatusDone, task.Status)
+}
+
+func TestGetTaskMockWhenNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+	assert.NoError(t, err)
+
+	repo := NewTaskRepositoryImpl(gormDB)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "task" WHERE "task"."id" = $1 AND ((project_id = 0 AND owner_id = $2) OR project_id IN (SELECT project_id FROM project_member WHERE user_id = $3)) AND "task"."deleted_at" IS NULL`)).
+		WithArgs(2, 7, 7, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "description", "status", "created_at", "updated_at", "deleted_at"}))
+
+	task, err := repo.GetTask(ownerCtx, 2)
+
+	assert.ErrorIs(t, err, ErrTaskNotFound)
+	assert.Nil(t, task)
+}
+
 func TestGetAllTasksMock(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	assert.NoError(t, err)
@@ -86,19 +275,23 @@ func TestGetAllTasksMock(t *testing.T) {
 		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "description", "created_at", "updated_at", "deleted_at"}).
 			AddRow(1, "Task 1", "Description 1", time.Now(), time.Now(), nil).
 			AddRow(2, "Task 2", "Description 2", time.Now(), time.Now(), nil))
+	expectNoTags(mock)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "task" WHERE ((project_id = 0 AND owner_id = $1) OR project_id IN (SELECT project_id FROM project_member WHERE user_id = $2)) AND "task"."deleted_at" IS NULL`)).
+		WithArgs(7, 7).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
 
 	pagination := pagination.PaginationRequest{
 		Page:     1,
 		PageSize: 10,
-		Order:    "title",
-		SortBy:   "asc",
+		Sort:     []pagination.SortField{{Field: "title"}},
 	}
 	request := GetAllTaskRequest{
 		PaginationRequest: &pagination,
 	}
-	gotTasks, err := repo.GetAllTasks(context.Background(), request)
+	gotTasks, total, err := repo.GetAllTasks(ownerCtx, request)
 
 	assert.NoError(t, err)
+	assert.Equal(t, int64(2), total)
 
 	for i, task := range gotTasks {
 		expectedTask := expectedTasks[i]
@@ -108,6 +301,113 @@ func TestGetAllTasksMock(t *testing.T) {
 	}
 }
 
+func TestGetAllTasksMockWithDueFilter(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+	assert.NoError(t, err)
+
+	repo := NewTaskRepositoryImpl(gormDB)
+
+	dueFrom := time.Now().UTC().Truncate(time.Microsecond)
+	dueTo := dueFrom.AddDate(0, 0, 7)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "task" WHERE due_at >= $1 AND due_at < $2 AND status NOT IN ($3,$4) AND ((project_id = 0 AND owner_id = $5) OR project_id IN (SELECT project_id FROM project_member WHERE user_id = $6)) AND "task"."deleted_at" IS NULL`)).
+		WithArgs(dueFrom, dueTo, StatusDone, StatusCancelled, 7, 7, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "description", "due_at", "created_at", "updated_at", "deleted_at"}).
+			AddRow(1, "Task 1", "Description 1", dueFrom, time.Now(), time.Now(), nil))
+	expectNoTags(mock)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "task" WHERE due_at >= $1 AND due_at < $2 AND status NOT IN ($3,$4) AND ((project_id = 0 AND owner_id = $5) OR project_id IN (SELECT project_id FROM project_member WHERE user_id = $6)) AND "task"."deleted_at" IS NULL`)).
+		WithArgs(dueFrom, dueTo, StatusDone, StatusCancelled, 7, 7).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	request := GetAllTaskRequest{
+		PaginationRequest: &pagination.PaginationRequest{Page: 1, PageSize: 10},
+		DueFrom:           &dueFrom,
+		DueTo:             &dueTo,
+		OpenOnly:          true,
+	}
+	gotTasks, _, err := repo.GetAllTasks(ownerCtx, request)
+
+	assert.NoError(t, err)
+	assert.Len(t, gotTasks, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetAllTasksMockInProject(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+	assert.NoError(t, err)
+
+	repo := NewTaskRepositoryImpl(gormDB)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "task" WHERE project_id = $1 AND ((project_id = 0 AND owner_id = $2) OR project_id IN (SELECT project_id FROM project_member WHERE user_id = $3)) AND "task"."deleted_at" IS NULL`)).
+		WithArgs(5, 7, 7, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "project_id"}).AddRow(1, "Task 1", 5))
+	expectNoTags(mock)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "task" WHERE project_id = $1 AND ((project_id = 0 AND owner_id = $2) OR project_id IN (SELECT project_id FROM project_member WHERE user_id = $3)) AND "task"."deleted_at" IS NULL`)).
+		WithArgs(5, 7, 7).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	request := GetAllTaskRequest{PaginationRequest: &pagination.PaginationRequest{Page: 1, PageSize: 10}, ProjectID: 5}
+	gotTasks, total, err := repo.GetAllTasks(ownerCtx, request)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Task{{ID: 1, Title: "Task 1", ProjectID: 5, Tags: []Tag{}}}, gotTasks)
+	assert.Equal(t, int64(1), total)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetAllTasksMockWithTagFilters(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+	assert.NoError(t, err)
+
+	repo := NewTaskRepositoryImpl(gormDB)
+
+	where := `WHERE id IN (SELECT task_tag.task_id FROM "task_tag" JOIN tag ON tag.id = task_tag.tag_id WHERE tag.name IN ($1,$2)) ` +
+		`AND id IN (SELECT task_tag.task_id FROM "task_tag" JOIN tag ON tag.id = task_tag.tag_id WHERE tag.name IN ($3,$4) GROUP BY "task_tag"."task_id" HAVING COUNT(*) = $5) ` +
+		`AND id NOT IN (SELECT task_tag.task_id FROM "task_tag" JOIN tag ON tag.id = task_tag.tag_id WHERE tag.name IN ($6)) ` +
+		`AND ((project_id = 0 AND owner_id = $7) OR project_id IN (SELECT project_id FROM project_member WHERE user_id = $8)) AND "task"."deleted_at" IS NULL`
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "task" `+where)).
+		WithArgs("home", "work", "home", "urgent", 2, "someday", 7, 7, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title"}).AddRow(1, "Task 1"))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "task_tag" WHERE "task_tag"."task_id" = $1`)).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"task_id", "tag_id"}).AddRow(1, 3).AddRow(1, 4))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "tag" WHERE "tag"."id" IN ($1,$2) ORDER BY name`)).
+		WithArgs(3, 4).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(3, "home").AddRow(4, "urgent"))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "task" ` + where)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	request := GetAllTaskRequest{
+		PaginationRequest: &pagination.PaginationRequest{Page: 1, PageSize: 10},
+		TagsAny:           []string{"home", "work"},
+		TagsAll:           []string{"home", "urgent"},
+		TagsNone:          []string{"someday"},
+	}
+	gotTasks, _, err := repo.GetAllTasks(ownerCtx, request)
+
+	assert.NoError(t, err)
+	assert.Len(t, gotTasks, 1)
+	assert.Equal(t, []string{"home", "urgent"}, gotTasks[0].TagNames())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestGetAllTasksMockWhenError(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	assert.NoError(t, err)
@@ -128,13 +428,12 @@ func TestGetAllTasksMockWhenError(t *testing.T) {
 	pagination := pagination.PaginationRequest{
 		Page:     1,
 		PageSize: 10,
-		Order:    "title",
-		SortBy:   "asc",
+		Sort:     []pagination.SortField{{Field: "title"}},
 	}
 	request := GetAllTaskRequest{
 		PaginationRequest: &pagination,
 	}
-	_, err = repo.GetAllTasks(context.Background(), request)
+	_, _, err = repo.GetAllTasks(ownerCtx, request)
 
 	assert.Error(t, err)
 }
@@ -152,16 +451,39 @@ func TestDeleteTaskMock(t *testing.T) {
 	repo := NewTaskRepositoryImpl(gormDB)
 
 	mock.ExpectBegin()
-	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "task" SET "deleted_at"=$1 WHERE "task"."id" = $2 AND "task"."deleted_at" IS NULL`)).
-		WithArgs(sqlmock.AnyArg(), 1).
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "task" SET "deleted_at"=$1 WHERE "task"."id" = $2 AND ((project_id = 0 AND owner_id = $3) OR project_id IN (SELECT project_id FROM project_member WHERE user_id = $4)) AND "task"."deleted_at" IS NULL`)).
+		WithArgs(sqlmock.AnyArg(), 1, 7, 7).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
 
-	err = repo.DeleteTask(context.Background(), 1)
+	err = repo.DeleteTask(ownerCtx, 1)
 
 	assert.NoError(t, err)
 }
 
+func TestDeleteTaskMockWhenNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+	assert.NoError(t, err)
+
+	repo := NewTaskRepositoryImpl(gormDB)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "task" SET "deleted_at"=$1 WHERE "task"."id" = $2 AND ((project_id = 0 AND owner_id = $3) OR project_id IN (SELECT project_id FROM project_member WHERE user_id = $4)) AND "task"."deleted_at" IS NULL`)).
+		WithArgs(sqlmock.AnyArg(), 2, 7, 7).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	err = repo.DeleteTask(ownerCtx, 2)
+
+	assert.ErrorIs(t, err, ErrTaskNotFound)
+}
+
 func TestDeleteTaskMockWhenError(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	assert.NoError(t, err)
@@ -181,7 +503,347 @@ func TestDeleteTaskMockWhenError(t *testing.T) {
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
 
-	err = repo.DeleteTask(context.Background(), 1)
+	err = repo.DeleteTask(ownerCtx, 1)
 
 	assert.Error(t, err)
 }
+
+func TestGetAllTasksMockWithSort(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+	assert.NoError(t, err)
+
+	repo := NewTaskRepositoryImpl(gormDB)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "task" WHERE ((project_id = 0 AND owner_id = $1) OR project_id IN (SELECT project_id FROM project_member WHERE user_id = $2)) AND "task"."deleted_at" IS NULL ORDER BY "due_at" DESC NULLS LAST,"title" COLLATE "C","id" LIMIT $3`)).
+		WithArgs(7, 7, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title"}).AddRow(1, "Task 1"))
+	expectNoTags(mock)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "task"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	request := GetAllTaskRequest{
+		PaginationRequest: &pagination.PaginationRequest{
+			Page:     1,
+			PageSize: 10,
+			Sort:     []pagination.SortField{{Field: "dueAt", Desc: true}, {Field: "title"}},
+		},
+	}
+	_, _, err = repo.GetAllTasks(ownerCtx, request)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetAllTasksMockWithDefaultSort(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+	assert.NoError(t, err)
+
+	repo := NewTaskRepositoryImpl(gormDB)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`ORDER BY "updated_at" DESC,"id" DESC LIMIT $3`)).
+		WithArgs(7, 7, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title"}))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "task"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	request := GetAllTaskRequest{
+		PaginationRequest: &pagination.PaginationRequest{Page: 1, PageSize: 10},
+	}
+	_, _, err = repo.GetAllTasks(ownerCtx, request)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetAllTasksMockWhenSortFieldUnknown(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+	assert.NoError(t, err)
+
+	repo := NewTaskRepositoryImpl(gormDB)
+
+	request := GetAllTaskRequest{
+		PaginationRequest: &pagination.PaginationRequest{
+			Page:     1,
+			PageSize: 10,
+			Sort:     []pagination.SortField{{Field: "title; DROP TABLE task"}},
+		},
+	}
+	_, _, err = repo.GetAllTasks(ownerCtx, request)
+
+	// the service rejects such a sort first; should one get here, it still never reaches the SQL
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSortColumnsExistInSchema(t *testing.T) {
+	s, err := schema.Parse(&Task{}, &sync.Map{}, schema.NamingStrategy{})
+	assert.NoError(t, err)
+
+	for field, column := range sortColumns {
+		assert.NotNil(t, s.LookUpField(column), field)
+	}
+}
+
+func TestGetAllTasksMockWithCursor(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+	assert.NoError(t, err)
+
+	repo := NewTaskRepositoryImpl(gormDB)
+
+	updatedAt := time.Date(2024, time.May, 15, 13, 30, 0, 0, time.UTC)
+	after := Task{ID: 7, UpdatedAt: updatedAt}.Cursor(pagination.SortField{Field: "updatedAt", Desc: true})
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "task" WHERE ((project_id = 0 AND owner_id = $1) OR project_id IN (SELECT project_id FROM project_member WHERE user_id = $2)) AND ("updated_at", id) < ($3, $4) AND "task"."deleted_at" IS NULL ORDER BY "updated_at" DESC,"id" DESC LIMIT $5`)).
+		WithArgs(7, 7, updatedAt, 7, 11).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title"}).AddRow(6, "Task 6"))
+	expectNoTags(mock)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "task"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(7))
+
+	request := GetAllTaskRequest{
+		PaginationRequest: &pagination.PaginationRequest{
+			PageSize:   10,
+			Sort:       []pagination.SortField{{Field: "updatedAt", Desc: true}},
+			CursorMode: true,
+			After:      &after,
+		},
+	}
+	gotTasks, total, err := repo.GetAllTasks(ownerCtx, request)
+
+	assert.NoError(t, err)
+	assert.Len(t, gotTasks, 1)
+	assert.Equal(t, int64(7), total)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetAllTasksMockWhenCursorTampered(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+	assert.NoError(t, err)
+
+	repo := NewTaskRepositoryImpl(gormDB)
+
+	request := GetAllTaskRequest{
+		PaginationRequest: &pagination.PaginationRequest{
+			PageSize:   10,
+			Sort:       []pagination.SortField{{Field: "updatedAt"}},
+			CursorMode: true,
+			After:      &pagination.Cursor{Sort: pagination.SortField{Field: "updatedAt"}, Key: "yesterday", ID: 7},
+		},
+	}
+	_, _, err = repo.GetAllTasks(ownerCtx, request)
+
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetTrashedTasksMock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+	assert.NoError(t, err)
+
+	repo := NewTaskRepositoryImpl(gormDB)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "task" WHERE ((project_id = 0 AND owner_id = $1) OR project_id IN (SELECT project_id FROM project_member WHERE user_id = $2)) AND deleted_at IS NOT NULL ORDER BY "deleted_at" DESC NULLS LAST,"id" DESC LIMIT $3`)).
+		WithArgs(7, 7, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "deleted_at"}).AddRow(1, "Task 1", time.Now()))
+	expectNoTags(mock)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "task" WHERE ((project_id = 0 AND owner_id = $1) OR project_id IN (SELECT project_id FROM project_member WHERE user_id = $2)) AND deleted_at IS NOT NULL`)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	request := GetAllTaskRequest{
+		PaginationRequest: &pagination.PaginationRequest{Page: 1, PageSize: 10, Sort: DefaultTrashSort},
+	}
+	gotTasks, total, err := repo.GetTrashedTasks(ownerCtx, request)
+
+	assert.NoError(t, err)
+	assert.Len(t, gotTasks, 1)
+	assert.True(t, gotTasks[0].DeletedAt.Valid)
+	assert.Equal(t, int64(1), total)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRestoreTaskMock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+	assert.NoError(t, err)
+
+	repo := NewTaskRepositoryImpl(gormDB)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "task" SET "deleted_at"=$1,"version"=version + 1,"updated_at"=$2 WHERE (id = $3 AND deleted_at IS NOT NULL) AND ((project_id = 0 AND owner_id = $4) OR project_id IN (SELECT project_id FROM project_member WHERE user_id = $5))`)).
+		WithArgs(nil, sqlmock.AnyArg(), 1, 7, 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = repo.RestoreTask(ownerCtx, 1)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRestoreTaskMockWhenNotTrashed(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+	assert.NoError(t, err)
+
+	repo := NewTaskRepositoryImpl(gormDB)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "task" SET`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "task" WHERE id = $1 AND ((project_id = 0 AND owner_id = $2) OR project_id IN (SELECT project_id FROM project_member WHERE user_id = $3)) AND "task"."deleted_at" IS NULL`)).
+		WithArgs(1, 7, 7).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	err = repo.RestoreTask(ownerCtx, 1)
+
+	assert.ErrorIs(t, err, ErrTaskNotTrashed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurgeTaskMock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+	assert.NoError(t, err)
+
+	repo := NewTaskRepositoryImpl(gormDB)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "task" WHERE "task"."id" = $1 AND ((project_id = 0 AND owner_id = $2) OR project_id IN (SELECT project_id FROM project_member WHERE user_id = $3))`)).
+		WithArgs(1, 7, 7).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	err = repo.PurgeTask(ownerCtx, 1)
+
+	assert.ErrorIs(t, err, ErrTaskNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurgeTaskMockRemovesTags(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+	assert.NoError(t, err)
+
+	repo := NewTaskRepositoryImpl(gormDB)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "task" WHERE "task"."id" = $1`)).
+		WithArgs(1, 7, 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "task_tag" WHERE task_id = $1`)).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repo.PurgeTask(ownerCtx, 1))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurgeTrashMock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+	assert.NoError(t, err)
+
+	repo := NewTaskRepositoryImpl(gormDB)
+
+	cutoff := time.Now().UTC().Truncate(time.Microsecond).AddDate(0, 0, -30)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "task_tag" WHERE task_id IN (SELECT "id" FROM "task" WHERE deleted_at IS NOT NULL AND deleted_at < $1)`)).
+		WithArgs(cutoff).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "task" WHERE deleted_at IS NOT NULL AND deleted_at < $1`)).
+		WithArgs(cutoff).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectCommit()
+
+	purged, err := repo.PurgeTrash(ownerCtx, cutoff)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), purged)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCountTasksByStatusMock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+	assert.NoError(t, err)
+
+	repo := NewTaskRepositoryImpl(gormDB)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT status, COUNT(*) AS count FROM "task" WHERE "task"."deleted_at" IS NULL GROUP BY "status"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"status", "count"}).
+			AddRow("todo", 3).
+			AddRow("done", 5))
+
+	counts, err := repo.CountTasksByStatus(ownerCtx)
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[Status]int64{StatusTodo: 3, StatusDone: 5}, counts)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -1,62 +1,355 @@
 package task
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mkmgo-todo/todo/auth"
+	"mkmgo-todo/todo/pagination"
+	"mkmgo-todo/todo/patch"
+	"slices"
+	"time"
 )
 
 type TaskRepository interface {
 	SaveTask(ctx context.Context, task *Task) error
-	GetAllTasks(ctx context.Context, request GetAllTaskRequest) ([]Task, error)
+	UpdateTask(ctx context.Context, task *Task) error
+	GetTask(ctx context.Context, id uint64) (*Task, error)
+	GetTaskIncludingTrash(ctx context.Context, id uint64) (*Task, error)
+	GetAllTasks(ctx context.Context, request GetAllTaskRequest) ([]Task, int64, error)
 	DeleteTask(ctx context.Context, id uint64) error
+	GetTrashedTasks(ctx context.Context, request GetAllTaskRequest) ([]Task, int64, error)
+	RestoreTask(ctx context.Context, id uint64) error
+	PurgeTask(ctx context.Context, id uint64) error
+	PurgeTrash(ctx context.Context, deletedBefore time.Time) (int64, error)
 }
 
+// ProjectRoles tells what the authenticated user may do in a project.
+type ProjectRoles interface {
+	// GetRole fails with an error of kind ErrNotFound when the user is no member of the project.
+	GetRole(ctx context.Context, projectID uint64) (auth.Role, error)
+}
+
+// TaskServiceImpl checks every operation against the project the task belongs to: viewers may
+// read its tasks, editors and owners may also change them. Tasks outside any project are their
+// owner's alone, which the repository already ensures by never returning them to anyone else.
 type TaskServiceImpl struct {
-	repo TaskRepository
+	repo  TaskRepository
+	roles ProjectRoles
 }
 
-func NewTaskServiceImpl(repo TaskRepository) *TaskServiceImpl {
-	return &TaskServiceImpl{repo: repo}
+func NewTaskServiceImpl(repo TaskRepository, roles ProjectRoles) *TaskServiceImpl {
+	return &TaskServiceImpl{repo: repo, roles: roles}
 }
 
+// SaveTask creates a task, or fully replaces an existing one when request.ID is set.
 func (svc *TaskServiceImpl) SaveTask(ctx context.Context, request *WriteTaskRequest) (*GetTaskResponse, error) {
+	if err := Validate(request); err != nil {
+		return nil, err
+	}
+	if request.ID != 0 {
+		existing, err := svc.repo.GetTask(ctx, request.ID)
+		if err != nil {
+			return nil, err
+		}
+		return svc.replaceTask(ctx, existing, request)
+	}
+	if err := authorize(ctx, svc.roles, request.ProjectID, auth.RoleEditor); err != nil {
+		return nil, err
+	}
 	task := Task{
 		Title:       request.Title,
 		Description: request.Description,
-	}
-	if request.ID != 0 {
-		task.ID = request.ID
+		Status:      StatusTodo,
+		StartAt:     request.StartAt,
+		DueAt:       request.DueAt,
+		ProjectID:   request.ProjectID,
+		Tags:        newTags(request.Tags),
 	}
 	if err := svc.repo.SaveTask(ctx, &task); err != nil {
 		return nil, err
 	}
-	return &GetTaskResponse{
-		ID:          task.ID,
-		Title:       task.Title,
-		Description: task.Description,
-		UpdatedAt:   task.FormattedUpdatedAt(),
-	}, nil
+	response := task.ToResponse()
+	return &response, nil
 }
 
-func (svc *TaskServiceImpl) GetAllTasks(ctx context.Context, request GetAllTaskRequest) ([]GetTaskResponse, error) {
-	tasks, err := svc.repo.GetAllTasks(ctx, request)
+// PatchTask applies a JSON (Merge) Patch to the writable fields of an existing task.
+func (svc *TaskServiceImpl) PatchTask(ctx context.Context, request *PatchTaskRequest) (*GetTaskResponse, error) {
+	existing, err := svc.repo.GetTask(ctx, request.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !existing.MatchesETag(request.IfMatch) {
+		return nil, ErrTaskVersionMismatch
+	}
+	doc, err := json.Marshal(existing.ToWriteRequest())
+	if err != nil {
+		return nil, err
+	}
+	patched, err := request.Patch.Apply(doc)
+	if errors.Is(err, patch.ErrTestFailed) {
+		return nil, fmt.Errorf("%w: %v", ErrPatchTestFailed, err)
+	}
 	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	var write WriteTaskRequest
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&write); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	if write.ID != existing.ID {
+		return nil, fmt.Errorf("%w: id cannot be changed", ErrInvalidPatch)
+	}
+	if err := Validate(&write); err != nil {
+		return nil, err
+	}
+	return svc.replaceTask(ctx, existing, &write)
+}
+
+// replaceTask overwrites the writable fields of task, keeping its identity, status and timestamps.
+// A different projectId moves the task, which takes the editor role in both projects; a task moved
+// out of every project becomes the mover's own. The request must already be validated.
+func (svc *TaskServiceImpl) replaceTask(ctx context.Context, task *Task, request *WriteTaskRequest) (*GetTaskResponse, error) {
+	if err := authorize(ctx, svc.roles, task.ProjectID, auth.RoleEditor); err != nil {
 		return nil, err
 	}
+	if !task.MatchesETag(request.IfMatch) {
+		return nil, ErrTaskVersionMismatch
+	}
+	if request.ProjectID != task.ProjectID {
+		if err := authorize(ctx, svc.roles, request.ProjectID, auth.RoleEditor); err != nil {
+			return nil, err
+		}
+		if request.ProjectID == 0 {
+			userID, err := currentUser(ctx)
+			if err != nil {
+				return nil, err
+			}
+			task.OwnerID = userID
+		}
+		task.ProjectID = request.ProjectID
+	}
+	task.Title = request.Title
+	task.Description = request.Description
+	task.StartAt = request.StartAt
+	task.DueAt = request.DueAt
+	task.Tags = newTags(request.Tags)
+	if err := svc.repo.UpdateTask(ctx, task); err != nil {
+		return nil, err
+	}
+	response := task.ToResponse()
+	return &response, nil
+}
+
+func (svc *TaskServiceImpl) GetTask(ctx context.Context, id uint64) (*GetTaskResponse, error) {
+	task, err := svc.repo.GetTask(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	response := task.ToResponse()
+	return &response, nil
+}
+
+func (svc *TaskServiceImpl) GetAllTasks(ctx context.Context, request GetAllTaskRequest) (*pagination.PaginationResponse[GetTaskResponse], error) {
+	if err := authorize(ctx, svc.roles, request.ProjectID, auth.RoleViewer); err != nil {
+		return nil, err
+	}
+	sortable := SortableFields()
+	if request.PaginationRequest.CursorMode {
+		sortable = KeysetSortableFields()
+	}
+	if err := checkPage(request.PaginationRequest, sortable); err != nil {
+		return nil, err
+	}
+	tasks, total, err := svc.repo.GetAllTasks(ctx, resolveFilters(request))
+	if err != nil {
+		return nil, err
+	}
+	hasNext := false
+	if request.PaginationRequest.CursorMode && len(tasks) > request.PaginationRequest.PageSize {
+		tasks, hasNext = tasks[:request.PaginationRequest.PageSize], true
+	}
+	responses := toResponses(tasks)
+	if !request.PaginationRequest.CursorMode {
+		return pagination.NewPaginationResponse(*request.PaginationRequest, responses, total), nil
+	}
+	var next pagination.Cursor
+	if hasNext {
+		next = tasks[len(tasks)-1].Cursor(request.PaginationRequest.Sort[0])
+	}
+	return pagination.NewCursorResponse(*request.PaginationRequest, responses, total, hasNext, next), nil
+}
+
+// GetTrash lists deleted tasks, filtered like GetAllTasks. The trash is paged by offset only.
+func (svc *TaskServiceImpl) GetTrash(ctx context.Context, request GetAllTaskRequest) (*pagination.PaginationResponse[GetTaskResponse], error) {
+	if err := authorize(ctx, svc.roles, request.ProjectID, auth.RoleViewer); err != nil {
+		return nil, err
+	}
+	if err := checkPage(request.PaginationRequest, TrashSortableFields()); err != nil {
+		return nil, err
+	}
+	tasks, total, err := svc.repo.GetTrashedTasks(ctx, resolveFilters(request))
+	if err != nil {
+		return nil, err
+	}
+	return pagination.NewPaginationResponse(*request.PaginationRequest, toResponses(tasks), total), nil
+}
+
+// checkPage rejects a sort field or cursor the listing cannot be queried by, so that the repository
+// only ever builds queries from valid input.
+func checkPage(request *pagination.PaginationRequest, sortable []string) error {
+	var fields []FieldError
+	for _, field := range request.Sort {
+		if !slices.Contains(sortable, field.Field) {
+			fields = append(fields, FieldError{Field: "sort", Message: fmt.Sprintf("unknown field %q", field.Field)})
+		}
+	}
+	if request.After != nil && !ValidCursor(*request.After) {
+		fields = append(fields, FieldError{Field: "cursor", Message: "is not a valid cursor"})
+	}
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+	return nil
+}
+
+// resolveFilters narrows the explicit due range by the preset filter, if any, and spells tag names
+// the way they are stored.
+func resolveFilters(request GetAllTaskRequest) GetAllTaskRequest {
+	request.TagsAny = normalizeTags(request.TagsAny)
+	request.TagsAll = normalizeTags(request.TagsAll)
+	request.TagsNone = normalizeTags(request.TagsNone)
+	if request.Due != "" {
+		from, to := request.Due.Range(time.Now())
+		request.DueFrom = latest(request.DueFrom, from)
+		request.DueTo = earliest(request.DueTo, to)
+		request.OpenOnly = request.OpenOnly || request.Due == DueOverdue
+	}
+	return request
+}
+
+func toResponses(tasks []Task) []GetTaskResponse {
 	responses := make([]GetTaskResponse, len(tasks))
 	for i, task := range tasks {
-		responses[i] = GetTaskResponse{
-			ID:          task.ID,
-			Title:       task.Title,
-			Description: task.Description,
-			UpdatedAt:   task.FormattedUpdatedAt(),
-		}
+		responses[i] = task.ToResponse()
+	}
+	return responses
+}
+
+func (svc *TaskServiceImpl) UpdateTaskStatus(ctx context.Context, request *UpdateTaskStatusRequest) (*GetTaskResponse, error) {
+	if err := Validate(request); err != nil {
+		return nil, err
+	}
+	task, err := svc.repo.GetTask(ctx, request.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorize(ctx, svc.roles, task.ProjectID, auth.RoleEditor); err != nil {
+		return nil, err
+	}
+	if !task.MatchesETag(request.IfMatch) {
+		return nil, ErrTaskVersionMismatch
+	}
+	if !task.Status.CanTransitionTo(request.Status) {
+		return nil, fmt.Errorf("%w: from %s to %s", ErrInvalidStatusTransition, task.Status, request.Status)
+	}
+	task.Status = request.Status
+	if task.Status == StatusDone {
+		completedAt := time.Now()
+		task.CompletedAt = &completedAt
+	} else {
+		task.CompletedAt = nil
+	}
+	tags := task.Tags
+	task.Tags = nil // leaves the tags alone
+	if err := svc.repo.UpdateTask(ctx, task); err != nil {
+		return nil, err
 	}
-	return responses, nil
+	task.Tags = tags
+	response := task.ToResponse()
+	return &response, nil
+}
+
+func (svc *TaskServiceImpl) CompleteTask(ctx context.Context, id uint64) (*GetTaskResponse, error) {
+	return svc.UpdateTaskStatus(ctx, &UpdateTaskStatusRequest{ID: id, Status: StatusDone})
+}
+
+func (svc *TaskServiceImpl) ReopenTask(ctx context.Context, id uint64) (*GetTaskResponse, error) {
+	return svc.UpdateTaskStatus(ctx, &UpdateTaskStatusRequest{ID: id, Status: StatusTodo})
 }
 
 func (svc *TaskServiceImpl) DeleteTask(ctx context.Context, id uint64) error {
+	task, err := svc.repo.GetTask(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := authorize(ctx, svc.roles, task.ProjectID, auth.RoleEditor); err != nil {
+		return err
+	}
 	if err := svc.repo.DeleteTask(ctx, id); err != nil {
 		return err
 	}
 	return nil
 }
+
+// RestoreTask moves a task out of the trash.
+func (svc *TaskServiceImpl) RestoreTask(ctx context.Context, id uint64) (*GetTaskResponse, error) {
+	task, err := svc.repo.GetTaskIncludingTrash(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorize(ctx, svc.roles, task.ProjectID, auth.RoleEditor); err != nil {
+		return nil, err
+	}
+	if err := svc.repo.RestoreTask(ctx, id); err != nil {
+		return nil, err
+	}
+	return svc.GetTask(ctx, id)
+}
+
+// PurgeTask removes a task for good, whether or not it is in the trash.
+func (svc *TaskServiceImpl) PurgeTask(ctx context.Context, id uint64) error {
+	task, err := svc.repo.GetTaskIncludingTrash(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := authorize(ctx, svc.roles, task.ProjectID, auth.RoleEditor); err != nil {
+		return err
+	}
+	return svc.repo.PurgeTask(ctx, id)
+}
+
+// authorize checks that the authenticated user holds at least the required role in the project.
+// Tasks and tags outside any project need no role.
+func authorize(ctx context.Context, roles ProjectRoles, projectID uint64, required auth.Role) error {
+	if projectID == 0 {
+		return nil
+	}
+	role, err := roles.GetRole(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	if !role.Allows(required) {
+		return fmt.Errorf("%w: needs the %s role, not %s", ErrTaskForbidden, required, role)
+	}
+	return nil
+}
+
+func latest(a, b *time.Time) *time.Time {
+	if a == nil || (b != nil && b.After(*a)) {
+		return b
+	}
+	return a
+}
+
+func earliest(a, b *time.Time) *time.Time {
+	if a == nil || (b != nil && b.Before(*a)) {
+		return b
+	}
+	return a
+}
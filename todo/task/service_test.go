@@ -2,9 +2,12 @@ package task
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"mkmgo-todo/todo/auth"
 	"mkmgo-todo/todo/pagination"
+	"mkmgo-todo/todo/patch"
 	"testing"
 	"time"
 
@@ -17,8 +20,16 @@ import (
 
 type MockTaskRepository struct {
 	SaveTaskFunc    func(ctx context.Context, task *Task) error
-	GetAllTasksFunc func(ctx context.Context, request GetAllTaskRequest) ([]Task, error)
+	UpdateTaskFunc  func(ctx context.Context, task *Task) error
+	GetTaskFunc     func(ctx context.Context, id uint64) (*Task, error)
+	GetAllTasksFunc func(ctx context.Context, request GetAllTaskRequest) ([]Task, int64, error)
 	DeleteTaskFunc  func(ctx context.Context, id uint64) error
+
+	GetTaskIncludingTrashFunc func(ctx context.Context, id uint64) (*Task, error)
+	GetTrashedTasksFunc       func(ctx context.Context, request GetAllTaskRequest) ([]Task, int64, error)
+	RestoreTaskFunc           func(ctx context.Context, id uint64) error
+	PurgeTaskFunc             func(ctx context.Context, id uint64) error
+	PurgeTrashFunc            func(ctx context.Context, deletedBefore time.Time) (int64, error)
 }
 
 func (m *MockTaskRepository) SaveTask(ctx context.Context, task *Task) error {
@@ -28,11 +39,32 @@ func (m *MockTaskRepository) SaveTask(ctx context.Context, task *Task) error {
 	return nil
 }
 
-func (m *MockTaskRepository) GetAllTasks(ctx context.Context, request GetAllTaskRequest) ([]Task, error) {
+func (m *MockTaskRepository) UpdateTask(ctx context.Context, task *Task) error {
+	if m.UpdateTaskFunc != nil {
+		return m.UpdateTaskFunc(ctx, task)
+	}
+	return nil
+}
+
+func (m *MockTaskRepository) GetTask(ctx context.Context, id uint64) (*Task, error) {
+	if m.GetTaskFunc != nil {
+		return m.GetTaskFunc(ctx, id)
+	}
+	return &Task{ID: id, Status: StatusTodo}, nil
+}
+
+func (m *MockTaskRepository) GetTaskIncludingTrash(ctx context.Context, id uint64) (*Task, error) {
+	if m.GetTaskIncludingTrashFunc != nil {
+		return m.GetTaskIncludingTrashFunc(ctx, id)
+	}
+	return &Task{ID: id, Status: StatusTodo}, nil
+}
+
+func (m *MockTaskRepository) GetAllTasks(ctx context.Context, request GetAllTaskRequest) ([]Task, int64, error) {
 	if m.GetAllTasksFunc != nil {
 		return m.GetAllTasksFunc(ctx, request)
 	}
-	return []Task{}, nil
+	return []Task{}, 0, nil
 }
 
 func (m *MockTaskRepository) DeleteTask(ctx context.Context, id uint64) error {
@@ -42,6 +74,58 @@ func (m *MockTaskRepository) DeleteTask(ctx context.Context, id uint64) error {
 	return nil
 }
 
+func (m *MockTaskRepository) GetTrashedTasks(ctx context.Context, request GetAllTaskRequest) ([]Task, int64, error) {
+	if m.GetTrashedTasksFunc != nil {
+		return m.GetTrashedTasksFunc(ctx, request)
+	}
+	return []Task{}, 0, nil
+}
+
+func (m *MockTaskRepository) RestoreTask(ctx context.Context, id uint64) error {
+	if m.RestoreTaskFunc != nil {
+		return m.RestoreTaskFunc(ctx, id)
+	}
+	return nil
+}
+
+func (m *MockTaskRepository) PurgeTask(ctx context.Context, id uint64) error {
+	if m.PurgeTaskFunc != nil {
+		return m.PurgeTaskFunc(ctx, id)
+	}
+	return nil
+}
+
+func (m *MockTaskRepository) PurgeTrash(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	if m.PurgeTrashFunc != nil {
+		return m.PurgeTrashFunc(ctx, deletedBefore)
+	}
+	return 0, nil
+}
+
+type MockProjectRoles struct {
+	GetRoleFunc func(ctx context.Context, projectID uint64) (auth.Role, error)
+}
+
+func (m *MockProjectRoles) GetRole(ctx context.Context, projectID uint64) (auth.Role, error) {
+	if m.GetRoleFunc != nil {
+		return m.GetRoleFunc(ctx, projectID)
+	}
+	return auth.RoleOwner, nil
+}
+
+// rolesOf answers GetRole with the role the user has in each project, and not found for others.
+func rolesOf(roles map[uint64]auth.Role) *MockProjectRoles {
+	return &MockProjectRoles{
+		GetRoleFunc: func(ctx context.Context, projectID uint64) (auth.Role, error) {
+			role, ok := roles[projectID]
+			if !ok {
+				return "", NewError(ErrNotFound, "project not found")
+			}
+			return role, nil
+		},
+	}
+}
+
 /*
 	Unit test for task/service.go
 */
@@ -54,7 +138,7 @@ func TestSaveTask(t *testing.T) {
 			return nil
 		},
 	}
-	service := NewTaskServiceImpl(mockRepo)
+	service := NewTaskServiceImpl(mockRepo, &MockProjectRoles{})
 
 	req := &WriteTaskRequest{
 		Title:       "New Task",
@@ -69,6 +153,23 @@ func TestSaveTask(t *testing.T) {
 	assert.Equal(t, req.Description, resp.Description)
 }
 
+func TestSaveTaskWithTags(t *testing.T) {
+	var saved Task
+	mockRepo := &MockTaskRepository{
+		SaveTaskFunc: func(ctx context.Context, task *Task) error {
+			saved = *task
+			return nil
+		},
+	}
+	service := NewTaskServiceImpl(mockRepo, &MockProjectRoles{})
+
+	resp, err := service.SaveTask(context.Background(), &WriteTaskRequest{Title: "Dishes", Tags: []string{"Urgent", "home", "urgent"}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Tag{{Name: "home"}, {Name: "urgent"}}, saved.Tags)
+	assert.Equal(t, []string{"home", "urgent"}, resp.Tags)
+}
+
 func TestUpdateTask(t *testing.T) {
 	mockRepo := &MockTaskRepository{
 		SaveTaskFunc: func(ctx context.Context, task *Task) error {
@@ -77,7 +178,7 @@ func TestUpdateTask(t *testing.T) {
 			return nil
 		},
 	}
-	service := NewTaskServiceImpl(mockRepo)
+	service := NewTaskServiceImpl(mockRepo, &MockProjectRoles{})
 
 	req := &WriteTaskRequest{
 		ID:          1,
@@ -93,6 +194,288 @@ func TestUpdateTask(t *testing.T) {
 	assert.Equal(t, req.Description, resp.Description)
 }
 
+func TestUpdateTaskKeepsStatus(t *testing.T) {
+	completedAt := time.Now()
+	mockRepo := &MockTaskRepository{
+		GetTaskFunc: func(ctx context.Context, id uint64) (*Task, error) {
+			return &Task{ID: id, Title: "Old Task", Status: StatusDone, CompletedAt: &completedAt}, nil
+		},
+	}
+	service := NewTaskServiceImpl(mockRepo, &MockProjectRoles{})
+
+	req := &WriteTaskRequest{
+		ID:          1,
+		Title:       "New Task",
+		Description: "Task Description",
+	}
+	resp, err := service.SaveTask(context.Background(), req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, req.Title, resp.Title)
+	assert.Equal(t, StatusDone, resp.Status)
+	assert.NotEmpty(t, resp.CompletedAt)
+}
+
+func TestUpdateTaskWhenNotFound(t *testing.T) {
+	mockRepo := &MockTaskRepository{
+		GetTaskFunc: func(ctx context.Context, id uint64) (*Task, error) {
+			return nil, ErrTaskNotFound
+		},
+		SaveTaskFunc: func(ctx context.Context, task *Task) error {
+			t.Fatal("SaveTask must not be called for an unknown task")
+			return nil
+		},
+	}
+	service := NewTaskServiceImpl(mockRepo, &MockProjectRoles{})
+
+	resp, err := service.SaveTask(context.Background(), &WriteTaskRequest{ID: 2, Title: "New Task"})
+
+	assert.ErrorIs(t, err, ErrTaskNotFound)
+	assert.Nil(t, resp)
+}
+
+func TestUpdateTaskWhenFailAtRepoUpdateTask(t *testing.T) {
+	mockRepo := &MockTaskRepository{
+		UpdateTaskFunc: func(ctx context.Context, task *Task) error {
+			return ErrTaskNotFound
+		},
+	}
+	service := NewTaskServiceImpl(mockRepo, &MockProjectRoles{})
+
+	resp, err := service.SaveTask(context.Background(), &WriteTaskRequest{ID: 1, Title: "New Task"})
+
+	assert.ErrorIs(t, err, ErrTaskNotFound)
+	assert.Nil(t, resp)
+}
+
+func TestPatchTaskWithMergePatch(t *testing.T) {
+	createdAt := time.Now().AddDate(0, -1, 0)
+	dueAt := time.Now().AddDate(0, 0, 1)
+	var updated *Task
+	mockRepo := &MockTaskRepository{
+		GetTaskFunc: func(ctx context.Context, id uint64) (*Task, error) {
+			return &Task{ID: id, Title: "Old Task", Description: "Old Description", Status: StatusInProgress, DueAt: &dueAt, CreatedAt: createdAt}, nil
+		},
+		UpdateTaskFunc: func(ctx context.Context, task *Task) error {
+			updated = task
+			return nil
+		},
+	}
+	service := NewTaskServiceImpl(mockRepo, &MockProjectRoles{})
+
+	mergePatch, err := patch.NewMergePatch([]byte(`{"title":"New Task","dueAt":null}`))
+	assert.NoError(t, err)
+	resp, err := service.PatchTask(context.Background(), &PatchTaskRequest{ID: 1, Patch: mergePatch})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "New Task", resp.Title)
+	assert.Equal(t, "Old Description", resp.Description)
+	assert.Equal(t, StatusInProgress, resp.Status)
+	assert.Nil(t, updated.DueAt)
+	assert.Equal(t, createdAt, updated.CreatedAt)
+}
+
+func TestPatchTaskWithJSONPatch(t *testing.T) {
+	service := NewTaskServiceImpl(&MockTaskRepository{
+		GetTaskFunc: func(ctx context.Context, id uint64) (*Task, error) {
+			return &Task{ID: id, Title: "Old Task", Description: "Old Description"}, nil
+		},
+	}, &MockProjectRoles{})
+
+	jsonPatch, err := patch.NewJSONPatch([]byte(`[{"op":"test","path":"/title","value":"Old Task"},{"op":"add","path":"/dueAt","value":"2030-01-02T15:04:05Z"}]`))
+	assert.NoError(t, err)
+	resp, err := service.PatchTask(context.Background(), &PatchTaskRequest{ID: 1, Patch: jsonPatch})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Old Task", resp.Title)
+	assert.NotEmpty(t, resp.DueAt)
+}
+
+func TestPatchTaskTestsResponseTime(t *testing.T) {
+	local := time.Local
+	t.Cleanup(func() { time.Local = local })
+	time.Local = time.FixedZone("WIB", 7*60*60)
+	dueAt := time.Date(2030, time.January, 2, 8, 4, 5, 0, time.UTC)
+	existing := &Task{ID: 1, Title: "Old Task", DueAt: &dueAt}
+	service := NewTaskServiceImpl(&MockTaskRepository{
+		GetTaskFunc: func(ctx context.Context, id uint64) (*Task, error) {
+			return existing, nil
+		},
+	}, &MockProjectRoles{})
+
+	body, err := json.Marshal([]map[string]any{
+		{"op": "test", "path": "/dueAt", "value": existing.ToResponse().DueAt},
+		{"op": "replace", "path": "/title", "value": "New Task"},
+	})
+	assert.NoError(t, err)
+	jsonPatch, err := patch.NewJSONPatch(body)
+	assert.NoError(t, err)
+	resp, err := service.PatchTask(context.Background(), &PatchTaskRequest{ID: 1, Patch: jsonPatch})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "New Task", resp.Title)
+	assert.Equal(t, "2030-01-02T15:04:05+07:00", resp.DueAt)
+}
+
+func TestPatchTaskWhenInvalid(t *testing.T) {
+	service := NewTaskServiceImpl(&MockTaskRepository{
+		UpdateTaskFunc: func(ctx context.Context, task *Task) error {
+			t.Fatal("UpdateTask must not be called for an invalid patch")
+			return nil
+		},
+	}, &MockProjectRoles{})
+
+	for _, body := range []string{`{"id":2}`, `{"owner":"Makima"}`, `{"title":5}`, `{"dueAt":"tomorrow"}`} {
+		mergePatch, err := patch.NewMergePatch([]byte(body))
+		assert.NoError(t, err)
+
+		_, err = service.PatchTask(context.Background(), &PatchTaskRequest{ID: 1, Patch: mergePatch})
+		assert.ErrorIs(t, err, ErrInvalidPatch, body)
+	}
+
+	jsonPatch, err := patch.NewJSONPatch([]byte(`[{"op":"test","path":"/title","value":"Other Task"}]`))
+	assert.NoError(t, err)
+	_, err = service.PatchTask(context.Background(), &PatchTaskRequest{ID: 1, Patch: jsonPatch})
+	assert.ErrorIs(t, err, ErrPatchTestFailed)
+	assert.ErrorIs(t, err, ErrConflict)
+}
+
+func TestPatchTaskWhenIfMatchDiffers(t *testing.T) {
+	service := NewTaskServiceImpl(&MockTaskRepository{
+		GetTaskFunc: func(ctx context.Context, id uint64) (*Task, error) {
+			return &Task{ID: id, Title: "Old Task", Version: 4}, nil
+		},
+	}, &MockProjectRoles{})
+
+	mergePatch, err := patch.NewMergePatch([]byte(`{"title":"New Task"}`))
+	assert.NoError(t, err)
+
+	_, err = service.PatchTask(context.Background(), &PatchTaskRequest{ID: 1, Patch: mergePatch, IfMatch: `"3"`})
+	assert.ErrorIs(t, err, ErrTaskVersionMismatch)
+	assert.ErrorIs(t, err, ErrPreconditionFailed)
+
+	resp, err := service.PatchTask(context.Background(), &PatchTaskRequest{ID: 1, Patch: mergePatch, IfMatch: `"3", "4"`})
+	assert.NoError(t, err)
+	assert.Equal(t, `"4"`, resp.ETag)
+}
+
+func TestSaveTaskWhenIfMatchDiffers(t *testing.T) {
+	service := NewTaskServiceImpl(&MockTaskRepository{
+		GetTaskFunc: func(ctx context.Context, id uint64) (*Task, error) {
+			return &Task{ID: id, Title: "Old Task", Version: 2}, nil
+		},
+	}, &MockProjectRoles{})
+
+	_, err := service.SaveTask(context.Background(), &WriteTaskRequest{ID: 1, Title: "New Task", IfMatch: `"1"`})
+	assert.ErrorIs(t, err, ErrPreconditionFailed)
+
+	_, err = service.SaveTask(context.Background(), &WriteTaskRequest{ID: 1, Title: "New Task", IfMatch: "*"})
+	assert.NoError(t, err)
+}
+
+func TestDomainErrors(t *testing.T) {
+	assert.True(t, IsDomainError(fmt.Errorf("%w: id %d", ErrTaskNotFound, 1)))
+	assert.True(t, IsDomainError(ErrInvalidPatch))
+	assert.True(t, IsDomainError(&ValidationError{}))
+	assert.False(t, IsDomainError(errors.New("pq: relation \"task\" does not exist")))
+	assert.Equal(t, "task not found", ErrTaskNotFound.Error())
+}
+
+func TestPatchTaskWhenNotFound(t *testing.T) {
+	service := NewTaskServiceImpl(&MockTaskRepository{
+		GetTaskFunc: func(ctx context.Context, id uint64) (*Task, error) {
+			return nil, ErrTaskNotFound
+		},
+	}, &MockProjectRoles{})
+
+	mergePatch, err := patch.NewMergePatch([]byte(`{"title":"New Task"}`))
+	assert.NoError(t, err)
+	resp, err := service.PatchTask(context.Background(), &PatchTaskRequest{ID: 1, Patch: mergePatch})
+
+	assert.ErrorIs(t, err, ErrTaskNotFound)
+	assert.Nil(t, resp)
+}
+
+func TestSaveTaskWithDates(t *testing.T) {
+	service := NewTaskServiceImpl(&MockTaskRepository{}, &MockProjectRoles{})
+
+	startAt := time.Now()
+	dueAt := startAt.Add(24 * time.Hour)
+	resp, err := service.SaveTask(context.Background(), &WriteTaskRequest{Title: "New Task", StartAt: &startAt, DueAt: &dueAt})
+
+	assert.NoError(t, err)
+	for want, got := range map[time.Time]string{startAt: resp.StartAt, dueAt: resp.DueAt} {
+		parsed, err := time.Parse(time.RFC3339Nano, got)
+		assert.NoError(t, err)
+		assert.True(t, want.Equal(parsed), "%s round-trips to %s", got, want)
+	}
+	assert.False(t, resp.Overdue)
+}
+
+func TestFormatTimeIsRFC3339(t *testing.T) {
+	local := time.Local
+	t.Cleanup(func() { time.Local = local })
+	at := time.Date(2024, time.May, 1, 2, 0, 0, 500000000, time.UTC)
+
+	time.Local = time.FixedZone("WIB", 7*60*60)
+	assert.Equal(t, "2024-05-01T09:00:00.5+07:00", formatTime(at))
+	time.Local = time.UTC
+	assert.Equal(t, "2024-05-01T02:00:00.5Z", formatTime(at))
+}
+
+func TestSaveTaskWhenStartAfterDue(t *testing.T) {
+	service := NewTaskServiceImpl(&MockTaskRepository{}, &MockProjectRoles{})
+
+	dueAt := time.Now()
+	startAt := dueAt.Add(time.Hour)
+	resp, err := service.SaveTask(context.Background(), &WriteTaskRequest{Title: "New Task", StartAt: &startAt, DueAt: &dueAt})
+
+	var validationErr *ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	assert.ErrorIs(t, err, ErrValidation)
+	assert.Equal(t, []FieldError{{Field: "dueAt", Message: "must not be before startAt"}}, validationErr.Fields)
+	assert.Nil(t, resp)
+}
+
+func TestSaveTaskWhenInvalid(t *testing.T) {
+	mockRepo := &MockTaskRepository{
+		SaveTaskFunc: func(ctx context.Context, task *Task) error {
+			t.Fatal("SaveTask must not be called for an invalid request")
+			return nil
+		},
+		GetTaskFunc: func(ctx context.Context, id uint64) (*Task, error) {
+			t.Fatal("GetTask must not be called for an invalid request")
+			return nil, nil
+		},
+	}
+	service := NewTaskServiceImpl(mockRepo, &MockProjectRoles{})
+
+	for _, id := range []uint64{0, 1} {
+		resp, err := service.SaveTask(context.Background(), &WriteTaskRequest{ID: id, Title: " ", Description: "bell\a"})
+
+		var validationErr *ValidationError
+		assert.ErrorAs(t, err, &validationErr)
+		assert.Len(t, validationErr.Fields, 2)
+		assert.Nil(t, resp)
+	}
+}
+
+func TestPatchTaskWhenResultInvalid(t *testing.T) {
+	service := NewTaskServiceImpl(&MockTaskRepository{
+		GetTaskFunc: func(ctx context.Context, id uint64) (*Task, error) {
+			return &Task{ID: id, Title: "Old Task"}, nil
+		},
+	}, &MockProjectRoles{})
+
+	mergePatch, err := patch.NewMergePatch([]byte(`{"title":null}`))
+	assert.NoError(t, err)
+	_, err = service.PatchTask(context.Background(), &PatchTaskRequest{ID: 1, Patch: mergePatch})
+
+	var validationErr *ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []FieldError{{Field: "title", Message: "is required"}}, validationErr.Fields)
+}
+
 func TestSaveTaskWhenFailAtRepoSaveTask(t *testing.T) {
 	mockRepo := &MockTaskRepository{
 		SaveTaskFunc: func(ctx context.Context, task *Task) error {
@@ -100,7 +483,7 @@ func TestSaveTaskWhenFailAtRepoSaveTask(t *testing.T) {
 		},
 	}
 
-	service := NewTaskServiceImpl(mockRepo)
+	service := NewTaskServiceImpl(mockRepo, &MockProjectRoles{})
 
 	req := &WriteTaskRequest{
 		Title:       "New Task",
@@ -114,22 +497,51 @@ func TestSaveTaskWhenFailAtRepoSaveTask(t *testing.T) {
 	assert.Equal(t, "write task error", err.Error())
 }
 
+func TestGetTask(t *testing.T) {
+	mockRepo := &MockTaskRepository{
+		GetTaskFunc: func(ctx context.Context, id uint64) (*Task, error) {
+			return &Task{ID: id, Title: "Task 1", Status: StatusInProgress, UpdatedAt: time.Now()}, nil
+		},
+	}
+	service := NewTaskServiceImpl(mockRepo, &MockProjectRoles{})
+
+	resp, err := service.GetTask(context.Background(), 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), resp.ID)
+	assert.Equal(t, "Task 1", resp.Title)
+	assert.Equal(t, StatusInProgress, resp.Status)
+}
+
+func TestGetTaskWhenNotFound(t *testing.T) {
+	mockRepo := &MockTaskRepository{
+		GetTaskFunc: func(ctx context.Context, id uint64) (*Task, error) {
+			return nil, ErrTaskNotFound
+		},
+	}
+	service := NewTaskServiceImpl(mockRepo, &MockProjectRoles{})
+
+	resp, err := service.GetTask(context.Background(), 1)
+
+	assert.ErrorIs(t, err, ErrTaskNotFound)
+	assert.Nil(t, resp)
+}
+
 func TestGetAllTasks(t *testing.T) {
 	mockRepo := &MockTaskRepository{
-		GetAllTasksFunc: func(ctx context.Context, request GetAllTaskRequest) ([]Task, error) {
+		GetAllTasksFunc: func(ctx context.Context, request GetAllTaskRequest) ([]Task, int64, error) {
 			return []Task{
 				{ID: 1, Title: "Task 1", Description: "Description 1", UpdatedAt: time.Now()},
 				{ID: 2, Title: "Task 2", Description: "Description 2", UpdatedAt: time.Now()},
-			}, nil
+			}, 12, nil
 		},
 	}
-	service := NewTaskServiceImpl(mockRepo)
+	service := NewTaskServiceImpl(mockRepo, &MockProjectRoles{})
 
 	pagination := pagination.PaginationRequest{
 		Page:     1,
 		PageSize: 10,
-		Order:    "title",
-		SortBy:   "asc",
+		Sort:     []pagination.SortField{{Field: "title"}},
 	}
 	request := GetAllTaskRequest{
 		PaginationRequest: &pagination,
@@ -137,25 +549,155 @@ func TestGetAllTasks(t *testing.T) {
 	resp, err := service.GetAllTasks(context.Background(), request)
 
 	assert.NoError(t, err)
-	assert.Len(t, resp, 2)
-	assert.Equal(t, "Task 1", resp[0].Title)
-	assert.Equal(t, "Task 2", resp[1].Title)
+	assert.Len(t, resp.Items, 2)
+	assert.Equal(t, "Task 1", resp.Items[0].Title)
+	assert.Equal(t, "Task 2", resp.Items[1].Title)
+	assert.Equal(t, int64(12), resp.TotalItems)
+	assert.Equal(t, 2, resp.TotalPages)
+	assert.True(t, resp.HasNext)
+}
+
+func TestGetAllTasksInCursorMode(t *testing.T) {
+	mockRepo := &MockTaskRepository{
+		GetAllTasksFunc: func(ctx context.Context, request GetAllTaskRequest) ([]Task, int64, error) {
+			// one task beyond the page signals a next page
+			return []Task{{ID: 3, Title: "c"}, {ID: 2, Title: "b"}, {ID: 1, Title: "a"}}, 3, nil
+		},
+	}
+	service := NewTaskServiceImpl(mockRepo, &MockProjectRoles{})
+
+	sort := pagination.SortField{Field: "id", Desc: true}
+	request := GetAllTaskRequest{
+		PaginationRequest: &pagination.PaginationRequest{PageSize: 2, Sort: []pagination.SortField{sort}, CursorMode: true},
+	}
+	resp, err := service.GetAllTasks(context.Background(), request)
+
+	assert.NoError(t, err)
+	assert.Len(t, resp.Items, 2)
+	assert.True(t, resp.HasNext)
+	next, err := pagination.DecodeCursor(resp.NextCursor)
+	assert.NoError(t, err)
+	assert.Equal(t, pagination.Cursor{Sort: sort, Key: "2", ID: 2}, *next)
+}
+
+func TestGetAllTasksWithDueFilter(t *testing.T) {
+	var got GetAllTaskRequest
+	mockRepo := &MockTaskRepository{
+		GetAllTasksFunc: func(ctx context.Context, request GetAllTaskRequest) ([]Task, int64, error) {
+			got = request
+			return nil, 0, nil
+		},
+	}
+	service := NewTaskServiceImpl(mockRepo, &MockProjectRoles{})
+	page := &pagination.PaginationRequest{Page: 1, PageSize: 10}
+
+	_, err := service.GetAllTasks(context.Background(), GetAllTaskRequest{PaginationRequest: page, Due: DueOverdue})
+	assert.NoError(t, err)
+	assert.Nil(t, got.DueFrom)
+	assert.NotNil(t, got.DueTo)
+	assert.True(t, got.OpenOnly)
+
+	// an explicit range is narrowed, not replaced, by the preset
+	dueFrom := time.Now().AddDate(0, 0, -30)
+	dueTo := time.Now().AddDate(0, 0, 30)
+	_, err = service.GetAllTasks(context.Background(), GetAllTaskRequest{PaginationRequest: page, Due: DueToday, DueFrom: &dueFrom, DueTo: &dueTo})
+	assert.NoError(t, err)
+	assert.True(t, got.DueFrom.After(dueFrom))
+	assert.True(t, got.DueTo.Before(dueTo))
+	assert.False(t, got.OpenOnly)
+}
+
+func TestGetAllTasksWithTagFilters(t *testing.T) {
+	var got GetAllTaskRequest
+	mockRepo := &MockTaskRepository{
+		GetAllTasksFunc: func(ctx context.Context, request GetAllTaskRequest) ([]Task, int64, error) {
+			got = request
+			return nil, 0, nil
+		},
+	}
+	service := NewTaskServiceImpl(mockRepo, &MockProjectRoles{})
+	request := GetAllTaskRequest{
+		PaginationRequest: &pagination.PaginationRequest{Page: 1, PageSize: 10},
+		TagsAny:           []string{"Work", "home"},
+		TagsAll:           []string{"URGENT", "urgent"},
+	}
+
+	_, err := service.GetAllTasks(context.Background(), request)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"home", "work"}, got.TagsAny)
+	assert.Equal(t, []string{"urgent"}, got.TagsAll)
+	assert.Nil(t, got.TagsNone)
+}
+
+func TestGetAllTasksWhenPageInvalid(t *testing.T) {
+	mockRepo := &MockTaskRepository{
+		GetAllTasksFunc: func(ctx context.Context, request GetAllTaskRequest) ([]Task, int64, error) {
+			t.Fatal("an invalid page must not be queried")
+			return nil, 0, nil
+		},
+	}
+	service := NewTaskServiceImpl(mockRepo, &MockProjectRoles{})
+
+	for _, page := range []*pagination.PaginationRequest{
+		{Page: 1, PageSize: 10, Sort: []pagination.SortField{{Field: "title; DROP TABLE task"}}},
+		{Page: 1, PageSize: 10, Sort: []pagination.SortField{{Field: "deletedAt"}}},
+		{PageSize: 10, Sort: []pagination.SortField{{Field: "dueAt"}}, CursorMode: true},
+		{PageSize: 10, Sort: []pagination.SortField{{Field: "updatedAt"}}, CursorMode: true,
+			After: &pagination.Cursor{Sort: pagination.SortField{Field: "updatedAt"}, Key: "yesterday", ID: 7}},
+	} {
+		_, err := service.GetAllTasks(context.Background(), GetAllTaskRequest{PaginationRequest: page})
+
+		var validationErr *ValidationError
+		if assert.ErrorAs(t, err, &validationErr) {
+			assert.Len(t, validationErr.Fields, 1)
+		}
+	}
+}
+
+func TestDueFilterRange(t *testing.T) {
+	// Wednesday
+	now := time.Date(2024, time.May, 15, 13, 30, 0, 0, time.UTC)
+
+	from, to := DueToday.Range(now)
+	assert.Equal(t, time.Date(2024, time.May, 15, 0, 0, 0, 0, time.UTC), *from)
+	assert.Equal(t, time.Date(2024, time.May, 16, 0, 0, 0, 0, time.UTC), *to)
+
+	from, to = DueThisWeek.Range(now)
+	assert.Equal(t, time.Date(2024, time.May, 13, 0, 0, 0, 0, time.UTC), *from)
+	assert.Equal(t, time.Date(2024, time.May, 20, 0, 0, 0, 0, time.UTC), *to)
+
+	// Sunday still belongs to the week that started on Monday
+	from, _ = DueThisWeek.Range(time.Date(2024, time.May, 19, 8, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2024, time.May, 13, 0, 0, 0, 0, time.UTC), *from)
+
+	from, to = DueOverdue.Range(now)
+	assert.Nil(t, from)
+	assert.Equal(t, now, *to)
+}
+
+func TestTaskIsOverdue(t *testing.T) {
+	now := time.Now()
+	yesterday := now.AddDate(0, 0, -1)
+
+	assert.True(t, Task{Status: StatusTodo, DueAt: &yesterday}.IsOverdue(now))
+	assert.False(t, Task{Status: StatusDone, DueAt: &yesterday}.IsOverdue(now))
+	assert.False(t, Task{Status: StatusTodo}.IsOverdue(now))
 }
 
 func TestGetAllTaskFailAtRepoGetAllTask(t *testing.T) {
 	mockRepo := &MockTaskRepository{
-		GetAllTasksFunc: func(ctx context.Context, request GetAllTaskRequest) ([]Task, error) {
-			return nil, fmt.Errorf("get all task error")
+		GetAllTasksFunc: func(ctx context.Context, request GetAllTaskRequest) ([]Task, int64, error) {
+			return nil, 0, fmt.Errorf("get all task error")
 		},
 	}
 
-	service := NewTaskServiceImpl(mockRepo)
+	service := NewTaskServiceImpl(mockRepo, &MockProjectRoles{})
 
 	pagination := pagination.PaginationRequest{
 		Page:     1,
 		PageSize: 10,
-		Order:    "title",
-		SortBy:   "asc",
+		Sort:     []pagination.SortField{{Field: "title"}},
 	}
 	request := GetAllTaskRequest{
 		PaginationRequest: &pagination,
@@ -176,7 +718,7 @@ func TestDeleteTask(t *testing.T) {
 			return errors.New("task not found")
 		},
 	}
-	service := NewTaskServiceImpl(mockRepo)
+	service := NewTaskServiceImpl(mockRepo, &MockProjectRoles{})
 
 	err := service.DeleteTask(context.Background(), 1)
 	assert.NoError(t, err)
@@ -184,3 +726,260 @@ func TestDeleteTask(t *testing.T) {
 	err = service.DeleteTask(context.Background(), 2)
 	assert.Error(t, err)
 }
+
+func TestRestoreTask(t *testing.T) {
+	restored := false
+	mockRepo := &MockTaskRepository{
+		RestoreTaskFunc: func(ctx context.Context, id uint64) error {
+			restored = true
+			return nil
+		},
+	}
+	service := NewTaskServiceImpl(mockRepo, &MockProjectRoles{})
+
+	resp, err := service.RestoreTask(context.Background(), 1)
+
+	assert.NoError(t, err)
+	assert.True(t, restored)
+	assert.Equal(t, uint64(1), resp.ID)
+}
+
+func TestRestoreTaskWhenNotTrashed(t *testing.T) {
+	mockRepo := &MockTaskRepository{
+		RestoreTaskFunc: func(ctx context.Context, id uint64) error {
+			return ErrTaskNotTrashed
+		},
+	}
+	service := NewTaskServiceImpl(mockRepo, &MockProjectRoles{})
+
+	resp, err := service.RestoreTask(context.Background(), 1)
+
+	assert.ErrorIs(t, err, ErrConflict)
+	assert.Nil(t, resp)
+}
+
+func TestSaveTaskInProject(t *testing.T) {
+	var saved *Task
+	mockRepo := &MockTaskRepository{
+		SaveTaskFunc: func(ctx context.Context, task *Task) error {
+			saved = task
+			return nil
+		},
+	}
+	service := NewTaskServiceImpl(mockRepo, rolesOf(map[uint64]auth.Role{5: auth.RoleEditor, 6: auth.RoleViewer}))
+
+	resp, err := service.SaveTask(context.Background(), &WriteTaskRequest{Title: "Shared", ProjectID: 5})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(5), resp.ProjectID)
+	assert.Equal(t, uint64(5), saved.ProjectID)
+
+	saved = nil
+	_, err = service.SaveTask(context.Background(), &WriteTaskRequest{Title: "Shared", ProjectID: 6})
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = service.SaveTask(context.Background(), &WriteTaskRequest{Title: "Shared", ProjectID: 7})
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Nil(t, saved)
+}
+
+func TestChangeProjectTaskAsViewer(t *testing.T) {
+	mockRepo := &MockTaskRepository{
+		GetTaskFunc: func(ctx context.Context, id uint64) (*Task, error) {
+			return &Task{ID: id, Title: "Shared", Status: StatusTodo, ProjectID: 6, Version: 1}, nil
+		},
+		GetTaskIncludingTrashFunc: func(ctx context.Context, id uint64) (*Task, error) {
+			return &Task{ID: id, Title: "Shared", Status: StatusTodo, ProjectID: 6, Version: 1}, nil
+		},
+		UpdateTaskFunc: func(ctx context.Context, task *Task) error {
+			t.Fatal("a viewer must not update tasks")
+			return nil
+		},
+		DeleteTaskFunc: func(ctx context.Context, id uint64) error {
+			t.Fatal("a viewer must not delete tasks")
+			return nil
+		},
+		RestoreTaskFunc: func(ctx context.Context, id uint64) error {
+			t.Fatal("a viewer must not restore tasks")
+			return nil
+		},
+		PurgeTaskFunc: func(ctx context.Context, id uint64) error {
+			t.Fatal("a viewer must not purge tasks")
+			return nil
+		},
+	}
+	service := NewTaskServiceImpl(mockRepo, rolesOf(map[uint64]auth.Role{6: auth.RoleViewer}))
+	ctx := context.Background()
+
+	resp, err := service.GetTask(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "Shared", resp.Title)
+
+	_, err = service.SaveTask(ctx, &WriteTaskRequest{ID: 1, Title: "Edited", ProjectID: 6})
+	assert.ErrorIs(t, err, ErrForbidden)
+	mergePatch, err := patch.NewMergePatch([]byte(`{"title":"Edited"}`))
+	assert.NoError(t, err)
+	_, err = service.PatchTask(ctx, &PatchTaskRequest{ID: 1, Patch: mergePatch})
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = service.CompleteTask(ctx, 1)
+	assert.ErrorIs(t, err, ErrForbidden)
+	assert.ErrorIs(t, service.DeleteTask(ctx, 1), ErrForbidden)
+	_, err = service.RestoreTask(ctx, 1)
+	assert.ErrorIs(t, err, ErrForbidden)
+	assert.ErrorIs(t, service.PurgeTask(ctx, 1), ErrForbidden)
+}
+
+func TestReplaceTaskMovesProject(t *testing.T) {
+	var saved *Task
+	mockRepo := &MockTaskRepository{
+		GetTaskFunc: func(ctx context.Context, id uint64) (*Task, error) {
+			return &Task{ID: id, Title: "Shared", Status: StatusTodo, OwnerID: 8, ProjectID: 5, Version: 1}, nil
+		},
+		UpdateTaskFunc: func(ctx context.Context, task *Task) error {
+			saved = task
+			return nil
+		},
+	}
+	service := NewTaskServiceImpl(mockRepo, rolesOf(map[uint64]auth.Role{5: auth.RoleEditor, 6: auth.RoleEditor, 7: auth.RoleViewer}))
+
+	resp, err := service.SaveTask(ownerCtx, &WriteTaskRequest{ID: 1, Title: "Shared", ProjectID: 6})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(6), resp.ProjectID)
+	assert.Equal(t, uint64(8), saved.OwnerID)
+
+	// out of every project the task becomes the mover's own
+	saved = nil
+	_, err = service.SaveTask(ownerCtx, &WriteTaskRequest{ID: 1, Title: "Mine"})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), saved.ProjectID)
+	assert.Equal(t, uint64(7), saved.OwnerID)
+
+	// moving takes the editor role in the target project too
+	saved = nil
+	_, err = service.SaveTask(ownerCtx, &WriteTaskRequest{ID: 1, Title: "Shared", ProjectID: 7})
+	assert.ErrorIs(t, err, ErrTaskForbidden)
+	_, err = service.SaveTask(ownerCtx, &WriteTaskRequest{ID: 1, Title: "Shared", ProjectID: 9})
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Nil(t, saved)
+}
+
+func TestGetProjectTasksAsNonMember(t *testing.T) {
+	mockRepo := &MockTaskRepository{
+		GetAllTasksFunc: func(ctx context.Context, request GetAllTaskRequest) ([]Task, int64, error) {
+			t.Fatal("tasks of a project the user is no member of must not be read")
+			return nil, 0, nil
+		},
+	}
+	service := NewTaskServiceImpl(mockRepo, rolesOf(map[uint64]auth.Role{6: auth.RoleViewer}))
+	request := GetAllTaskRequest{PaginationRequest: &pagination.PaginationRequest{Page: 1, PageSize: 10}, ProjectID: 5}
+
+	_, err := service.GetAllTasks(context.Background(), request)
+
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestTrashPurgerPurge(t *testing.T) {
+	var got time.Time
+	mockRepo := &MockTaskRepository{
+		PurgeTrashFunc: func(ctx context.Context, deletedBefore time.Time) (int64, error) {
+			got = deletedBefore
+			return 3, nil
+		},
+	}
+	now := time.Date(2024, time.May, 15, 13, 30, 0, 0, time.UTC)
+	purger := NewTrashPurger(mockRepo, 24*time.Hour, time.Hour)
+	purger.now = func() time.Time { return now }
+
+	purged, err := purger.Purge(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), purged)
+	assert.Equal(t, now.AddDate(0, 0, -1), got)
+}
+
+func TestCompleteTask(t *testing.T) {
+	var saved *Task
+	mockRepo := &MockTaskRepository{
+		UpdateTaskFunc: func(ctx context.Context, task *Task) error {
+			saved = task
+			return nil
+		},
+	}
+	service := NewTaskServiceImpl(mockRepo, &MockProjectRoles{})
+
+	resp, err := service.CompleteTask(context.Background(), 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, StatusDone, resp.Status)
+	assert.NotEmpty(t, resp.CompletedAt)
+	assert.NotNil(t, saved.CompletedAt)
+}
+
+func TestReopenTask(t *testing.T) {
+	completedAt := time.Now()
+	mockRepo := &MockTaskRepository{
+		GetTaskFunc: func(ctx context.Context, id uint64) (*Task, error) {
+			return &Task{ID: id, Status: StatusDone, CompletedAt: &completedAt}, nil
+		},
+	}
+	service := NewTaskServiceImpl(mockRepo, &MockProjectRoles{})
+
+	resp, err := service.ReopenTask(context.Background(), 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, StatusTodo, resp.Status)
+	assert.Empty(t, resp.CompletedAt)
+}
+
+func TestUpdateTaskStatusKeepsTags(t *testing.T) {
+	var updated Task
+	mockRepo := &MockTaskRepository{
+		GetTaskFunc: func(ctx context.Context, id uint64) (*Task, error) {
+			return &Task{ID: id, Status: StatusTodo, Tags: []Tag{{ID: 3, Name: "home"}}}, nil
+		},
+		UpdateTaskFunc: func(ctx context.Context, task *Task) error {
+			updated = *task
+			return nil
+		},
+	}
+	service := NewTaskServiceImpl(mockRepo, &MockProjectRoles{})
+
+	resp, err := service.UpdateTaskStatus(context.Background(), &UpdateTaskStatusRequest{ID: 1, Status: StatusDone})
+
+	assert.NoError(t, err)
+	assert.Nil(t, updated.Tags, "the repository is told to leave the tags alone")
+	assert.Equal(t, []string{"home"}, resp.Tags)
+}
+
+func TestUpdateTaskStatusWhenInvalidTransition(t *testing.T) {
+	mockRepo := &MockTaskRepository{
+		GetTaskFunc: func(ctx context.Context, id uint64) (*Task, error) {
+			return &Task{ID: id, Status: StatusCancelled}, nil
+		},
+	}
+	service := NewTaskServiceImpl(mockRepo, &MockProjectRoles{})
+
+	resp, err := service.UpdateTaskStatus(context.Background(), &UpdateTaskStatusRequest{ID: 1, Status: StatusDone})
+
+	assert.ErrorIs(t, err, ErrInvalidStatusTransition)
+	assert.Nil(t, resp)
+}
+
+func TestUpdateTaskStatusWhenInvalidStatus(t *testing.T) {
+	service := NewTaskServiceImpl(&MockTaskRepository{}, &MockProjectRoles{})
+
+	resp, err := service.UpdateTaskStatus(context.Background(), &UpdateTaskStatusRequest{ID: 1, Status: "archived"})
+
+	var validationErr *ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "status", validationErr.Fields[0].Field)
+	assert.Nil(t, resp)
+}
+
+func TestStatusCanTransitionTo(t *testing.T) {
+	assert.True(t, StatusTodo.CanTransitionTo(StatusInProgress))
+	assert.True(t, StatusInProgress.CanTransitionTo(StatusDone))
+	assert.True(t, StatusDone.CanTransitionTo(StatusTodo))
+	assert.True(t, StatusCancelled.CanTransitionTo(StatusTodo))
+	assert.False(t, StatusDone.CanTransitionTo(StatusDone))
+	assert.False(t, StatusCancelled.CanTransitionTo(StatusDone))
+	assert.False(t, Status("archived").CanTransitionTo(StatusTodo))
+}
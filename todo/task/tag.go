@@ -0,0 +1,97 @@
+package task
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Tag labels tasks. A tag has the scope of the tasks it labels: one of project 0 is its owner's
+// alone, any other belongs to its project and is shared by the members. Names are stored in lower
+// case and are unique within a scope.
+type Tag struct {
+	ID        uint64    `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"not null;uniqueIndex:idx_tag_scope"`
+	ProjectID uint64    `json:"projectId" gorm:"not null;default:0;uniqueIndex:idx_tag_scope"`
+	OwnerID   uint64    `json:"ownerId" gorm:"not null;default:0;uniqueIndex:idx_tag_scope"` // 0 for project tags
+	CreatedAt time.Time `json:"createdAt" gorm:"not null"`
+}
+
+func (Tag) TableName() string {
+	return "tag"
+}
+
+// TaskTag links a task to one of its tags.
+type TaskTag struct {
+	TaskID uint64 `gorm:"primaryKey"`
+	TagID  uint64 `gorm:"primaryKey;index"`
+}
+
+func (TaskTag) TableName() string {
+	return "task_tag"
+}
+
+type CreateTagRequest struct {
+	Name      string `json:"name" validate:"required,tagname"`
+	ProjectID uint64 `json:"projectId"`
+}
+
+type UpdateTagRequest struct {
+	ID   uint64 `json:"-"`
+	Name string `json:"name" validate:"required,tagname"`
+}
+
+type TagResponse struct {
+	ID        uint64 `json:"id"`
+	Name      string `json:"name"`
+	ProjectID uint64 `json:"projectId,omitempty"`
+}
+
+func (t Tag) ToResponse() TagResponse {
+	return TagResponse{ID: t.ID, Name: t.Name, ProjectID: t.ProjectID}
+}
+
+// tagScope returns the project and owner the tags of a task belong to.
+func (t Task) tagScope() (projectID, ownerID uint64) {
+	if t.ProjectID != 0 {
+		return t.ProjectID, 0
+	}
+	return 0, t.OwnerID
+}
+
+// TagNames returns the names of the task's tags in alphabetical order, nil when it has none.
+func (t Task) TagNames() []string {
+	if len(t.Tags) == 0 {
+		return nil
+	}
+	names := make([]string, len(t.Tags))
+	for i, tag := range t.Tags {
+		names[i] = tag.Name
+	}
+	slices.Sort(names)
+	return names
+}
+
+// normalizeTags spells tag names the way they are stored: lower case, each once, sorted.
+func normalizeTags(names []string) []string {
+	if names == nil {
+		return nil
+	}
+	normalized := make([]string, len(names))
+	for i, name := range names {
+		normalized[i] = strings.ToLower(name)
+	}
+	slices.Sort(normalized)
+	return slices.Compact(normalized)
+}
+
+// newTags returns the tags named, for the repository to resolve within the task's scope. The
+// result is never nil, so writing it replaces the task's tags even when there are none.
+func newTags(names []string) []Tag {
+	names = normalizeTags(names)
+	tags := make([]Tag, len(names))
+	for i, name := range names {
+		tags[i] = Tag{Name: name}
+	}
+	return tags
+}
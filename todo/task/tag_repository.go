@@ -0,0 +1,115 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+)
+
+type TagRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+func NewTagRepositoryImpl(db *gorm.DB) *TagRepositoryImpl {
+	return &TagRepositoryImpl{DB: db}
+}
+
+// GetTags returns the tags visible to the user by name, only those of one project when projectID
+// is set. Tags are visible to whoever may see the tasks of their scope.
+func (r *TagRepositoryImpl) GetTags(ctx context.Context, projectID uint64) ([]Tag, error) {
+	log := zerolog.Ctx(ctx).With().Str("method", "tagRepository.GetTags").Logger()
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	db := r.DB.WithContext(ctx).Scopes(visibleTo(userID))
+	if projectID != 0 {
+		db = db.Where("project_id = ?", projectID)
+	}
+	var tags []Tag
+	if err := db.Order("name, id").Find(&tags).Error; err != nil {
+		log.Error().Err(err).Msg("failed to retrieve tags")
+		return nil, fmt.Errorf("failed to retrieve tags: %w", err)
+	}
+	log.Info().Int("count", len(tags)).Msg("success to retrieve tags")
+	return tags, nil
+}
+
+func (r *TagRepositoryImpl) GetTag(ctx context.Context, id uint64) (*Tag, error) {
+	log := zerolog.Ctx(ctx).With().Str("method", "tagRepository.GetTag").Logger()
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var tag Tag
+	if err := r.DB.WithContext(ctx).Scopes(visibleTo(userID)).First(&tag, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Info().Uint64("id", id).Msg("tag not found")
+			return nil, fmt.Errorf("%w: id %d", ErrTagNotFound, id)
+		}
+		log.Error().Err(err).Msg("failed to retrieve tag")
+		return nil, fmt.Errorf("failed to retrieve tag: %w", err)
+	}
+	log.Info().Msg("success to retrieve tag")
+	return &tag, nil
+}
+
+func (r *TagRepositoryImpl) SaveTag(ctx context.Context, tag *Tag) error {
+	log := zerolog.Ctx(ctx).With().Str("method", "tagRepository.SaveTag").Logger()
+	if err := r.DB.WithContext(ctx).Create(tag).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			log.Info().Str("name", tag.Name).Msg("tag already exists")
+			return fmt.Errorf("%w: %s", ErrTagExists, tag.Name)
+		}
+		log.Error().Err(err).Msg("failed to save tag")
+		return fmt.Errorf("failed to save tag: %w", err)
+	}
+	log.Info().Uint64("id", tag.ID).Msg("success to save tag")
+	return nil
+}
+
+// UpdateTag renames a tag, on every task it labels.
+func (r *TagRepositoryImpl) UpdateTag(ctx context.Context, tag *Tag) error {
+	log := zerolog.Ctx(ctx).With().Str("method", "tagRepository.UpdateTag").Logger()
+	result := r.DB.WithContext(ctx).Model(tag).UpdateColumn("name", tag.Name)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrDuplicatedKey) {
+			log.Info().Str("name", tag.Name).Msg("tag already exists")
+			return fmt.Errorf("%w: %s", ErrTagExists, tag.Name)
+		}
+		log.Error().Err(result.Error).Msg("failed to update tag")
+		return fmt.Errorf("failed to update tag: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		log.Info().Uint64("id", tag.ID).Msg("tag not found")
+		return fmt.Errorf("%w: id %d", ErrTagNotFound, tag.ID)
+	}
+	log.Info().Uint64("id", tag.ID).Msg("success to update tag")
+	return nil
+}
+
+// DeleteTag removes a tag from every task it labels, then deletes it.
+func (r *TagRepositoryImpl) DeleteTag(ctx context.Context, id uint64) error {
+	log := zerolog.Ctx(ctx).With().Str("method", "tagRepository.DeleteTag").Logger()
+	var result *gorm.DB
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tag_id = ?", id).Delete(&TaskTag{}).Error; err != nil {
+			return err
+		}
+		result = tx.Delete(&Tag{}, id)
+		return result.Error
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("failed to delete tag")
+		return fmt.Errorf("failed to delete tag: %w", err)
+	}
+	if result.RowsAffected == 0 {
+		log.Info().Uint64("id", id).Msg("tag not found")
+		return fmt.Errorf("%w: id %d", ErrTagNotFound, id)
+	}
+	log.Info().Uint64("id", id).Msg("success to delete tag")
+	return nil
+}
@@ -0,0 +1,105 @@
+package task
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func newMockTagRepository(t *testing.T) (*TagRepositoryImpl, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{TranslateError: true})
+	assert.NoError(t, err)
+
+	return NewTagRepositoryImpl(gormDB), mock
+}
+
+func TestGetTagsMock(t *testing.T) {
+	repo, mock := newMockTagRepository(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "tag" WHERE project_id = $1 AND ((project_id = 0 AND owner_id = $2) OR project_id IN (SELECT project_id FROM project_member WHERE user_id = $3)) ORDER BY name, id`)).
+		WithArgs(5, 7, 7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "project_id"}).AddRow(3, "backend", 5).AddRow(4, "docs", 5))
+
+	tags, err := repo.GetTags(ownerCtx, 5)
+
+	assert.NoError(t, err)
+	assert.Len(t, tags, 2)
+	assert.Equal(t, "backend", tags[0].Name)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetTagMockWhenNotFound(t *testing.T) {
+	repo, mock := newMockTagRepository(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "tag" WHERE "tag"."id" = $1 AND ((project_id = 0 AND owner_id = $2) OR project_id IN (SELECT project_id FROM project_member WHERE user_id = $3)) ORDER BY "tag"."id" LIMIT $4`)).
+		WithArgs(3, 7, 7, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+
+	_, err := repo.GetTag(ownerCtx, 3)
+
+	assert.ErrorIs(t, err, ErrTagNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateTagMockWhenNameTaken(t *testing.T) {
+	repo, mock := newMockTagRepository(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "tag" SET "name"=$1 WHERE "id" = $2`)).
+		WithArgs("home", 3).
+		WillReturnError(&pgconn.PgError{Code: "23505"})
+	mock.ExpectRollback()
+
+	err := repo.UpdateTag(ownerCtx, &Tag{ID: 3, Name: "home"})
+
+	assert.ErrorIs(t, err, ErrTagExists)
+	assert.ErrorIs(t, err, ErrConflict)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteTagMock(t *testing.T) {
+	repo, mock := newMockTagRepository(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "task_tag" WHERE tag_id = $1`)).
+		WithArgs(3).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "tag" WHERE "tag"."id" = $1`)).
+		WithArgs(3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := repo.DeleteTag(ownerCtx, 3)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteTagMockWhenNotFound(t *testing.T) {
+	repo, mock := newMockTagRepository(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "task_tag" WHERE tag_id = $1`)).
+		WithArgs(3).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "tag" WHERE "tag"."id" = $1`)).
+		WithArgs(3).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	err := repo.DeleteTag(ownerCtx, 3)
+
+	assert.ErrorIs(t, err, ErrTagNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
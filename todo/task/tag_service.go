@@ -0,0 +1,103 @@
+package task
+
+import (
+	"context"
+	"strings"
+
+	"mkmgo-todo/todo/auth"
+)
+
+type TagRepository interface {
+	GetTags(ctx context.Context, projectID uint64) ([]Tag, error)
+	GetTag(ctx context.Context, id uint64) (*Tag, error)
+	SaveTag(ctx context.Context, tag *Tag) error
+	UpdateTag(ctx context.Context, tag *Tag) error
+	DeleteTag(ctx context.Context, id uint64) error
+}
+
+// TagServiceImpl manages tags ahead of and apart from the tasks they label. Tags follow the rules
+// of their scope like tasks do: viewers of a project may list its tags, editors may also change
+// them.
+type TagServiceImpl struct {
+	repo  TagRepository
+	roles ProjectRoles
+}
+
+func NewTagServiceImpl(repo TagRepository, roles ProjectRoles) *TagServiceImpl {
+	return &TagServiceImpl{repo: repo, roles: roles}
+}
+
+// GetTags lists the tags the user can use, only those of one project when projectID is set.
+func (svc *TagServiceImpl) GetTags(ctx context.Context, projectID uint64) ([]TagResponse, error) {
+	if err := authorize(ctx, svc.roles, projectID, auth.RoleViewer); err != nil {
+		return nil, err
+	}
+	tags, err := svc.repo.GetTags(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]TagResponse, len(tags))
+	for i, tag := range tags {
+		responses[i] = tag.ToResponse()
+	}
+	return responses, nil
+}
+
+// CreateTag creates a tag in a project, or the user's own tag when no project is given.
+func (svc *TagServiceImpl) CreateTag(ctx context.Context, request *CreateTagRequest) (*TagResponse, error) {
+	if err := Validate(request); err != nil {
+		return nil, err
+	}
+	if err := authorize(ctx, svc.roles, request.ProjectID, auth.RoleEditor); err != nil {
+		return nil, err
+	}
+	tag := Tag{Name: strings.ToLower(request.Name), ProjectID: request.ProjectID}
+	if tag.ProjectID == 0 {
+		userID, err := currentUser(ctx)
+		if err != nil {
+			return nil, err
+		}
+		tag.OwnerID = userID
+	}
+	if err := svc.repo.SaveTag(ctx, &tag); err != nil {
+		return nil, err
+	}
+	response := tag.ToResponse()
+	return &response, nil
+}
+
+// UpdateTag renames a tag.
+func (svc *TagServiceImpl) UpdateTag(ctx context.Context, request *UpdateTagRequest) (*TagResponse, error) {
+	if err := Validate(request); err != nil {
+		return nil, err
+	}
+	tag, err := svc.editableTag(ctx, request.ID)
+	if err != nil {
+		return nil, err
+	}
+	tag.Name = strings.ToLower(request.Name)
+	if err := svc.repo.UpdateTag(ctx, tag); err != nil {
+		return nil, err
+	}
+	response := tag.ToResponse()
+	return &response, nil
+}
+
+// DeleteTag deletes a tag, taking it off every task it labels.
+func (svc *TagServiceImpl) DeleteTag(ctx context.Context, id uint64) error {
+	if _, err := svc.editableTag(ctx, id); err != nil {
+		return err
+	}
+	return svc.repo.DeleteTag(ctx, id)
+}
+
+func (svc *TagServiceImpl) editableTag(ctx context.Context, id uint64) (*Tag, error) {
+	tag, err := svc.repo.GetTag(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorize(ctx, svc.roles, tag.ProjectID, auth.RoleEditor); err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
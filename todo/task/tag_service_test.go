@@ -0,0 +1,187 @@
+package task
+
+import (
+	"context"
+	"mkmgo-todo/todo/auth"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+	Mock task/tag_repository.go
+*/
+
+type MockTagRepository struct {
+	GetTagsFunc   func(ctx context.Context, projectID uint64) ([]Tag, error)
+	GetTagFunc    func(ctx context.Context, id uint64) (*Tag, error)
+	SaveTagFunc   func(ctx context.Context, tag *Tag) error
+	UpdateTagFunc func(ctx context.Context, tag *Tag) error
+	DeleteTagFunc func(ctx context.Context, id uint64) error
+}
+
+func (m *MockTagRepository) GetTags(ctx context.Context, projectID uint64) ([]Tag, error) {
+	if m.GetTagsFunc != nil {
+		return m.GetTagsFunc(ctx, projectID)
+	}
+	return []Tag{}, nil
+}
+
+func (m *MockTagRepository) GetTag(ctx context.Context, id uint64) (*Tag, error) {
+	if m.GetTagFunc != nil {
+		return m.GetTagFunc(ctx, id)
+	}
+	return &Tag{ID: id, Name: "home", OwnerID: 7}, nil
+}
+
+func (m *MockTagRepository) SaveTag(ctx context.Context, tag *Tag) error {
+	if m.SaveTagFunc != nil {
+		return m.SaveTagFunc(ctx, tag)
+	}
+	return nil
+}
+
+func (m *MockTagRepository) UpdateTag(ctx context.Context, tag *Tag) error {
+	if m.UpdateTagFunc != nil {
+		return m.UpdateTagFunc(ctx, tag)
+	}
+	return nil
+}
+
+func (m *MockTagRepository) DeleteTag(ctx context.Context, id uint64) error {
+	if m.DeleteTagFunc != nil {
+		return m.DeleteTagFunc(ctx, id)
+	}
+	return nil
+}
+
+/*
+	Unit test for task/tag_service.go
+*/
+
+func TestCreateTag(t *testing.T) {
+	var saved *Tag
+	mockRepo := &MockTagRepository{
+		SaveTagFunc: func(ctx context.Context, tag *Tag) error {
+			tag.ID = 3
+			saved = tag
+			return nil
+		},
+	}
+	service := NewTagServiceImpl(mockRepo, &MockProjectRoles{})
+
+	resp, err := service.CreateTag(ownerCtx, &CreateTagRequest{Name: "Urgent"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &TagResponse{ID: 3, Name: "urgent"}, resp)
+	assert.Equal(t, uint64(7), saved.OwnerID)
+	assert.Equal(t, uint64(0), saved.ProjectID)
+}
+
+func TestCreateTagInProject(t *testing.T) {
+	var saved *Tag
+	mockRepo := &MockTagRepository{
+		SaveTagFunc: func(ctx context.Context, tag *Tag) error {
+			saved = tag
+			return nil
+		},
+	}
+	service := NewTagServiceImpl(mockRepo, rolesOf(map[uint64]auth.Role{5: auth.RoleEditor, 6: auth.RoleViewer}))
+
+	resp, err := service.CreateTag(ownerCtx, &CreateTagRequest{Name: "backend", ProjectID: 5})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(5), resp.ProjectID)
+	assert.Equal(t, uint64(0), saved.OwnerID, "project tags have no owner")
+
+	saved = nil
+	_, err = service.CreateTag(ownerCtx, &CreateTagRequest{Name: "backend", ProjectID: 6})
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = service.CreateTag(ownerCtx, &CreateTagRequest{Name: "backend", ProjectID: 7})
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Nil(t, saved)
+}
+
+func TestCreateTagWhenInvalid(t *testing.T) {
+	service := NewTagServiceImpl(&MockTagRepository{
+		SaveTagFunc: func(ctx context.Context, tag *Tag) error {
+			t.Fatal("an invalid tag must not be saved")
+			return nil
+		},
+	}, &MockProjectRoles{})
+
+	_, err := service.CreateTag(ownerCtx, &CreateTagRequest{Name: "home,work"})
+
+	assert.ErrorIs(t, err, ErrValidation)
+}
+
+func TestGetTagsInProjectAsNonMember(t *testing.T) {
+	service := NewTagServiceImpl(&MockTagRepository{
+		GetTagsFunc: func(ctx context.Context, projectID uint64) ([]Tag, error) {
+			t.Fatal("tags must not be listed for non-members")
+			return nil, nil
+		},
+	}, rolesOf(map[uint64]auth.Role{}))
+
+	_, err := service.GetTags(ownerCtx, 5)
+
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestUpdateTag(t *testing.T) {
+	var updated *Tag
+	mockRepo := &MockTagRepository{
+		UpdateTagFunc: func(ctx context.Context, tag *Tag) error {
+			updated = tag
+			return nil
+		},
+	}
+	service := NewTagServiceImpl(mockRepo, &MockProjectRoles{})
+
+	resp, err := service.UpdateTag(ownerCtx, &UpdateTagRequest{ID: 3, Name: "Chores"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &TagResponse{ID: 3, Name: "chores"}, resp)
+	assert.Equal(t, "chores", updated.Name)
+}
+
+func TestChangeProjectTagAsViewer(t *testing.T) {
+	mockRepo := &MockTagRepository{
+		GetTagFunc: func(ctx context.Context, id uint64) (*Tag, error) {
+			return &Tag{ID: id, Name: "backend", ProjectID: 6}, nil
+		},
+		UpdateTagFunc: func(ctx context.Context, tag *Tag) error {
+			t.Fatal("a viewer must not rename tags")
+			return nil
+		},
+		DeleteTagFunc: func(ctx context.Context, id uint64) error {
+			t.Fatal("a viewer must not delete tags")
+			return nil
+		},
+	}
+	service := NewTagServiceImpl(mockRepo, rolesOf(map[uint64]auth.Role{6: auth.RoleViewer}))
+
+	resps, err := service.GetTags(ownerCtx, 6)
+	assert.NoError(t, err)
+	assert.Empty(t, resps)
+
+	_, err = service.UpdateTag(ownerCtx, &UpdateTagRequest{ID: 3, Name: "frontend"})
+	assert.ErrorIs(t, err, ErrForbidden)
+	assert.ErrorIs(t, service.DeleteTag(ownerCtx, 3), ErrForbidden)
+}
+
+func TestDeleteTagWhenNotFound(t *testing.T) {
+	mockRepo := &MockTagRepository{
+		GetTagFunc: func(ctx context.Context, id uint64) (*Tag, error) {
+			return nil, ErrTagNotFound
+		},
+		DeleteTagFunc: func(ctx context.Context, id uint64) error {
+			t.Fatal("a missing tag must not be deleted")
+			return nil
+		},
+	}
+	service := NewTagServiceImpl(mockRepo, &MockProjectRoles{})
+
+	err := service.DeleteTag(ownerCtx, 3)
+
+	assert.ErrorIs(t, err, ErrNotFound)
+}
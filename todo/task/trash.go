@@ -0,0 +1,45 @@
+package task
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// TrashPurger hard-deletes tasks that have been in the trash longer than the retention period.
+type TrashPurger struct {
+	repo      TaskRepository
+	retention time.Duration
+	interval  time.Duration
+	now       func() time.Time
+}
+
+func NewTrashPurger(repo TaskRepository, retention, interval time.Duration) *TrashPurger {
+	return &TrashPurger{repo: repo, retention: retention, interval: interval, now: time.Now}
+}
+
+// Run purges once straight away and then every interval until ctx is done. A failed purge is
+// logged and retried on the next tick.
+func (p *TrashPurger) Run(ctx context.Context) {
+	log := zerolog.Ctx(ctx).With().Str("method", "trashPurger.Run").Logger()
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		if purged, err := p.Purge(ctx); err != nil {
+			log.Error().Err(err).Msg("failed to purge trash")
+		} else if purged > 0 {
+			log.Info().Int64("purged", purged).Msg("purged expired tasks from trash")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge hard-deletes every task deleted more than the retention period ago.
+func (p *TrashPurger) Purge(ctx context.Context) (int64, error) {
+	return p.repo.PurgeTrash(ctx, p.now().Add(-p.retention))
+}
@@ -0,0 +1,210 @@
+package task
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Validate checks a request struct against the rules in its `validate` field tags and reports
+// every violating field at once, keyed by its JSON name. Rules are comma separated:
+//
+//	required       the field must be set; a string must contain more than whitespace
+//	trimmed        no leading or trailing whitespace
+//	min=N, max=N   length bounds in characters (or elements for slices)
+//	oneof=a b c    the value must be one of the listed words
+//	printable      no control characters at all
+//	text           no control characters except newline, carriage return and tab
+//	notbefore=F    a time that must not be before the time in sibling field F
+//	email          a bare email address such as jane@example.com
+//	tagname        a tag name, or for a list every element: not blank, trimmed, at most
+//	               50 characters, printable and without commas
+//
+// All rules except required accept a zero value, so optional fields are only checked when set.
+func Validate(request any) error {
+	value := reflect.Indirect(reflect.ValueOf(request))
+	structType := value.Type()
+
+	var fields []FieldError
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		tag, ok := field.Tag.Lookup("validate")
+		if !ok {
+			continue
+		}
+		for _, rule := range strings.Split(tag, ",") {
+			name, param, _ := strings.Cut(rule, "=")
+			check, ok := validationRules[name]
+			if !ok {
+				panic(fmt.Sprintf("task: unknown validation rule %q on %s.%s", name, structType.Name(), field.Name))
+			}
+			fieldValue := value.Field(i)
+			if name != "required" && fieldValue.IsZero() {
+				continue
+			}
+			if message := check(fieldValue, param, value); message != "" {
+				fields = append(fields, FieldError{Field: jsonName(field), Message: message})
+				break
+			}
+		}
+	}
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+	return nil
+}
+
+type validationRule func(value reflect.Value, param string, parent reflect.Value) string
+
+var validationRules = map[string]validationRule{
+	"required":  validateRequired,
+	"trimmed":   validateTrimmed,
+	"min":       validateMin,
+	"max":       validateMax,
+	"oneof":     validateOneOf,
+	"printable": validatePrintable,
+	"text":      validateText,
+	"notbefore": validateNotBefore,
+	"email":     validateEmail,
+	"tagname":   validateTagName,
+}
+
+func validateRequired(value reflect.Value, _ string, _ reflect.Value) string {
+	if value.IsZero() || (value.Kind() == reflect.String && strings.TrimSpace(value.String()) == "") {
+		return "is required"
+	}
+	return ""
+}
+
+func validateTrimmed(value reflect.Value, _ string, _ reflect.Value) string {
+	if s := value.String(); strings.TrimSpace(s) != s {
+		return "must not start or end with whitespace"
+	}
+	return ""
+}
+
+func validateMin(value reflect.Value, param string, _ reflect.Value) string {
+	if min := mustAtoi(param); length(value) < min {
+		return fmt.Sprintf("must be at least %d characters long", min)
+	}
+	return ""
+}
+
+func validateMax(value reflect.Value, param string, _ reflect.Value) string {
+	if max := mustAtoi(param); length(value) > max {
+		if value.Kind() == reflect.Slice {
+			return fmt.Sprintf("must have at most %d elements", max)
+		}
+		return fmt.Sprintf("must be at most %d characters long", max)
+	}
+	return ""
+}
+
+func validateOneOf(value reflect.Value, param string, _ reflect.Value) string {
+	allowed := strings.Fields(param)
+	if !slices.Contains(allowed, value.String()) {
+		return "must be one of " + strings.Join(allowed, ", ")
+	}
+	return ""
+}
+
+func validatePrintable(value reflect.Value, _ string, _ reflect.Value) string {
+	if strings.IndexFunc(value.String(), unicode.IsControl) >= 0 {
+		return "must not contain control characters"
+	}
+	return ""
+}
+
+func validateText(value reflect.Value, _ string, _ reflect.Value) string {
+	isForbidden := func(r rune) bool {
+		return unicode.IsControl(r) && r != '\n' && r != '\r' && r != '\t'
+	}
+	if strings.IndexFunc(value.String(), isForbidden) >= 0 {
+		return "must not contain control characters other than line breaks and tabs"
+	}
+	return ""
+}
+
+func validateNotBefore(value reflect.Value, param string, parent reflect.Value) string {
+	other, ok := parent.Type().FieldByName(param)
+	if !ok {
+		panic(fmt.Sprintf("task: notbefore refers to unknown field %s", param))
+	}
+	otherValue := parent.FieldByIndex(other.Index)
+	if otherValue.IsZero() {
+		return ""
+	}
+	start, ok := reflect.Indirect(otherValue).Interface().(time.Time)
+	if !ok {
+		panic(fmt.Sprintf("task: notbefore field %s is not a time", param))
+	}
+	if reflect.Indirect(value).Interface().(time.Time).Before(start) {
+		return "must not be before " + jsonName(other)
+	}
+	return ""
+}
+
+func validateEmail(value reflect.Value, _ string, _ reflect.Value) string {
+	address, err := mail.ParseAddress(value.String())
+	if err != nil || address.Address != value.String() {
+		return "must be an email address"
+	}
+	return ""
+}
+
+// maxTagNameLength keeps tag names short enough to list several in a query parameter.
+const maxTagNameLength = 50
+
+func validateTagName(value reflect.Value, _ string, _ reflect.Value) string {
+	if value.Kind() == reflect.Slice {
+		for i := 0; i < value.Len(); i++ {
+			if message := validateTagName(value.Index(i), "", reflect.Value{}); message != "" {
+				return fmt.Sprintf("tag %q %s", value.Index(i).String(), message)
+			}
+		}
+		return ""
+	}
+	name := value.String()
+	switch {
+	case strings.TrimSpace(name) == "":
+		return "must not be blank"
+	case strings.TrimSpace(name) != name:
+		return "must not start or end with whitespace"
+	case utf8.RuneCountInString(name) > maxTagNameLength:
+		return fmt.Sprintf("must be at most %d characters long", maxTagNameLength)
+	case strings.ContainsRune(name, ','):
+		return "must not contain commas"
+	case strings.IndexFunc(name, unicode.IsControl) >= 0:
+		return "must not contain control characters"
+	}
+	return ""
+}
+
+func length(value reflect.Value) int {
+	if value.Kind() == reflect.String {
+		return utf8.RuneCountInString(value.String())
+	}
+	return value.Len()
+}
+
+func mustAtoi(param string) int {
+	n, err := strconv.Atoi(param)
+	if err != nil {
+		panic(fmt.Sprintf("task: invalid validation rule parameter %q", param))
+	}
+	return n
+}
+
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
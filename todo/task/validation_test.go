@@ -0,0 +1,107 @@
+package task
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateWriteTaskRequest(t *testing.T) {
+	startAt := time.Now()
+	dueAt := startAt.Add(time.Hour)
+
+	err := Validate(&WriteTaskRequest{Title: "Makima", Description: "line one\nline two\ttabbed", StartAt: &startAt, DueAt: &dueAt})
+	assert.NoError(t, err)
+
+	err = Validate(&WriteTaskRequest{Title: "Makima", DueAt: &dueAt})
+	assert.NoError(t, err, "dates are only compared when both are set")
+}
+
+func TestValidateReportsEveryField(t *testing.T) {
+	startAt := time.Now()
+	dueAt := startAt.Add(-time.Hour)
+
+	err := Validate(&WriteTaskRequest{
+		Title:       " Makima",
+		Description: strings.Repeat("a", 10001),
+		StartAt:     &startAt,
+		DueAt:       &dueAt,
+	})
+
+	var validationErr *ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	assert.ErrorIs(t, err, ErrValidation)
+	assert.Equal(t, []FieldError{
+		{Field: "title", Message: "must not start or end with whitespace"},
+		{Field: "description", Message: "must be at most 10000 characters long"},
+		{Field: "dueAt", Message: "must not be before startAt"},
+	}, validationErr.Fields)
+}
+
+func TestValidateRules(t *testing.T) {
+	tests := []struct {
+		request WriteTaskRequest
+		field   string
+		message string
+	}{
+		{WriteTaskRequest{}, "title", "is required"},
+		{WriteTaskRequest{Title: "   "}, "title", "is required"},
+		{WriteTaskRequest{Title: strings.Repeat("ま", 201)}, "title", "must be at most 200 characters long"},
+		{WriteTaskRequest{Title: "Maki\x00ma"}, "title", "must not contain control characters"},
+		{WriteTaskRequest{Title: "Maki\nma"}, "title", "must not contain control characters"},
+		{WriteTaskRequest{Title: "Makima", Description: "beep\a"}, "description", "must not contain control characters other than line breaks and tabs"},
+		{WriteTaskRequest{Title: "Makima", Tags: []string{"home", " work"}}, "tags", `tag " work" must not start or end with whitespace`},
+		{WriteTaskRequest{Title: "Makima", Tags: []string{""}}, "tags", `tag "" must not be blank`},
+		{WriteTaskRequest{Title: "Makima", Tags: []string{"home,work"}}, "tags", `tag "home,work" must not contain commas`},
+		{WriteTaskRequest{Title: "Makima", Tags: []string{strings.Repeat("a", 51)}}, "tags", `tag "` + strings.Repeat("a", 51) + `" must be at most 50 characters long`},
+		{WriteTaskRequest{Title: "Makima", Tags: strings.Split(strings.Repeat("a,", 20)+"a", ",")}, "tags", "must have at most 20 elements"},
+		{WriteTaskRequest{Title: "Makima", Tags: []string{"to\tdo"}}, "tags", `tag "to\tdo" must not contain control characters`},
+	}
+
+	for _, tt := range tests {
+		err := Validate(&tt.request)
+
+		var validationErr *ValidationError
+		if assert.ErrorAs(t, err, &validationErr, tt.message) {
+			assert.Equal(t, []FieldError{{Field: tt.field, Message: tt.message}}, validationErr.Fields)
+		}
+	}
+
+	assert.NoError(t, Validate(&WriteTaskRequest{Title: strings.Repeat("ま", 200)}), "length counts characters, not bytes")
+}
+
+func TestValidateEmail(t *testing.T) {
+	type request struct {
+		Email string `json:"email" validate:"email"`
+	}
+
+	assert.NoError(t, Validate(&request{Email: "makima@example.com"}))
+	for _, email := range []string{"makima", "Makima <makima@example.com>", "makima@"} {
+		var validationErr *ValidationError
+		if assert.ErrorAs(t, Validate(&request{Email: email}), &validationErr, email) {
+			assert.Equal(t, []FieldError{{Field: "email", Message: "must be an email address"}}, validationErr.Fields)
+		}
+	}
+}
+
+func TestValidateUpdateTaskStatusRequest(t *testing.T) {
+	assert.NoError(t, Validate(&UpdateTaskStatusRequest{Status: StatusInProgress}))
+
+	err := Validate(&UpdateTaskStatusRequest{Status: "archived"})
+	var validationErr *ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []FieldError{{Field: "status", Message: "must be one of todo, in_progress, done, cancelled"}}, validationErr.Fields)
+
+	err = Validate(&UpdateTaskStatusRequest{})
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []FieldError{{Field: "status", Message: "is required"}}, validationErr.Fields)
+}
+
+func TestValidatePanicsOnUnknownRule(t *testing.T) {
+	type request struct {
+		Name string `json:"name" validate:"shiny"`
+	}
+	assert.Panics(t, func() { Validate(&request{Name: "Makima"}) })
+}
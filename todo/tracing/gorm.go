@@ -0,0 +1,74 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const parentContextKey = "tracing:parent_context"
+
+// GORMPlugin starts a client span around every statement GORM executes, named after the kind of
+// statement and carrying the SQL with its placeholders.
+type GORMPlugin struct{}
+
+func NewGORMPlugin() *GORMPlugin {
+	return &GORMPlugin{}
+}
+
+func (p *GORMPlugin) Name() string {
+	return "tracing"
+}
+
+func (p *GORMPlugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().Before("gorm:create").Register("tracing:before_create", startStatement("create")),
+		callbacks.Create().After("gorm:create").Register("tracing:after_create", endStatement),
+		callbacks.Query().Before("gorm:query").Register("tracing:before_query", startStatement("query")),
+		callbacks.Query().After("gorm:query").Register("tracing:after_query", endStatement),
+		callbacks.Update().Before("gorm:update").Register("tracing:before_update", startStatement("update")),
+		callbacks.Update().After("gorm:update").Register("tracing:after_update", endStatement),
+		callbacks.Delete().Before("gorm:delete").Register("tracing:before_delete", startStatement("delete")),
+		callbacks.Delete().After("gorm:delete").Register("tracing:after_delete", endStatement),
+		callbacks.Row().Before("gorm:row").Register("tracing:before_row", startStatement("row")),
+		callbacks.Row().After("gorm:row").Register("tracing:after_row", endStatement),
+		callbacks.Raw().Before("gorm:raw").Register("tracing:before_raw", startStatement("raw")),
+		callbacks.Raw().After("gorm:raw").Register("tracing:after_raw", endStatement),
+	)
+}
+
+func startStatement(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		parent := db.Statement.Context
+		ctx, _ := start(parent, "gorm."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(attribute.String("db.system", db.Dialector.Name())))
+		db.InstanceSet(parentContextKey, parent)
+		db.Statement.Context = ctx
+	}
+}
+
+func endStatement(db *gorm.DB) {
+	span := trace.SpanFromContext(db.Statement.Context)
+	span.SetAttributes(
+		semconv.DBQueryText(db.Statement.SQL.String()),
+		semconv.DBCollectionName(db.Statement.Table),
+		attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+	)
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(db.Error)
+		span.SetStatus(codes.Error, db.Error.Error())
+	}
+	span.End()
+
+	// Later statements in the same session belong under the caller's span, not this one.
+	if parent, ok := db.InstanceGet(parentContextKey); ok {
+		db.Statement.Context = parent.(context.Context)
+	}
+}
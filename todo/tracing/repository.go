@@ -0,0 +1,87 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"mkmgo-todo/todo/task"
+)
+
+// TaskRepository wraps a task.TaskRepository and records a span for every call to it.
+type TaskRepository struct {
+	next task.TaskRepository
+}
+
+func NewTaskRepository(next task.TaskRepository) *TaskRepository {
+	return &TaskRepository{next: next}
+}
+
+func (r *TaskRepository) SaveTask(ctx context.Context, t *task.Task) error {
+	ctx, span := start(ctx, "TaskRepository.SaveTask")
+	err := r.next.SaveTask(ctx, t)
+	end(span, err)
+	return err
+}
+
+func (r *TaskRepository) UpdateTask(ctx context.Context, t *task.Task) error {
+	ctx, span := start(ctx, "TaskRepository.UpdateTask")
+	err := r.next.UpdateTask(ctx, t)
+	end(span, err)
+	return err
+}
+
+func (r *TaskRepository) GetTask(ctx context.Context, id uint64) (*task.Task, error) {
+	ctx, span := start(ctx, "TaskRepository.GetTask")
+	t, err := r.next.GetTask(ctx, id)
+	end(span, err)
+	return t, err
+}
+
+func (r *TaskRepository) GetTaskIncludingTrash(ctx context.Context, id uint64) (*task.Task, error) {
+	ctx, span := start(ctx, "TaskRepository.GetTaskIncludingTrash")
+	t, err := r.next.GetTaskIncludingTrash(ctx, id)
+	end(span, err)
+	return t, err
+}
+
+func (r *TaskRepository) GetAllTasks(ctx context.Context, request task.GetAllTaskRequest) ([]task.Task, int64, error) {
+	ctx, span := start(ctx, "TaskRepository.GetAllTasks")
+	tasks, total, err := r.next.GetAllTasks(ctx, request)
+	end(span, err)
+	return tasks, total, err
+}
+
+func (r *TaskRepository) DeleteTask(ctx context.Context, id uint64) error {
+	ctx, span := start(ctx, "TaskRepository.DeleteTask")
+	err := r.next.DeleteTask(ctx, id)
+	end(span, err)
+	return err
+}
+
+func (r *TaskRepository) GetTrashedTasks(ctx context.Context, request task.GetAllTaskRequest) ([]task.Task, int64, error) {
+	ctx, span := start(ctx, "TaskRepository.GetTrashedTasks")
+	tasks, total, err := r.next.GetTrashedTasks(ctx, request)
+	end(span, err)
+	return tasks, total, err
+}
+
+func (r *TaskRepository) RestoreTask(ctx context.Context, id uint64) error {
+	ctx, span := start(ctx, "TaskRepository.RestoreTask")
+	err := r.next.RestoreTask(ctx, id)
+	end(span, err)
+	return err
+}
+
+func (r *TaskRepository) PurgeTask(ctx context.Context, id uint64) error {
+	ctx, span := start(ctx, "TaskRepository.PurgeTask")
+	err := r.next.PurgeTask(ctx, id)
+	end(span, err)
+	return err
+}
+
+func (r *TaskRepository) PurgeTrash(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	ctx, span := start(ctx, "TaskRepository.PurgeTrash")
+	purged, err := r.next.PurgeTrash(ctx, deletedBefore)
+	end(span, err)
+	return purged, err
+}
@@ -0,0 +1,95 @@
+package tracing
+
+import (
+	"context"
+
+	"mkmgo-todo/todo/handler"
+	"mkmgo-todo/todo/pagination"
+	"mkmgo-todo/todo/task"
+)
+
+// TaskService wraps a handler.TaskService and records a span for every call to it.
+type TaskService struct {
+	next handler.TaskService
+}
+
+func NewTaskService(next handler.TaskService) *TaskService {
+	return &TaskService{next: next}
+}
+
+func (s *TaskService) SaveTask(ctx context.Context, request *task.WriteTaskRequest) (*task.GetTaskResponse, error) {
+	ctx, span := start(ctx, "TaskService.SaveTask")
+	response, err := s.next.SaveTask(ctx, request)
+	end(span, err)
+	return response, err
+}
+
+func (s *TaskService) PatchTask(ctx context.Context, request *task.PatchTaskRequest) (*task.GetTaskResponse, error) {
+	ctx, span := start(ctx, "TaskService.PatchTask")
+	response, err := s.next.PatchTask(ctx, request)
+	end(span, err)
+	return response, err
+}
+
+func (s *TaskService) GetTask(ctx context.Context, id uint64) (*task.GetTaskResponse, error) {
+	ctx, span := start(ctx, "TaskService.GetTask")
+	response, err := s.next.GetTask(ctx, id)
+	end(span, err)
+	return response, err
+}
+
+func (s *TaskService) GetAllTasks(ctx context.Context, request task.GetAllTaskRequest) (*pagination.PaginationResponse[task.GetTaskResponse], error) {
+	ctx, span := start(ctx, "TaskService.GetAllTasks")
+	response, err := s.next.GetAllTasks(ctx, request)
+	end(span, err)
+	return response, err
+}
+
+func (s *TaskService) UpdateTaskStatus(ctx context.Context, request *task.UpdateTaskStatusRequest) (*task.GetTaskResponse, error) {
+	ctx, span := start(ctx, "TaskService.UpdateTaskStatus")
+	response, err := s.next.UpdateTaskStatus(ctx, request)
+	end(span, err)
+	return response, err
+}
+
+func (s *TaskService) CompleteTask(ctx context.Context, id uint64) (*task.GetTaskResponse, error) {
+	ctx, span := start(ctx, "TaskService.CompleteTask")
+	response, err := s.next.CompleteTask(ctx, id)
+	end(span, err)
+	return response, err
+}
+
+func (s *TaskService) ReopenTask(ctx context.Context, id uint64) (*task.GetTaskResponse, error) {
+	ctx, span := start(ctx, "TaskService.ReopenTask")
+	response, err := s.next.ReopenTask(ctx, id)
+	end(span, err)
+	return response, err
+}
+
+func (s *TaskService) DeleteTask(ctx context.Context, id uint64) error {
+	ctx, span := start(ctx, "TaskService.DeleteTask")
+	err := s.next.DeleteTask(ctx, id)
+	end(span, err)
+	return err
+}
+
+func (s *TaskService) GetTrash(ctx context.Context, request task.GetAllTaskRequest) (*pagination.PaginationResponse[task.GetTaskResponse], error) {
+	ctx, span := start(ctx, "TaskService.GetTrash")
+	response, err := s.next.GetTrash(ctx, request)
+	end(span, err)
+	return response, err
+}
+
+func (s *TaskService) RestoreTask(ctx context.Context, id uint64) (*task.GetTaskResponse, error) {
+	ctx, span := start(ctx, "TaskService.RestoreTask")
+	response, err := s.next.RestoreTask(ctx, id)
+	end(span, err)
+	return response, err
+}
+
+func (s *TaskService) PurgeTask(ctx context.Context, id uint64) error {
+	ctx, span := start(ctx, "TaskService.PurgeTask")
+	err := s.next.PurgeTask(ctx, id)
+	end(span, err)
+	return err
+}
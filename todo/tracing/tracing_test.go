@@ -0,0 +1,161 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"mkmgo-todo/todo/handler"
+	"mkmgo-todo/todo/task"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// recordSpans installs a tracer provider that keeps every finished span for the test.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func spanNamed(t *testing.T, spans []sdktrace.ReadOnlySpan, name string) sdktrace.ReadOnlySpan {
+	for _, span := range spans {
+		if span.Name() == name {
+			return span
+		}
+	}
+	require.Failf(t, "span not recorded", "no span named %s", name)
+	return nil
+}
+
+func attributeValue(span sdktrace.ReadOnlySpan, key attribute.Key) string {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value.Emit()
+		}
+	}
+	return ""
+}
+
+/* Mock TaskService */
+
+type MockTaskService struct {
+	handler.TaskService
+	GetTaskFunc func(ctx context.Context, id uint64) (*task.GetTaskResponse, error)
+}
+
+func (m *MockTaskService) GetTask(ctx context.Context, id uint64) (*task.GetTaskResponse, error) {
+	return m.GetTaskFunc(ctx, id)
+}
+
+/* Mock TaskRepository */
+
+type MockTaskRepository struct {
+	task.TaskRepository
+	GetTaskFunc func(ctx context.Context, id uint64) (*task.Task, error)
+}
+
+func (m *MockTaskRepository) GetTask(ctx context.Context, id uint64) (*task.Task, error) {
+	return m.GetTaskFunc(ctx, id)
+}
+
+/* Unit test for TaskService and TaskRepository */
+
+func TestServiceAndRepositorySpansAreNested(t *testing.T) {
+	recorder := recordSpans(t)
+	repo := NewTaskRepository(&MockTaskRepository{
+		GetTaskFunc: func(ctx context.Context, id uint64) (*task.Task, error) {
+			return &task.Task{ID: id}, nil
+		},
+	})
+	svc := NewTaskService(&MockTaskService{
+		GetTaskFunc: func(ctx context.Context, id uint64) (*task.GetTaskResponse, error) {
+			found, err := repo.GetTask(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			response := found.ToResponse()
+			return &response, nil
+		},
+	})
+
+	response, err := svc.GetTask(context.Background(), 4)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(4), response.ID)
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	service := spanNamed(t, spans, "TaskService.GetTask")
+	repository := spanNamed(t, spans, "TaskRepository.GetTask")
+	assert.Equal(t, service.SpanContext().SpanID(), repository.Parent().SpanID())
+	assert.Equal(t, codes.Unset, service.Status().Code)
+}
+
+func TestSpanStatusOnErrors(t *testing.T) {
+	recorder := recordSpans(t)
+	repo := NewTaskRepository(&MockTaskRepository{
+		GetTaskFunc: func(ctx context.Context, id uint64) (*task.Task, error) {
+			if id == 1 {
+				return nil, fmt.Errorf("%w: id %d", task.ErrTaskNotFound, id)
+			}
+			return nil, errors.New("connection refused")
+		},
+	})
+
+	_, err := repo.GetTask(context.Background(), 1)
+	assert.ErrorIs(t, err, task.ErrTaskNotFound)
+	_, err = repo.GetTask(context.Background(), 2)
+	assert.Error(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	assert.Len(t, spans[0].Events(), 1)
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.Equal(t, "connection refused", spans[1].Status().Description)
+}
+
+/* Unit test for GORMPlugin */
+
+func TestGORMPluginTracesQueries(t *testing.T) {
+	recorder := recordSpans(t)
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "trace.db")), &gorm.Config{Logger: logger.Discard})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(NewGORMPlugin()))
+	require.NoError(t, db.AutoMigrate(&task.Task{}))
+
+	ctx, parent := start(context.Background(), "parent")
+	require.NoError(t, db.WithContext(ctx).Create(&task.Task{Title: "traced"}).Error)
+	var found task.Task
+	require.NoError(t, db.WithContext(ctx).First(&found).Error)
+	err = db.WithContext(ctx).First(&task.Task{}, 999).Error
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	parent.End()
+
+	var queries []sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Parent().SpanID() == parent.SpanContext().SpanID() {
+			queries = append(queries, span)
+		}
+	}
+	require.Len(t, queries, 3)
+	assert.Equal(t, "gorm.create", queries[0].Name())
+	assert.Equal(t, "gorm.query", queries[1].Name())
+	assert.Contains(t, attributeValue(queries[1], "db.query.text"), "FROM `task`")
+	assert.Equal(t, "task", attributeValue(queries[1], "db.collection.name"))
+	assert.Equal(t, "sqlite", attributeValue(queries[1], "db.system"))
+	assert.Equal(t, codes.Unset, queries[2].Status().Code)
+}
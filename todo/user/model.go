@@ -0,0 +1,43 @@
+// Package user manages the accounts tasks belong to.
+package user
+
+import "time"
+
+type User struct {
+	ID    uint64 `gorm:"primaryKey"`
+	Email string `gorm:"not null;uniqueIndex"` // stored in lower case
+	Name  string `gorm:"not null"`
+	// PasswordHash is a bcrypt hash, empty for accounts that cannot sign in with a password.
+	PasswordHash string `gorm:"not null;default:''"`
+	// ExternalIssuer and ExternalSubject name the account at the OpenID Connect provider it signs
+	// in with, if any.
+	ExternalIssuer  *string `gorm:"uniqueIndex:idx_user_account_external"`
+	ExternalSubject *string `gorm:"uniqueIndex:idx_user_account_external"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// TableName avoids user, a reserved word in Postgres.
+func (User) TableName() string {
+	return "user_account"
+}
+
+type RegisterRequest struct {
+	Email    string `json:"email" validate:"required,trimmed,max=254,email"`
+	Name     string `json:"name" validate:"required,trimmed,max=100,printable"`
+	Password string `json:"password" validate:"required,min=8,max=72"`
+}
+
+type UserResponse struct {
+	ID    uint64 `json:"id"`
+	Email string `json:"email"`
+	Name  string `json:"name"`
+}
+
+func (u User) ToResponse() UserResponse {
+	return UserResponse{
+		ID:    u.ID,
+		Email: u.Email,
+		Name:  u.Name,
+	}
+}
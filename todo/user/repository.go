@@ -0,0 +1,117 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+)
+
+type UserRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+func NewUserRepositoryImpl(db *gorm.DB) *UserRepositoryImpl {
+	return &UserRepositoryImpl{DB: db}
+}
+
+// SaveUser creates an account. Both its email and its single sign-on identity, if any, must be
+// free; which one was taken decides the error.
+func (r *UserRepositoryImpl) SaveUser(ctx context.Context, user *User) error {
+	log := zerolog.Ctx(ctx).With().Str("method", "userRepository.SaveUser").Logger()
+	if err := r.DB.WithContext(ctx).Create(user).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			if r.identityLinked(ctx, user) {
+				log.Info().Msg("identity already linked")
+				return ErrIdentityTaken
+			}
+			log.Info().Msg("email already registered")
+			return ErrEmailTaken
+		}
+		log.Error().Err(err).Msg("failed to save user")
+		return fmt.Errorf("failed to save user: %w", err)
+	}
+	log.Info().Uint64("id", user.ID).Msg("success to save user")
+	return nil
+}
+
+// identityLinked reports whether another account already has the user's single sign-on identity.
+func (r *UserRepositoryImpl) identityLinked(ctx context.Context, user *User) bool {
+	if user.ExternalIssuer == nil || user.ExternalSubject == nil {
+		return false
+	}
+	var count int64
+	err := r.DB.WithContext(ctx).Model(&User{}).
+		Where("external_issuer = ? AND external_subject = ?", *user.ExternalIssuer, *user.ExternalSubject).
+		Count(&count).Error
+	return err == nil && count > 0
+}
+
+func (r *UserRepositoryImpl) GetUser(ctx context.Context, id uint64) (*User, error) {
+	log := zerolog.Ctx(ctx).With().Str("method", "userRepository.GetUser").Logger()
+	var user User
+	if err := r.DB.WithContext(ctx).First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Info().Uint64("id", id).Msg("user not found")
+			return nil, fmt.Errorf("%w: id %d", ErrUserNotFound, id)
+		}
+		log.Error().Err(err).Msg("failed to retrieve user")
+		return nil, fmt.Errorf("failed to retrieve user: %w", err)
+	}
+	log.Info().Msg("success to retrieve user")
+	return &user, nil
+}
+
+func (r *UserRepositoryImpl) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	log := zerolog.Ctx(ctx).With().Str("method", "userRepository.GetUserByEmail").Logger()
+	var user User
+	if err := r.DB.WithContext(ctx).Where("email = ?", email).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Info().Msg("user not found")
+			return nil, ErrUserNotFound
+		}
+		log.Error().Err(err).Msg("failed to retrieve user")
+		return nil, fmt.Errorf("failed to retrieve user: %w", err)
+	}
+	log.Info().Msg("success to retrieve user")
+	return &user, nil
+}
+
+// GetUserByIdentity finds the account linked to a user of an OpenID Connect provider.
+func (r *UserRepositoryImpl) GetUserByIdentity(ctx context.Context, issuer, subject string) (*User, error) {
+	log := zerolog.Ctx(ctx).With().Str("method", "userRepository.GetUserByIdentity").Logger()
+	var user User
+	if err := r.DB.WithContext(ctx).Where("external_issuer = ? AND external_subject = ?", issuer, subject).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Info().Msg("user not found")
+			return nil, ErrUserNotFound
+		}
+		log.Error().Err(err).Msg("failed to retrieve user")
+		return nil, fmt.Errorf("failed to retrieve user: %w", err)
+	}
+	log.Info().Msg("success to retrieve user")
+	return &user, nil
+}
+
+// LinkIdentity lets an existing account sign in as a user of an OpenID Connect provider.
+func (r *UserRepositoryImpl) LinkIdentity(ctx context.Context, id uint64, issuer, subject string) error {
+	log := zerolog.Ctx(ctx).With().Str("method", "userRepository.LinkIdentity").Logger()
+	result := r.DB.WithContext(ctx).Model(&User{}).Where("id = ?", id).
+		Updates(map[string]any{"external_issuer": issuer, "external_subject": subject})
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrDuplicatedKey) {
+			log.Info().Msg("identity already linked")
+			return ErrIdentityTaken
+		}
+		log.Error().Err(result.Error).Msg("failed to link identity")
+		return fmt.Errorf("failed to link identity: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		log.Info().Uint64("id", id).Msg("user not found")
+		return fmt.Errorf("%w: id %d", ErrUserNotFound, id)
+	}
+	log.Info().Uint64("id", id).Msg("success to link identity")
+	return nil
+}
@@ -0,0 +1,114 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func newMockRepository(t *testing.T) (*UserRepositoryImpl, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{TranslateError: true})
+	assert.NoError(t, err)
+	return NewUserRepositoryImpl(gormDB), mock
+}
+
+func TestSaveUserMock(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	user := &User{Email: "ana@example.com", Name: "Ana", PasswordHash: "hash"}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "user_account" ("email","name","password_hash","external_issuer","external_subject","created_at","updated_at") VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING "id"`)).
+		WithArgs("ana@example.com", "Ana", "hash", nil, nil, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	err := repo.SaveUser(context.Background(), user)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), user.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSaveUserMockWhenEmailTaken(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "user_account"`)).
+		WillReturnError(&pgconn.PgError{Code: "23505"})
+	mock.ExpectRollback()
+
+	err := repo.SaveUser(context.Background(), &User{Email: "ana@example.com", Name: "Ana"})
+
+	assert.ErrorIs(t, err, ErrEmailTaken)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSaveUserMockWhenIdentityTaken(t *testing.T) {
+	repo, mock := newMockRepository(t)
+	issuer, subject := "https://sso.example.com", "u-1"
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "user_account"`)).
+		WillReturnError(&pgconn.PgError{Code: "23505"})
+	mock.ExpectRollback()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "user_account" WHERE external_issuer = $1 AND external_subject = $2`)).
+		WithArgs(issuer, subject).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	err := repo.SaveUser(context.Background(), &User{Email: "ana@example.com", Name: "Ana", ExternalIssuer: &issuer, ExternalSubject: &subject})
+
+	assert.ErrorIs(t, err, ErrIdentityTaken)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetUserByEmailMock(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "user_account" WHERE email = $1 ORDER BY "user_account"."id" LIMIT $2`)).
+		WithArgs("ana@example.com", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name"}).AddRow(1, "ana@example.com", "Ana"))
+
+	user, err := repo.GetUserByEmail(context.Background(), "ana@example.com")
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), user.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetUserMockWhenNotFound(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "user_account" WHERE "user_account"."id" = $1 ORDER BY "user_account"."id" LIMIT $2`)).
+		WithArgs(1, 1).
+		WillReturnError(gorm.ErrRecordNotFound)
+
+	_, err := repo.GetUser(context.Background(), 1)
+
+	assert.ErrorIs(t, err, ErrUserNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetUserMockWhenError(t *testing.T) {
+	repo, mock := newMockRepository(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "user_account"`)).
+		WillReturnError(errors.New("connection reset"))
+
+	_, err := repo.GetUser(context.Background(), 1)
+
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrUserNotFound)
+}
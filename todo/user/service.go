@@ -0,0 +1,178 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"mkmgo-todo/todo/auth"
+	"mkmgo-todo/todo/task"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrUserNotFound = task.NewError(task.ErrNotFound, "user not found")
+	ErrEmailTaken   = task.NewError(task.ErrConflict, "email is already registered")
+	// ErrIdentityTaken reports an account already linked to another single sign-on user, or a
+	// single sign-on user already linked to another account.
+	ErrIdentityTaken   = task.NewError(task.ErrConflict, "account is already linked to another single sign-on user")
+	ErrUnverifiedEmail = task.NewError(task.ErrValidation, "single sign-on provider did not vouch for an email address")
+)
+
+type UserRepository interface {
+	SaveUser(ctx context.Context, user *User) error
+	GetUser(ctx context.Context, id uint64) (*User, error)
+	GetUserByEmail(ctx context.Context, email string) (*User, error)
+	GetUserByIdentity(ctx context.Context, issuer, subject string) (*User, error)
+	LinkIdentity(ctx context.Context, id uint64, issuer, subject string) error
+}
+
+type UserServiceImpl struct {
+	repo UserRepository
+	cost int // bcrypt cost
+}
+
+func NewUserServiceImpl(repo UserRepository) *UserServiceImpl {
+	return &UserServiceImpl{repo: repo, cost: bcrypt.DefaultCost}
+}
+
+// Register creates an account that signs in with the given email and password.
+func (svc *UserServiceImpl) Register(ctx context.Context, request *RegisterRequest) (*UserResponse, error) {
+	if err := task.Validate(request); err != nil {
+		return nil, err
+	}
+	// bcrypt only looks at the first 72 bytes, which is fewer than 72 characters outside ASCII.
+	if len(request.Password) > 72 {
+		return nil, &task.ValidationError{Fields: []task.FieldError{{Field: "password", Message: "must be at most 72 bytes long"}}}
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(request.Password), svc.cost)
+	if err != nil {
+		return nil, err
+	}
+	user := User{
+		Email:        normalizeEmail(request.Email),
+		Name:         request.Name,
+		PasswordHash: string(hash),
+	}
+	if err := svc.repo.SaveUser(ctx, &user); err != nil {
+		return nil, err
+	}
+	response := user.ToResponse()
+	return &response, nil
+}
+
+func (svc *UserServiceImpl) GetUser(ctx context.Context, id uint64) (*UserResponse, error) {
+	user, err := svc.repo.GetUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	response := user.ToResponse()
+	return &response, nil
+}
+
+// dummyHash is compared against when no account matches, so that unknown emails take as long to
+// reject as wrong passwords and cannot be told apart by timing.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("not a password"), bcrypt.DefaultCost)
+
+// VerifyPassword returns the ID of the account the email and password belong to, or
+// auth.ErrInvalidCredentials.
+func (svc *UserServiceImpl) VerifyPassword(ctx context.Context, email, password string) (uint64, error) {
+	user, err := svc.repo.GetUserByEmail(ctx, normalizeEmail(email))
+	if errors.Is(err, ErrUserNotFound) {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return 0, auth.ErrInvalidCredentials
+	}
+	if err != nil {
+		return 0, err
+	}
+	if user.PasswordHash == "" || bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return 0, auth.ErrInvalidCredentials
+	}
+	return user.ID, nil
+}
+
+// ProvisionUser returns the account a single sign-on user owns tasks as, creating it on their first
+// sign-in. An account without a password already registered with the same email becomes theirs if
+// the provider has verified the address; an unverified address could belong to anyone, so it is
+// refused. An account with a password is never taken over: registering does not prove the email is
+// the registrant's, so its owner has to link it with LinkUser while signed in.
+func (svc *UserServiceImpl) ProvisionUser(ctx context.Context, identity auth.Identity) (uint64, error) {
+	user, err := svc.repo.GetUserByIdentity(ctx, identity.Issuer, identity.Subject)
+	if err == nil {
+		return user.ID, nil
+	}
+	if !errors.Is(err, ErrUserNotFound) {
+		return 0, err
+	}
+	if identity.Email == "" || !identity.EmailVerified {
+		return 0, ErrUnverifiedEmail
+	}
+
+	email := normalizeEmail(identity.Email)
+	existing, err := svc.repo.GetUserByEmail(ctx, email)
+	if err == nil {
+		if existing.PasswordHash != "" {
+			return 0, fmt.Errorf("%w: sign in with the password and link single sign-on from there", ErrEmailTaken)
+		}
+		if existing.ExternalSubject != nil {
+			return 0, ErrIdentityTaken
+		}
+		if err := svc.repo.LinkIdentity(ctx, existing.ID, identity.Issuer, identity.Subject); err != nil {
+			return 0, err
+		}
+		return existing.ID, nil
+	}
+	if !errors.Is(err, ErrUserNotFound) {
+		return 0, err
+	}
+
+	name := strings.TrimSpace(identity.Name)
+	if name == "" {
+		name = email
+	}
+	user = &User{
+		Email:           email,
+		Name:            name,
+		ExternalIssuer:  &identity.Issuer,
+		ExternalSubject: &identity.Subject,
+	}
+	if err := svc.repo.SaveUser(ctx, user); err != nil {
+		if errors.Is(err, ErrIdentityTaken) {
+			// a sign-in running alongside this one created the account first
+			if user, err := svc.repo.GetUserByIdentity(ctx, identity.Issuer, identity.Subject); err == nil {
+				return user.ID, nil
+			}
+		}
+		return 0, err
+	}
+	return user.ID, nil
+}
+
+// LinkUser lets the provider's user sign in as the account of userID, whose owner has signed in to
+// ask for it. An account links to one provider's user, and a provider's user to one account.
+func (svc *UserServiceImpl) LinkUser(ctx context.Context, userID uint64, identity auth.Identity) error {
+	linked, err := svc.repo.GetUserByIdentity(ctx, identity.Issuer, identity.Subject)
+	if err == nil {
+		if linked.ID == userID {
+			return nil
+		}
+		return ErrIdentityTaken
+	}
+	if !errors.Is(err, ErrUserNotFound) {
+		return err
+	}
+	user, err := svc.repo.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.ExternalSubject != nil {
+		return ErrIdentityTaken
+	}
+	return svc.repo.LinkIdentity(ctx, userID, identity.Issuer, identity.Subject)
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
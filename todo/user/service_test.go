@@ -0,0 +1,329 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"mkmgo-todo/todo/auth"
+	"mkmgo-todo/todo/task"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+/*
+	Mock user/repository.go
+*/
+
+type MockUserRepository struct {
+	SaveUserFunc          func(ctx context.Context, user *User) error
+	GetUserFunc           func(ctx context.Context, id uint64) (*User, error)
+	GetUserByEmailFunc    func(ctx context.Context, email string) (*User, error)
+	GetUserByIdentityFunc func(ctx context.Context, issuer, subject string) (*User, error)
+	LinkIdentityFunc      func(ctx context.Context, id uint64, issuer, subject string) error
+}
+
+func (m *MockUserRepository) SaveUser(ctx context.Context, user *User) error {
+	if m.SaveUserFunc != nil {
+		return m.SaveUserFunc(ctx, user)
+	}
+	return nil
+}
+
+func (m *MockUserRepository) GetUser(ctx context.Context, id uint64) (*User, error) {
+	if m.GetUserFunc != nil {
+		return m.GetUserFunc(ctx, id)
+	}
+	return nil, nil
+}
+
+func (m *MockUserRepository) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	if m.GetUserByEmailFunc != nil {
+		return m.GetUserByEmailFunc(ctx, email)
+	}
+	return nil, nil
+}
+
+func (m *MockUserRepository) GetUserByIdentity(ctx context.Context, issuer, subject string) (*User, error) {
+	if m.GetUserByIdentityFunc != nil {
+		return m.GetUserByIdentityFunc(ctx, issuer, subject)
+	}
+	return nil, ErrUserNotFound
+}
+
+func (m *MockUserRepository) LinkIdentity(ctx context.Context, id uint64, issuer, subject string) error {
+	if m.LinkIdentityFunc != nil {
+		return m.LinkIdentityFunc(ctx, id, issuer, subject)
+	}
+	return nil
+}
+
+func newTestService(repo UserRepository) *UserServiceImpl {
+	svc := NewUserServiceImpl(repo)
+	svc.cost = bcrypt.MinCost
+	return svc
+}
+
+/*
+	Unit test for user/service.go
+*/
+
+func TestRegister(t *testing.T) {
+	var saved User
+	svc := newTestService(&MockUserRepository{
+		SaveUserFunc: func(ctx context.Context, user *User) error {
+			user.ID = 1
+			saved = *user
+			return nil
+		},
+	})
+
+	resp, err := svc.Register(context.Background(), &RegisterRequest{Email: "Ana@Example.com", Name: "Ana", Password: "correct horse"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &UserResponse{ID: 1, Email: "ana@example.com", Name: "Ana"}, resp)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(saved.PasswordHash), []byte("correct horse")))
+}
+
+func TestRegisterWhenInvalid(t *testing.T) {
+	svc := newTestService(&MockUserRepository{
+		SaveUserFunc: func(ctx context.Context, user *User) error {
+			t.Fatal("invalid request must not be saved")
+			return nil
+		},
+	})
+
+	tests := map[string]RegisterRequest{
+		"email":    {Email: "not an email", Name: "Ana", Password: "correct horse"},
+		"name":     {Email: "ana@example.com", Password: "correct horse"},
+		"password": {Email: "ana@example.com", Name: "Ana", Password: "short"},
+	}
+	for field, request := range tests {
+		_, err := svc.Register(context.Background(), &request)
+
+		var validationErr *task.ValidationError
+		if assert.ErrorAs(t, err, &validationErr, field) {
+			assert.Equal(t, field, validationErr.Fields[0].Field)
+		}
+	}
+
+	// 36 characters but 72 bytes
+	_, err := svc.Register(context.Background(), &RegisterRequest{Email: "ana@example.com", Name: "Ana", Password: strings.Repeat("é", 37)})
+	assert.ErrorIs(t, err, task.ErrValidation)
+}
+
+func TestRegisterWhenEmailTaken(t *testing.T) {
+	svc := newTestService(&MockUserRepository{
+		SaveUserFunc: func(ctx context.Context, user *User) error {
+			return ErrEmailTaken
+		},
+	})
+
+	_, err := svc.Register(context.Background(), &RegisterRequest{Email: "ana@example.com", Name: "Ana", Password: "correct horse"})
+
+	assert.ErrorIs(t, err, ErrEmailTaken)
+	assert.ErrorIs(t, err, task.ErrConflict)
+}
+
+func TestVerifyPassword(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	svc := newTestService(&MockUserRepository{
+		GetUserByEmailFunc: func(ctx context.Context, email string) (*User, error) {
+			switch email {
+			case "ana@example.com":
+				return &User{ID: 1, Email: email, PasswordHash: string(hash)}, nil
+			case "sso@example.com":
+				return &User{ID: 2, Email: email}, nil
+			}
+			return nil, ErrUserNotFound
+		},
+	})
+
+	id, err := svc.VerifyPassword(context.Background(), " Ana@example.com", "correct horse")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), id)
+
+	_, err = svc.VerifyPassword(context.Background(), "ana@example.com", "wrong horse")
+	assert.ErrorIs(t, err, auth.ErrInvalidCredentials)
+	_, err = svc.VerifyPassword(context.Background(), "bob@example.com", "correct horse")
+	assert.ErrorIs(t, err, auth.ErrInvalidCredentials)
+	_, err = svc.VerifyPassword(context.Background(), "sso@example.com", "")
+	assert.ErrorIs(t, err, auth.ErrInvalidCredentials)
+}
+
+func TestVerifyPasswordWhenFailAtRepo(t *testing.T) {
+	svc := newTestService(&MockUserRepository{
+		GetUserByEmailFunc: func(ctx context.Context, email string) (*User, error) {
+			return nil, errors.New("database is locked")
+		},
+	})
+
+	_, err := svc.VerifyPassword(context.Background(), "ana@example.com", "correct horse")
+
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, auth.ErrInvalidCredentials)
+}
+
+var ssoIdentity = auth.Identity{Issuer: "https://sso.example.com", Subject: "u-1", Email: "Ana@example.com", EmailVerified: true, Name: "Ana"}
+
+func TestProvisionUserWhenKnown(t *testing.T) {
+	svc := newTestService(&MockUserRepository{
+		GetUserByIdentityFunc: func(ctx context.Context, issuer, subject string) (*User, error) {
+			assert.Equal(t, "https://sso.example.com", issuer)
+			assert.Equal(t, "u-1", subject)
+			return &User{ID: 3}, nil
+		},
+		SaveUserFunc: func(ctx context.Context, user *User) error {
+			t.Fatal("a known user must not be created again")
+			return nil
+		},
+	})
+
+	id, err := svc.ProvisionUser(context.Background(), ssoIdentity)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), id)
+}
+
+func TestProvisionUserCreatesAccount(t *testing.T) {
+	var saved User
+	svc := newTestService(&MockUserRepository{
+		GetUserByEmailFunc: func(ctx context.Context, email string) (*User, error) {
+			return nil, ErrUserNotFound
+		},
+		SaveUserFunc: func(ctx context.Context, user *User) error {
+			user.ID = 4
+			saved = *user
+			return nil
+		},
+	})
+
+	id, err := svc.ProvisionUser(context.Background(), ssoIdentity)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(4), id)
+	assert.Equal(t, "ana@example.com", saved.Email)
+	assert.Equal(t, "Ana", saved.Name)
+	assert.Empty(t, saved.PasswordHash, "single sign-on users have no password")
+	assert.Equal(t, "u-1", *saved.ExternalSubject)
+}
+
+func TestProvisionUserWhenCreatedAlongside(t *testing.T) {
+	lookups := 0
+	svc := newTestService(&MockUserRepository{
+		GetUserByIdentityFunc: func(ctx context.Context, issuer, subject string) (*User, error) {
+			lookups++
+			if lookups == 1 {
+				return nil, ErrUserNotFound
+			}
+			return &User{ID: 5}, nil
+		},
+		GetUserByEmailFunc: func(ctx context.Context, email string) (*User, error) {
+			return nil, ErrUserNotFound
+		},
+		SaveUserFunc: func(ctx context.Context, user *User) error {
+			return ErrIdentityTaken
+		},
+	})
+
+	id, err := svc.ProvisionUser(context.Background(), ssoIdentity)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(5), id)
+}
+
+func TestProvisionUserLinksVerifiedEmail(t *testing.T) {
+	var linked uint64
+	svc := newTestService(&MockUserRepository{
+		GetUserByEmailFunc: func(ctx context.Context, email string) (*User, error) {
+			return &User{ID: 1, Email: email}, nil
+		},
+		LinkIdentityFunc: func(ctx context.Context, id uint64, issuer, subject string) error {
+			linked = id
+			return nil
+		},
+	})
+
+	id, err := svc.ProvisionUser(context.Background(), ssoIdentity)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), id)
+	assert.Equal(t, uint64(1), linked)
+}
+
+func TestProvisionUserWhenPasswordAccountHasEmail(t *testing.T) {
+	svc := newTestService(&MockUserRepository{
+		GetUserByEmailFunc: func(ctx context.Context, email string) (*User, error) {
+			return &User{ID: 1, Email: email, PasswordHash: "hash"}, nil
+		},
+		LinkIdentityFunc: func(ctx context.Context, id uint64, issuer, subject string) error {
+			t.Fatal("an account with a password must not be taken over")
+			return nil
+		},
+	})
+
+	_, err := svc.ProvisionUser(context.Background(), ssoIdentity)
+
+	assert.ErrorIs(t, err, ErrEmailTaken)
+}
+
+func TestLinkUser(t *testing.T) {
+	var linked uint64
+	svc := newTestService(&MockUserRepository{
+		GetUserFunc: func(ctx context.Context, id uint64) (*User, error) {
+			return &User{ID: id, PasswordHash: "hash"}, nil
+		},
+		LinkIdentityFunc: func(ctx context.Context, id uint64, issuer, subject string) error {
+			assert.Equal(t, "u-1", subject)
+			linked = id
+			return nil
+		},
+	})
+
+	err := svc.LinkUser(context.Background(), 1, ssoIdentity)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), linked)
+}
+
+func TestLinkUserWhenTaken(t *testing.T) {
+	other := "u-2"
+	svc := newTestService(&MockUserRepository{
+		GetUserByIdentityFunc: func(ctx context.Context, issuer, subject string) (*User, error) {
+			return &User{ID: 2}, nil
+		},
+		LinkIdentityFunc: func(ctx context.Context, id uint64, issuer, subject string) error {
+			t.Fatal("a linked identity must not move to another account")
+			return nil
+		},
+	})
+	assert.ErrorIs(t, svc.LinkUser(context.Background(), 1, ssoIdentity), ErrIdentityTaken)
+	assert.NoError(t, svc.LinkUser(context.Background(), 2, ssoIdentity), "linking again is harmless")
+
+	svc = newTestService(&MockUserRepository{
+		GetUserFunc: func(ctx context.Context, id uint64) (*User, error) {
+			return &User{ID: id, ExternalSubject: &other}, nil
+		},
+	})
+	assert.ErrorIs(t, svc.LinkUser(context.Background(), 1, ssoIdentity), ErrIdentityTaken)
+}
+
+func TestProvisionUserWhenRefused(t *testing.T) {
+	unverified := ssoIdentity
+	unverified.EmailVerified = false
+	svc := newTestService(&MockUserRepository{
+		GetUserByEmailFunc: func(ctx context.Context, email string) (*User, error) {
+			other := "u-2"
+			return &User{ID: 1, Email: email, ExternalSubject: &other}, nil
+		},
+	})
+
+	_, err := svc.ProvisionUser(context.Background(), unverified)
+	assert.ErrorIs(t, err, ErrUnverifiedEmail)
+
+	_, err = svc.ProvisionUser(context.Background(), ssoIdentity)
+	assert.ErrorIs(t, err, ErrIdentityTaken)
+}